@@ -0,0 +1,382 @@
+package ai
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/radovskyb/chess/engine"
+)
+
+// Weights holds the weight of each term used to evaluate a position, in
+// centipawns. Bonuses are positive and penalties are negative.
+//
+// Tables indexed by square are from white's point of view, starting at
+// a8 and ending at h1, so that they read the same way as a board that's
+// printed with white at the bottom. They're mirrored for black.
+type Weights struct {
+	// Material holds the value of each piece name, indexed by their
+	// PieceName values.
+	Material [6]int `json:"material"`
+
+	// PieceSquare holds a bonus for each piece name on each square,
+	// where the king's table is for the middle game.
+	PieceSquare [6][64]int `json:"piece_square"`
+
+	// KingEndgame holds the king's bonus on each square in the end
+	// game, when there's less material left to attack it with.
+	KingEndgame [64]int `json:"king_endgame"`
+
+	// Mobility holds a bonus for each position that a piece of each
+	// piece name can move to.
+	Mobility [6]int `json:"mobility"`
+
+	// DoubledPawn is added for each pawn on the same file as another
+	// pawn of the same color.
+	DoubledPawn int `json:"doubled_pawn"`
+
+	// IsolatedPawn is added for each pawn without any pawns of the
+	// same color on the files next to it.
+	IsolatedPawn int `json:"isolated_pawn"`
+
+	// PassedPawn holds a bonus for a pawn without any opposing pawns
+	// in front of it or on the files next to it, indexed by how many
+	// ranks the pawn is from it's own side of the board.
+	PassedPawn [8]int `json:"passed_pawn"`
+
+	// KingShield is added in the middle game for each pawn in front of
+	// it's king.
+	KingShield int `json:"king_shield"`
+
+	// KingAttack is added in the middle game for each position next to
+	// a king that's attacked by the opponent.
+	KingAttack int `json:"king_attack"`
+}
+
+// DefaultWeights holds the weights used by Evaluate, and by Search when
+// it's limits don't have any weights. It can be changed to tune the
+// evaluation, but not while a search is running.
+var DefaultWeights = Weights{
+	Material: [6]int{
+		engine.Pawn:   100,
+		engine.Knight: 320,
+		engine.Bishop: 330,
+		engine.Rook:   500,
+		engine.Queen:  900,
+	},
+	PieceSquare: [6][64]int{
+		engine.Pawn: {
+			0, 0, 0, 0, 0, 0, 0, 0,
+			50, 50, 50, 50, 50, 50, 50, 50,
+			10, 10, 20, 30, 30, 20, 10, 10,
+			5, 5, 10, 25, 25, 10, 5, 5,
+			0, 0, 0, 20, 20, 0, 0, 0,
+			5, -5, -10, 0, 0, -10, -5, 5,
+			5, 10, 10, -20, -20, 10, 10, 5,
+			0, 0, 0, 0, 0, 0, 0, 0,
+		},
+		engine.Knight: {
+			-50, -40, -30, -30, -30, -30, -40, -50,
+			-40, -20, 0, 0, 0, 0, -20, -40,
+			-30, 0, 10, 15, 15, 10, 0, -30,
+			-30, 5, 15, 20, 20, 15, 5, -30,
+			-30, 0, 15, 20, 20, 15, 0, -30,
+			-30, 5, 10, 15, 15, 10, 5, -30,
+			-40, -20, 0, 5, 5, 0, -20, -40,
+			-50, -40, -30, -30, -30, -30, -40, -50,
+		},
+		engine.Bishop: {
+			-20, -10, -10, -10, -10, -10, -10, -20,
+			-10, 0, 0, 0, 0, 0, 0, -10,
+			-10, 0, 5, 10, 10, 5, 0, -10,
+			-10, 5, 5, 10, 10, 5, 5, -10,
+			-10, 0, 10, 10, 10, 10, 0, -10,
+			-10, 10, 10, 10, 10, 10, 10, -10,
+			-10, 5, 0, 0, 0, 0, 5, -10,
+			-20, -10, -10, -10, -10, -10, -10, -20,
+		},
+		engine.Rook: {
+			0, 0, 0, 0, 0, 0, 0, 0,
+			5, 10, 10, 10, 10, 10, 10, 5,
+			-5, 0, 0, 0, 0, 0, 0, -5,
+			-5, 0, 0, 0, 0, 0, 0, -5,
+			-5, 0, 0, 0, 0, 0, 0, -5,
+			-5, 0, 0, 0, 0, 0, 0, -5,
+			-5, 0, 0, 0, 0, 0, 0, -5,
+			0, 0, 0, 5, 5, 0, 0, 0,
+		},
+		engine.Queen: {
+			-20, -10, -10, -5, -5, -10, -10, -20,
+			-10, 0, 0, 0, 0, 0, 0, -10,
+			-10, 0, 5, 5, 5, 5, 0, -10,
+			-5, 0, 5, 5, 5, 5, 0, -5,
+			0, 0, 5, 5, 5, 5, 0, -5,
+			-10, 5, 5, 5, 5, 5, 0, -10,
+			-10, 0, 5, 0, 0, 0, 0, -10,
+			-20, -10, -10, -5, -5, -10, -10, -20,
+		},
+		engine.King: {
+			-30, -40, -40, -50, -50, -40, -40, -30,
+			-30, -40, -40, -50, -50, -40, -40, -30,
+			-30, -40, -40, -50, -50, -40, -40, -30,
+			-30, -40, -40, -50, -50, -40, -40, -30,
+			-20, -30, -30, -40, -40, -30, -30, -20,
+			-10, -20, -20, -20, -20, -20, -20, -10,
+			20, 20, 0, 0, 0, 0, 20, 20,
+			20, 30, 10, 0, 0, 10, 30, 20,
+		},
+	},
+	KingEndgame: [64]int{
+		-50, -40, -30, -20, -20, -30, -40, -50,
+		-30, -20, -10, 0, 0, -10, -20, -30,
+		-30, -10, 20, 30, 30, 20, -10, -30,
+		-30, -10, 30, 40, 40, 30, -10, -30,
+		-30, -10, 30, 40, 40, 30, -10, -30,
+		-30, -10, 20, 30, 30, 20, -10, -30,
+		-30, -30, 0, 0, 0, 0, -30, -30,
+		-50, -30, -30, -30, -30, -30, -30, -50,
+	},
+	Mobility: [6]int{
+		engine.Knight: 4,
+		engine.Bishop: 4,
+		engine.Rook:   2,
+		engine.Queen:  1,
+	},
+	DoubledPawn:  -10,
+	IsolatedPawn: -15,
+	PassedPawn:   [8]int{0, 5, 10, 20, 35, 60, 100, 0},
+	KingShield:   10,
+	KingAttack:   -8,
+}
+
+// LoadWeights reads weights from JSON in r. Any weights that aren't in
+// the JSON are taken from DefaultWeights.
+func LoadWeights(r io.Reader) (Weights, error) {
+	w := DefaultWeights
+	if err := json.NewDecoder(r).Decode(&w); err != nil {
+		return Weights{}, err
+	}
+	return w, nil
+}
+
+// Evaluate returns a score for board b's current position in centipawns
+// using DefaultWeights, where positive scores are better for white and
+// negative scores are better for black.
+func Evaluate(b *engine.Board) int {
+	return DefaultWeights.Evaluate(b)
+}
+
+// Evaluate returns a score for board b's current position in centipawns
+// using the weights w, where positive scores are better for white and
+// negative scores are better for black.
+func (w *Weights) Evaluate(b *engine.Board) int {
+	return w.evaluate(b.Position())
+}
+
+// phaseWeights holds how much each piece name counts towards the game
+// phase. When all of the pieces are on the board, they add up to
+// maxPhase.
+var phaseWeights = [6]int{engine.Knight: 1, engine.Bishop: 1, engine.Rook: 2, engine.Queen: 4}
+
+const maxPhase = 24
+
+// Offsets that knights and kings move by, and directions that bishops
+// and rooks move in.
+var (
+	knightOffsets = []engine.Pos{{X: 1, Y: 2}, {X: 2, Y: 1}, {X: 2, Y: -1}, {X: 1, Y: -2},
+		{X: -1, Y: -2}, {X: -2, Y: -1}, {X: -2, Y: 1}, {X: -1, Y: 2}}
+	bishopDirs = []engine.Pos{{X: 1, Y: 1}, {X: 1, Y: -1}, {X: -1, Y: -1}, {X: -1, Y: 1}}
+	rookDirs   = []engine.Pos{{X: 0, Y: 1}, {X: 1, Y: 0}, {X: 0, Y: -1}, {X: -1, Y: 0}}
+	kingDirs   = append(append([]engine.Pos{}, bishopDirs...), rookDirs...)
+)
+
+// onBoard reports whether x and y are a position on the board.
+func onBoard(x, y int) bool {
+	return x >= 0 && x < 8 && y >= 0 && y < 8
+}
+
+// An evaluation holds the pieces of a position while it's evaluated.
+type evaluation struct {
+	squares [8][8]*engine.Piece // Indexed by x and then y.
+	pieces  [64]engine.Piece
+
+	// pawns holds the number of pawns of each color on each file.
+	pawns [2][8]int
+
+	// attacked holds the positions attacked by each color.
+	attacked [2][8][8]bool
+
+	kings [2]engine.Pos
+	phase int
+}
+
+// evaluate returns a score for position p in centipawns using the
+// weights w, where positive scores are better for white.
+func (w *Weights) evaluate(p engine.Position) int {
+	e := &evaluation{}
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			piece, found := p.PieceAt(engine.Pos{X: x, Y: y})
+			if !found {
+				continue
+			}
+			e.pieces[y*8+x] = piece
+			e.squares[x][y] = &e.pieces[y*8+x]
+			e.phase += phaseWeights[piece.Name]
+			switch piece.Name {
+			case engine.Pawn:
+				e.pawns[piece.Color][x]++
+			case engine.King:
+				e.kings[piece.Color] = engine.Pos{X: x, Y: y}
+			}
+		}
+	}
+	if e.phase > maxPhase {
+		e.phase = maxPhase
+	}
+
+	var scores [2]int
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			if piece := e.squares[x][y]; piece != nil {
+				scores[piece.Color] += w.pieceScore(e, piece, x, y)
+			}
+		}
+	}
+	for _, color := range []engine.Color{engine.White, engine.Black} {
+		scores[color] += w.kingSafety(e, color)
+	}
+	return scores[engine.White] - scores[engine.Black]
+}
+
+// tableIndex returns the index in a table from white's point of view
+// for a piece of color on position x, y.
+func tableIndex(color engine.Color, x, y int) int {
+	if color == engine.Black {
+		return y*8 + x
+	}
+	return (7-y)*8 + x
+}
+
+// pieceScore returns the score of piece on position x, y, which is
+// it's material, piece square bonus, mobility and pawn structure.
+// pieceScore also marks the positions that the piece attacks.
+func (w *Weights) pieceScore(e *evaluation, piece *engine.Piece, x, y int) int {
+	i := tableIndex(piece.Color, x, y)
+	score := w.Material[piece.Name]
+
+	switch piece.Name {
+	case engine.King:
+		// Move from the middle game table to the end game table as
+		// pieces come off of the board.
+		score += (w.PieceSquare[engine.King][i]*e.phase +
+			w.KingEndgame[i]*(maxPhase-e.phase)) / maxPhase
+		e.attack(piece.Color, x, y, kingDirs, false)
+		return score
+	case engine.Pawn:
+		score += w.PieceSquare[engine.Pawn][i] + w.pawnStructure(e, piece.Color, x, y)
+		forward := 1
+		if piece.Color == engine.Black {
+			forward = -1
+		}
+		for _, dx := range []int{-1, 1} {
+			if onBoard(x+dx, y+forward) {
+				e.attacked[piece.Color][x+dx][y+forward] = true
+			}
+		}
+		return score
+	}
+
+	score += w.PieceSquare[piece.Name][i]
+	var moves int
+	switch piece.Name {
+	case engine.Knight:
+		moves = e.attack(piece.Color, x, y, knightOffsets, false)
+	case engine.Bishop:
+		moves = e.attack(piece.Color, x, y, bishopDirs, true)
+	case engine.Rook:
+		moves = e.attack(piece.Color, x, y, rookDirs, true)
+	case engine.Queen:
+		moves = e.attack(piece.Color, x, y, kingDirs, true)
+	}
+	return score + moves*w.Mobility[piece.Name]
+}
+
+// attack marks the positions attacked by a piece of color on position
+// x, y, which moves by each of offsets, repeating them until it reaches
+// another piece if slide is true. attack returns the number of attacked
+// positions that aren't occupied by color's own pieces.
+func (e *evaluation) attack(color engine.Color, x, y int, offsets []engine.Pos, slide bool) int {
+	moves := 0
+	for _, o := range offsets {
+		for tx, ty := x+o.X, y+o.Y; onBoard(tx, ty); tx, ty = tx+o.X, ty+o.Y {
+			e.attacked[color][tx][ty] = true
+			target := e.squares[tx][ty]
+			if target == nil || target.Color != color {
+				moves++
+			}
+			if target != nil || !slide {
+				break
+			}
+		}
+	}
+	return moves
+}
+
+// pawnStructure returns the bonuses and penalties for a pawn of color
+// on position x, y being doubled, isolated or passed.
+func (w *Weights) pawnStructure(e *evaluation, color engine.Color, x, y int) int {
+	score := 0
+	if e.pawns[color][x] > 1 {
+		score += w.DoubledPawn
+	}
+	if (x == 0 || e.pawns[color][x-1] == 0) && (x == 7 || e.pawns[color][x+1] == 0) {
+		score += w.IsolatedPawn
+	}
+
+	// Look for opposing pawns in front of the pawn on it's own file
+	// and the files next to it.
+	forward, rank := 1, y
+	if color == engine.Black {
+		forward, rank = -1, 7-y
+	}
+	for fx := x - 1; fx <= x+1; fx++ {
+		for fy := y + forward; onBoard(fx, fy); fy += forward {
+			if p := e.squares[fx][fy]; p != nil && p.Name == engine.Pawn && p.Color != color {
+				return score
+			}
+		}
+	}
+	return score + w.PassedPawn[rank]
+}
+
+// kingSafety returns the bonus for pawns sheltering color's king and
+// the penalty for the opponent attacking the positions next to it,
+// which both matter less as pieces come off of the board. It must be
+// called after all of the pieces' attacks have been marked.
+func (w *Weights) kingSafety(e *evaluation, color engine.Color) int {
+	king := e.kings[color]
+	forward := 1
+	if color == engine.Black {
+		forward = -1
+	}
+
+	score := 0
+	for x := king.X - 1; x <= king.X+1; x++ {
+		for dy := 1; dy <= 2; dy++ {
+			if !onBoard(x, king.Y+dy*forward) {
+				continue
+			}
+			p := e.squares[x][king.Y+dy*forward]
+			if p != nil && p.Name == engine.Pawn && p.Color == color {
+				score += w.KingShield
+			}
+		}
+	}
+	for _, d := range kingDirs {
+		x, y := king.X+d.X, king.Y+d.Y
+		if onBoard(x, y) && e.attacked[color^1][x][y] {
+			score += w.KingAttack
+		}
+	}
+	return score * e.phase / maxPhase
+}
@@ -0,0 +1,168 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/radovskyb/chess/engine"
+)
+
+// mirrorFEN returns fen with the board flipped and the colors of the
+// pieces and the side to move swapped.
+func mirrorFEN(fen string) string {
+	swapCase := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z':
+				return r - 'a' + 'A'
+			case r >= 'A' && r <= 'Z':
+				return r - 'A' + 'a'
+			}
+			return r
+		}, s)
+	}
+
+	fields := strings.Fields(fen)
+	ranks := strings.Split(fields[0], "/")
+	for i, j := 0, len(ranks)-1; i < j; i, j = i+1, j-1 {
+		ranks[i], ranks[j] = ranks[j], ranks[i]
+	}
+	fields[0] = swapCase(strings.Join(ranks, "/"))
+	if fields[1] == "w" {
+		fields[1] = "b"
+	} else {
+		fields[1] = "w"
+	}
+	fields[2] = swapCase(fields[2])
+	if fields[3] != "-" {
+		fields[3] = fields[3][:1] + string('1'+'8'-fields[3][1])
+	}
+	return strings.Join(fields, " ")
+}
+
+func parseFEN(t *testing.T, fen string) *engine.Board {
+	t.Helper()
+	b, err := engine.ParseFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestEvaluateSymmetry(t *testing.T) {
+	if score := Evaluate(engine.NewBoard()); score != 0 {
+		t.Errorf("expected the starting position to evaluate to 0, got %d", score)
+	}
+
+	for _, fen := range []string{
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	} {
+		score := Evaluate(parseFEN(t, fen))
+		mirrored := Evaluate(parseFEN(t, mirrorFEN(fen)))
+		if score != -mirrored {
+			t.Errorf("%s: expected mirrored position to evaluate to %d, got %d",
+				fen, -score, mirrored)
+		}
+	}
+}
+
+func TestEvaluateMaterial(t *testing.T) {
+	// White is a queen up.
+	b := parseFEN(t, "rnb1kbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	if score := Evaluate(b); score < 800 {
+		t.Errorf("expected white to be about a queen up, got %d", score)
+	}
+}
+
+func TestEvaluateTerms(t *testing.T) {
+	testCases := []struct {
+		name     string
+		weights  Weights
+		fen      string
+		expected int
+	}{
+		{
+			// Both of the pawns on the e file are doubled.
+			"doubled pawns",
+			Weights{DoubledPawn: -10},
+			"4k3/8/8/8/8/4P3/3PP3/4K3 w - - 0 1",
+			-20,
+		},
+		{
+			// The pawn on a2 is isolated and black's pawns aren't.
+			"isolated pawns",
+			Weights{IsolatedPawn: -15},
+			"4k3/5pp1/8/8/8/8/P7/4K3 w - - 0 1",
+			-15,
+		},
+		{
+			// White's pawn on e6 is passed, but black's pawn on
+			// a7 is blocked by the pawn on b5's file.
+			"passed pawns",
+			Weights{PassedPawn: [8]int{0, 1, 2, 3, 4, 5, 6, 0}},
+			"4k3/p7/4P3/1P6/8/8/8/4K3 w - - 0 1",
+			5,
+		},
+		{
+			// A knight on d4 can move to 8 positions.
+			"mobility",
+			Weights{Mobility: [6]int{engine.Knight: 1}},
+			"4k3/8/8/8/3N4/8/8/4K3 w - - 0 1",
+			8,
+		},
+		{
+			// White's king has 3 pawns in front of it and black's
+			// king has none, but with only queens left, king safety
+			// counts for 8/24 of it's weight.
+			"king shield",
+			Weights{KingShield: 12},
+			"3qk3/8/8/8/8/8/5PPP/3Q2K1 w - - 0 1",
+			12,
+		},
+		{
+			// Black's rook attacks d1 and d2 next to white's king,
+			// but with only rooks left, king safety counts for
+			// 4/24 of it's weight.
+			"king attack",
+			Weights{KingAttack: -9},
+			"3rk3/8/8/8/8/8/8/R3K3 w - - 0 1",
+			-3,
+		},
+	}
+	for _, tc := range testCases {
+		score := tc.weights.Evaluate(parseFEN(t, tc.fen))
+		if score != tc.expected {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.expected, score)
+		}
+	}
+}
+
+func TestLoadWeights(t *testing.T) {
+	w, err := LoadWeights(strings.NewReader(`{"doubled_pawn": -25, "mobility": [0, 5, 5, 3, 2, 0]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.DoubledPawn != -25 || w.Mobility[engine.Knight] != 5 {
+		t.Errorf("expected weights from JSON to be loaded, got %d and %d",
+			w.DoubledPawn, w.Mobility[engine.Knight])
+	}
+	if w.Material != DefaultWeights.Material || w.IsolatedPawn != DefaultWeights.IsolatedPawn {
+		t.Error("expected weights that aren't in the JSON to be the default weights")
+	}
+
+	if _, err := LoadWeights(strings.NewReader(`{"doubled_pawn": "a lot"}`)); err == nil {
+		t.Error("expected an error for invalid weights")
+	}
+}
+
+func BenchmarkEvaluate(b *testing.B) {
+	board, err := engine.ParseFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < b.N; i++ {
+		Evaluate(board)
+	}
+}
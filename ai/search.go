@@ -0,0 +1,449 @@
+// Package ai implements a computer opponent that searches for the best
+// move on an engine.Board.
+package ai
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/radovskyb/chess/engine"
+)
+
+var ErrNoMoves = errors.New("ai: no legal moves to search")
+
+// MateScore is the score of a position where the side to move has been
+// checkmated. Scores within maxPly of MateScore or -MateScore mean that
+// a checkmate has been found.
+const MateScore = 100000
+
+// DefaultDepth is the depth that a search stops at when it's not given
+// any limits.
+const DefaultDepth = 4
+
+// pieceValues holds the value of each piece name in centipawns for
+// ordering moves, indexed by their PieceName values.
+var pieceValues = [...]int{
+	engine.Pawn:   100,
+	engine.Knight: 320,
+	engine.Bishop: 330,
+	engine.Rook:   500,
+	engine.Queen:  900,
+	engine.King:   2000,
+}
+
+// maxPly is the deepest that a search can ever go, including quiescence
+// search and check extensions.
+const maxPly = 128
+
+// Limits holds the limits of a search.
+type Limits struct {
+	// Depth is the maximum number of half moves to search ahead, or 0
+	// for no depth limit.
+	Depth int
+
+	// Time is the maximum amount of time to search for, or 0 for no
+	// time limit.
+	Time time.Duration
+
+	// Infinite makes the search ignore Depth and Time and keep going
+	// until it's stopped.
+	Infinite bool
+
+	// Weights holds the weights to evaluate positions with, or nil to
+	// use DefaultWeights.
+	Weights *Weights
+}
+
+// A Result holds the best move found by a search.
+type Result struct {
+	// Move is the best move found.
+	Move engine.Move
+
+	// Score is the score of Move in centipawns from the point of view
+	// of the side to move.
+	Score int
+
+	// Depth is the deepest search that was fully completed.
+	Depth int
+
+	// Nodes is the number of positions that were searched.
+	Nodes uint64
+
+	// PV holds the principal variation, which is the line of moves
+	// that the search expects to be played, starting with Move.
+	PV []engine.Move
+}
+
+// MateIn returns the number of moves until a checkmate and true if the
+// result's score is a checkmate, or false if it's not. The number of
+// moves is negative when the side to move is getting checkmated.
+func (r Result) MateIn() (int, bool) {
+	switch {
+	case r.Score > MateScore-maxPly:
+		return (MateScore - r.Score + 1) / 2, true
+	case r.Score < -MateScore+maxPly:
+		return -(MateScore + r.Score) / 2, true
+	}
+	return 0, false
+}
+
+// Search finds the best move for the side to move on board b using an
+// iterative deepening alpha-beta search, searching 1 half move deeper
+// each time until it reaches one of limits. If neither limit is set,
+// the search stops at DefaultDepth.
+//
+// The search is made on a copy of b, so b isn't changed.
+//
+// Search returns ErrNoMoves if the side to move doesn't have any legal
+// moves.
+func Search(b *engine.Board, limits Limits) (Result, error) {
+	return SearchContext(context.Background(), b, limits, nil)
+}
+
+// SearchContext is like Search, but also stops searching when ctx is
+// done, returning the best move found so far. If info isn't nil, it's
+// called with the result of each depth as soon as it's completed.
+func SearchContext(ctx context.Context, b *engine.Board, limits Limits, info func(Result)) (Result, error) {
+	s := &searcher{
+		b:       b.Clone(),
+		tt:      make([]ttEntry, ttSize),
+		weights: DefaultWeights,
+		done:    ctx.Done(),
+	}
+	if limits.Weights != nil {
+		s.weights = *limits.Weights
+	}
+	maxDepth := limits.Depth
+	switch {
+	case limits.Infinite:
+		maxDepth = maxPly
+	case maxDepth <= 0 && limits.Time > 0:
+		maxDepth = maxPly
+	case maxDepth <= 0:
+		maxDepth = DefaultDepth
+	}
+	if limits.Time > 0 && !limits.Infinite {
+		s.deadline = time.Now().Add(limits.Time)
+	}
+
+	moves := s.b.LegalMoves()
+	if len(moves) == 0 {
+		return Result{}, ErrNoMoves
+	}
+	s.orderMoves(moves, engine.Move{})
+	result := Result{Move: moves[0], PV: moves[:1]}
+
+	for depth := 1; depth <= maxDepth; depth++ {
+		// Stop between depths too, since a shallow depth can finish
+		// before ctx is checked while searching.
+		if depth > 1 && ctx.Err() != nil {
+			break
+		}
+		score := s.search(depth, 0, -MateScore-1, MateScore+1)
+		if s.stopped {
+			break
+		}
+		result.Move, result.Score, result.Depth = s.rootMove, score, depth
+		result.PV = s.pv(s.rootMove, depth)
+		result.Nodes = s.nodes
+		if info != nil {
+			info(result)
+		}
+
+		// There's no point searching deeper once a checkmate is found.
+		if _, mate := result.MateIn(); mate {
+			break
+		}
+	}
+	result.Nodes = s.nodes
+	return result, nil
+}
+
+// A searcher holds the state of a single search.
+type searcher struct {
+	b        *engine.Board
+	tt       []ttEntry
+	weights  Weights
+	deadline time.Time
+	done     <-chan struct{}
+	nodes    uint64
+	stopped  bool
+
+	// rootMove holds the best move found at the root of the last
+	// search.
+	rootMove engine.Move
+}
+
+// timeUp reports whether the search has run out of time or has been
+// stopped, only checking every few thousand nodes.
+func (s *searcher) timeUp() bool {
+	if s.stopped || s.nodes&2047 != 0 {
+		return s.stopped
+	}
+	select {
+	case <-s.done:
+		s.stopped = true
+	default:
+		s.stopped = !s.deadline.IsZero() && time.Now().After(s.deadline)
+	}
+	return s.stopped
+}
+
+// inCheck reports whether the side to move is in check.
+func (s *searcher) inCheck() bool {
+	check, color := s.b.HasCheck()
+	return check && color == s.b.Turn()
+}
+
+// search returns the score of the board's position from the point of
+// view of the side to move, searching depth half moves ahead.
+//
+// Scores at or below alpha and at or above beta are only bounds, since
+// those moves won't be played by one of the sides.
+func (s *searcher) search(depth, ply, alpha, beta int) int {
+	s.nodes++
+	if s.timeUp() {
+		return 0
+	}
+
+	inCheck := s.inCheck()
+	if inCheck {
+		// Search positions in check 1 half move deeper so that
+		// checkmates aren't missed.
+		depth++
+	}
+	if depth <= 0 || ply >= maxPly {
+		return s.quiesce(ply, alpha, beta)
+	}
+
+	// Positions that are drawn by repetition or any of the other
+	// draw rules are scored as 0, except at the root, where a move
+	// still needs to be found.
+	if ply > 0 && s.b.DrawReason() != engine.NoDraw {
+		return 0
+	}
+
+	hash := s.b.Hash()
+	entry := &s.tt[hash%ttSize]
+	if entry.hash == hash && ply > 0 && int(entry.depth) >= depth {
+		score := fromTT(int(entry.score), ply)
+		switch {
+		case entry.bound == exactBound,
+			entry.bound == lowerBound && score >= beta,
+			entry.bound == upperBound && score <= alpha:
+			return score
+		}
+	}
+	var hashMove engine.Move
+	if entry.hash == hash {
+		hashMove = entry.move
+	}
+
+	moves := s.b.LegalMoves()
+	if len(moves) == 0 {
+		if inCheck {
+			return -MateScore + ply
+		}
+		return 0
+	}
+	s.orderMoves(moves, hashMove)
+	bound, bestScore, bestMove := upperBound, -MateScore-1, moves[0]
+	for _, m := range moves {
+		s.makeMove(m)
+		score := -s.search(depth-1, ply+1, -beta, -alpha)
+		s.undoMove()
+		if s.stopped {
+			return 0
+		}
+		if score > bestScore {
+			bestScore, bestMove = score, m
+		}
+		if score > alpha {
+			alpha, bound = score, exactBound
+		}
+		if alpha >= beta {
+			bound = lowerBound
+			break
+		}
+	}
+
+	if ply == 0 {
+		s.rootMove = bestMove
+	}
+
+	// Keep the results of deeper searches of other positions, since
+	// they took longer to find.
+	if entry.hash == hash || depth >= int(entry.depth) {
+		*entry = ttEntry{
+			hash:  hash,
+			move:  bestMove,
+			score: int32(toTT(bestScore, ply)),
+			depth: int16(depth),
+			bound: bound,
+		}
+	}
+	return bestScore
+}
+
+// quiesce returns the score of the board's position from the point of
+// view of the side to move, only searching captures and promotions
+// until the position is quiet, so that the evaluation isn't made in
+// the middle of an exchange of pieces.
+func (s *searcher) quiesce(ply, alpha, beta int) int {
+	s.nodes++
+	if s.timeUp() {
+		return 0
+	}
+
+	pos := s.b.Position()
+	if ply >= maxPly {
+		return s.evaluate(pos)
+	}
+	inCheck := s.inCheck()
+
+	// Unless the side to move is in check, it can choose not to
+	// capture anything, so the evaluation is a lower bound.
+	if !inCheck {
+		score := s.evaluate(pos)
+		if score >= beta {
+			return score
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+
+	moves := s.b.LegalMoves()
+	if len(moves) == 0 && inCheck {
+		return -MateScore + ply
+	}
+	if !inCheck {
+		moves = captures(pos, moves)
+	}
+	s.orderMoves(moves, engine.Move{})
+	for _, m := range moves {
+		s.makeMove(m)
+		score := -s.quiesce(ply+1, -beta, -alpha)
+		s.undoMove()
+		if s.stopped {
+			return 0
+		}
+		if score >= beta {
+			return score
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+	return alpha
+}
+
+// evaluate returns the score of position p from the point of view of
+// the side to move.
+func (s *searcher) evaluate(p engine.Position) int {
+	if p.Turn() == engine.Black {
+		return -s.weights.evaluate(p)
+	}
+	return s.weights.evaluate(p)
+}
+
+// captures returns the moves out of moves that capture a piece or
+// promote a pawn to a queen.
+func captures(pos engine.Position, moves []engine.Move) []engine.Move {
+	var caps []engine.Move
+	for _, m := range moves {
+		if _, found := pos.PieceAt(m.To); found || m.Promotion == engine.Queen {
+			caps = append(caps, m)
+			continue
+		}
+		// A pawn moving diagonally to an empty position is an
+		// en passant capture.
+		if piece, _ := pos.PieceAt(m.From); piece.Name == engine.Pawn && m.From.X != m.To.X {
+			caps = append(caps, m)
+		}
+	}
+	return caps
+}
+
+// orderMoves sorts moves so that the moves most likely to be best are
+// searched first, which lets alpha-beta skip more of the other moves.
+//
+// The hash move from an earlier search goes first, followed by captures
+// of the most valuable pieces by the least valuable pieces, followed by
+// promotions and then every other move.
+func (s *searcher) orderMoves(moves []engine.Move, hashMove engine.Move) {
+	pos := s.b.Position()
+	scores := make([]int, len(moves))
+	for i, m := range moves {
+		if m == hashMove {
+			scores[i] = 1 << 20
+		} else if victim, found := pos.PieceAt(m.To); found {
+			attacker, _ := pos.PieceAt(m.From)
+			scores[i] = pieceValues[victim.Name]*10 - pieceValues[attacker.Name]
+		}
+		if m.Promotion != engine.Pawn {
+			scores[i] += pieceValues[m.Promotion]
+		}
+	}
+	sort.Stable(byScore{moves, scores})
+}
+
+// byScore sorts moves by their scores from highest to lowest.
+type byScore struct {
+	moves  []engine.Move
+	scores []int
+}
+
+func (s byScore) Len() int           { return len(s.moves) }
+func (s byScore) Less(i, j int) bool { return s.scores[i] > s.scores[j] }
+func (s byScore) Swap(i, j int) {
+	s.moves[i], s.moves[j] = s.moves[j], s.moves[i]
+	s.scores[i], s.scores[j] = s.scores[j], s.scores[i]
+}
+
+// makeMove makes move m, which must be a legal move, on the search's
+// board.
+func (s *searcher) makeMove(m engine.Move) {
+	if err := s.b.MoveWithPromotion(m.From, m.To, m.Promotion); err != nil {
+		panic("ai: legal move " + m.String() + " failed: " + err.Error())
+	}
+}
+
+// undoMove undoes the last move made by makeMove.
+func (s *searcher) undoMove() {
+	if err := s.b.UndoMove(); err != nil {
+		panic("ai: undo failed: " + err.Error())
+	}
+}
+
+// pv returns the principal variation of up to depth moves, starting
+// with move first and following the best moves stored in the
+// transposition table.
+func (s *searcher) pv(first engine.Move, depth int) []engine.Move {
+	pv := []engine.Move{first}
+	s.makeMove(first)
+	for len(pv) < depth {
+		entry := s.tt[s.b.Hash()%ttSize]
+		if entry.hash != s.b.Hash() || !legal(s.b, entry.move) {
+			break
+		}
+		pv = append(pv, entry.move)
+		s.makeMove(entry.move)
+	}
+	for range pv {
+		s.undoMove()
+	}
+	return pv
+}
+
+// legal reports whether move m is one of the board's legal moves.
+func legal(b *engine.Board, m engine.Move) bool {
+	for _, lm := range b.LegalMovesFrom(m.From) {
+		if lm == m {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package ai
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/radovskyb/chess/engine"
+)
+
+func TestSearch(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fen      string
+		expected string
+	}{
+		{"back rank mate", "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "a1a8"},
+		{"capture hanging queen", "4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1", "d2d5"},
+		{"capture and promote to queen", "3r4/4P3/8/8/8/8/k7/4K3 w - - 0 1", "e7d8q"},
+		{"black mates", "rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq g3 0 2", "d8h4"},
+	}
+	for _, tc := range testCases {
+		b, err := engine.ParseFEN(tc.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := Search(b, Limits{Depth: 3})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if result.Move.String() != tc.expected {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.expected, result.Move)
+		}
+	}
+}
+
+func TestSearchMateInTwo(t *testing.T) {
+	b, err := engine.ParseFEN("7k/8/8/8/8/8/R7/1R4K1 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Search(b, Limits{Depth: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, mate := result.MateIn(); !mate || n != 2 {
+		t.Errorf("expected mate in 2, got score %d", result.Score)
+	}
+	if len(result.PV) != 3 || result.PV[0] != result.Move {
+		t.Errorf("expected a principal variation of 3 moves starting with %s, got %v",
+			result.Move, result.PV)
+	}
+}
+
+func TestSearchAvoidsLosingMaterial(t *testing.T) {
+	// The pawn on d5 is protected by the pawn on c6, so taking it with
+	// the queen loses the queen.
+	b, err := engine.ParseFEN("4k3/8/2p5/3p4/8/8/8/3QK3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Search(b, Limits{Depth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Move.String() == "d1d5" {
+		t.Error("expected search to not take a protected pawn with the queen")
+	}
+}
+
+func TestSearchWeights(t *testing.T) {
+	// White has a queen against a rook.
+	b, err := engine.ParseFEN("r3k3/8/8/8/8/8/8/3QK3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Search(b, Limits{Depth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Score > 1000 {
+		t.Errorf("expected the default weights to score less than 1000, got %d", result.Score)
+	}
+
+	w := DefaultWeights
+	w.Material[engine.Queen] = 5000
+	if result, err = Search(b, Limits{Depth: 1, Weights: &w}); err != nil {
+		t.Fatal(err)
+	}
+	if result.Score < 4000 {
+		t.Errorf("expected the limits' weights to score more than 4000, got %d", result.Score)
+	}
+}
+
+func TestSearchTimeLimit(t *testing.T) {
+	start := time.Now()
+	result, err := Search(engine.NewBoard(), Limits{Time: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected search to stop after 100ms, took %s", elapsed)
+	}
+	if result.Depth < 1 {
+		t.Errorf("expected search to complete at least depth 1, got %d", result.Depth)
+	}
+}
+
+func TestSearchDoesntChangeBoard(t *testing.T) {
+	b := engine.NewBoard()
+	for _, move := range []string{"e4", "e5", "Nf3"} {
+		if err := b.MoveSAN(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.UndoMove(); err != nil {
+		t.Fatal(err)
+	}
+	fen := b.FEN()
+
+	if _, err := Search(b, Limits{Depth: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if b.FEN() != fen {
+		t.Errorf("expected board to still be at %q, got %q", fen, b.FEN())
+	}
+	if err := b.RedoMove(); err != nil {
+		t.Errorf("expected undone move to still be redoable: %v", err)
+	}
+}
+
+func TestSearchNoMoves(t *testing.T) {
+	b, err := engine.ParseFEN("rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq g3 0 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.MoveSAN("Qh4#"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Search(b, Limits{Depth: 1}); err != ErrNoMoves {
+		t.Errorf("expected error %v, got %v", ErrNoMoves, err)
+	}
+}
+
+func TestSearchContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var depths []int
+	info := func(r Result) {
+		depths = append(depths, r.Depth)
+		if r.Depth == 2 {
+			cancel()
+		}
+	}
+	result, err := SearchContext(ctx, engine.NewBoard(), Limits{Infinite: true}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Depth != 2 || len(depths) != 2 || depths[0] != 1 {
+		t.Errorf("expected search to stop after depth 2, got depths %v", depths)
+	}
+}
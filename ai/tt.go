@@ -0,0 +1,52 @@
+package ai
+
+import "github.com/radovskyb/chess/engine"
+
+// ttSize is the number of entries in a transposition table.
+const ttSize = 1 << 18
+
+// Bounds describe how an entry's score relates to the position's real
+// score.
+const (
+	exactBound uint8 = iota
+	lowerBound       // The real score is at least the entry's score.
+	upperBound       // The real score is at most the entry's score.
+)
+
+// A ttEntry holds the result of searching a position, so that it
+// doesn't need to be searched again when it's reached by a different
+// order of moves, and so that it's best move can be searched first
+// when searching it deeper.
+type ttEntry struct {
+	hash  uint64
+	move  engine.Move
+	score int32
+	depth int16
+	bound uint8
+}
+
+// toTT converts a score found ply half moves from the root to a score
+// to store in the transposition table. Checkmate scores are stored as
+// the distance from the position instead of from the root, since the
+// position can be reached at different plies.
+func toTT(score, ply int) int {
+	switch {
+	case score > MateScore-maxPly:
+		return score + ply
+	case score < -MateScore+maxPly:
+		return score - ply
+	}
+	return score
+}
+
+// fromTT converts a score stored in the transposition table back to a
+// score found ply half moves from the root.
+func fromTT(score, ply int) int {
+	switch {
+	case score > MateScore-maxPly:
+		return score - ply
+	case score < -MateScore+maxPly:
+		return score + ply
+	}
+	return score
+}
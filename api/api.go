@@ -0,0 +1,389 @@
+// Package api serves an HTTP JSON API for playing games of chess, so
+// that tools and bots can drive games without linking to the engine.
+//
+// The API's endpoints are:
+//
+//	POST   /games              Create a game, optionally from {"fen": "..."} and
+//	                           with {"time_control": "5+3"}.
+//	GET    /games/{id}         Get a game's position and status.
+//	DELETE /games/{id}         Delete a game.
+//	GET    /games/{id}/moves   List the legal moves.
+//	POST   /games/{id}/moves   Make a move from {"move": "e2e4"} or {"move": "Nf3"}.
+//	POST   /games/{id}/undo    Undo the last move.
+//	GET    /games/{id}/history List the moves made in coordinate notation and SAN.
+//	GET    /games/{id}/pgn     Get the game as PGN.
+//
+// A timed game's clocks run between requests, and a player whose time
+// runs out loses as soon as the next request about the game is made.
+//
+// A request that fails is answered with an Error in the body, such as
+// {"error": {"code": "move_blocked", "message": "..."}}.
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/radovskyb/chess/clock"
+	"github.com/radovskyb/chess/engine"
+	"github.com/radovskyb/chess/pgn"
+)
+
+// DefaultAddr is the address that the API is served on when no address
+// is given.
+const DefaultAddr = ":8081"
+
+// A Server serves the API and holds it's games in memory.
+type Server struct {
+	// routes holds the handlers for the requests about a game, by the
+	// rest of the path after the game's id and then by method.
+	routes map[string]map[string]gameHandler
+
+	mu     sync.Mutex // Guards games and lastID.
+	games  map[string]*game
+	lastID int
+}
+
+// A game is a game held by a Server.
+type game struct {
+	*engine.Game
+
+	// timed holds the game with it's clock if the game is timed, or nil
+	// if it's untimed. Moves in a timed game are made through timed so
+	// that the clock is pressed.
+	timed *clock.Game
+}
+
+// A gameHandler handles a request for the game g with id.
+type gameHandler func(w http.ResponseWriter, r *http.Request, id string, g *game) error
+
+// NewServer creates a Server without any games.
+func NewServer() *Server {
+	s := &Server{games: make(map[string]*game)}
+	s.routes = map[string]map[string]gameHandler{
+		"":        {http.MethodGet: s.getGame, http.MethodDelete: s.deleteGame},
+		"moves":   {http.MethodGet: s.legalMoves, http.MethodPost: s.move},
+		"undo":    {http.MethodPost: s.undo},
+		"history": {http.MethodGet: s.history},
+		"pgn":     {http.MethodGet: s.pgn},
+	}
+	return s
+}
+
+// ServeHTTP serves an API request. The server's games are locked while
+// a request about a game is handled, and any error that it's handler
+// returns is sent as the response.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/games" {
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+		s.createGame(w, r)
+		return
+	}
+
+	rest, found := strings.CutPrefix(r.URL.Path, "/games/")
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	id, action, _ := strings.Cut(rest, "/")
+	handlers, found := s.routes[action]
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	h, found := handlers[r.Method]
+	if !found {
+		var methods []string
+		for method := range handlers {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		methodNotAllowed(w, methods...)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, found := s.games[id]
+	if !found {
+		writeError(w, ErrGameNotFound)
+		return
+	}
+	if g.timed != nil {
+		g.timed.CheckTime()
+	}
+	if err := h(w, r, id, g); err != nil {
+		writeError(w, err)
+	}
+}
+
+// methodNotAllowed responds that the request's method isn't one of the
+// allowed methods.
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+// A Game is the body of a response about a game.
+type Game struct {
+	ID  string `json:"id"`
+	FEN string `json:"fen"`
+
+	// Turn holds the color to move, and Status holds the game's status,
+	// such as ongoing or checkmate.
+	Turn   string `json:"turn"`
+	Status string `json:"status"`
+
+	// Winner holds the color that won the game, if it's ended with a
+	// winner.
+	Winner string `json:"winner,omitempty"`
+
+	// Message holds a message about the game's status, such as white is
+	// in check.
+	Message string `json:"message,omitempty"`
+
+	// LastMove holds the last move that was made, encoded with
+	// engine.MoveInfo's Encode method.
+	LastMove json.RawMessage `json:"last_move,omitempty"`
+
+	// Clock holds the game's clock if it's timed.
+	Clock *Clock `json:"clock,omitempty"`
+}
+
+// A Clock is the clock of a timed game in a response.
+type Clock struct {
+	// Control holds the game's time control, such as 5+3.
+	Control string `json:"control"`
+
+	// White and Black hold the time that each player has left in
+	// milliseconds.
+	White int64 `json:"white"`
+	Black int64 `json:"black"`
+}
+
+// newGame returns the response for the game g with id.
+func newGame(id string, g *game) (Game, error) {
+	status, winner := g.Status()
+	resp := Game{
+		ID:      id,
+		FEN:     g.FEN(),
+		Turn:    g.Turn().String(),
+		Status:  status.String(),
+		Message: g.Message(),
+	}
+	if winner == engine.White || winner == engine.Black {
+		resp.Winner = winner.String()
+	}
+	if m, err := g.LastMove(); err == nil {
+		data, err := m.Encode()
+		if err != nil {
+			return Game{}, err
+		}
+		resp.LastMove = data
+	}
+	if g.timed != nil {
+		c := g.timed.Clock
+		resp.Clock = &Clock{
+			Control: c.Control().String(),
+			White:   c.Remaining(engine.White).Milliseconds(),
+			Black:   c.Remaining(engine.Black).Milliseconds(),
+		}
+	}
+	return resp, nil
+}
+
+// writeGame responds with the game g with id.
+func writeGame(w http.ResponseWriter, status int, id string, g *game) error {
+	resp, err := newGame(id, g)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, status, resp)
+	return nil
+}
+
+// writeJSON responds with v encoded as JSON.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError responds with err.
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, errorStatus(err), struct {
+		Error Error `json:"error"`
+	}{Error{Code: errorCode(err), Message: err.Error()}})
+}
+
+// decodeRequest decodes the JSON body of r into v. An empty body leaves
+// v unchanged.
+func decodeRequest(r *http.Request, v interface{}) error {
+	if r.ContentLength == 0 {
+		return nil
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return ErrInvalidRequest
+	}
+	return nil
+}
+
+// createGame creates a game from the starting position, or from the
+// FEN in the request, which is timed if the request has a time control.
+func (s *Server) createGame(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		FEN         string `json:"fen"`
+		TimeControl string `json:"time_control"`
+	}
+	if err := decodeRequest(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	b := engine.NewBoard()
+	if req.FEN != "" {
+		var err error
+		if b, err = engine.ParseFEN(req.FEN); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	var control clock.Control
+	if req.TimeControl != "" {
+		var err error
+		if control, err = clock.Parse(req.TimeControl); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastID++
+	id := strconv.Itoa(s.lastID)
+	g := &game{Game: engine.NewGame(b)}
+	if len(control) > 0 {
+		g.timed = clock.NewGame(g.Game, clock.New(control))
+	}
+	s.games[id] = g
+
+	w.Header().Set("Location", "/games/"+id)
+	if err := writeGame(w, http.StatusCreated, id, g); err != nil {
+		writeError(w, err)
+	}
+}
+
+func (s *Server) getGame(w http.ResponseWriter, r *http.Request, id string, g *game) error {
+	return writeGame(w, http.StatusOK, id, g)
+}
+
+func (s *Server) deleteGame(w http.ResponseWriter, r *http.Request, id string, g *game) error {
+	delete(s.games, id)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// legalMoves responds with the legal moves in coordinate notation, or
+// none if the game is over.
+func (s *Server) legalMoves(w http.ResponseWriter, r *http.Request, id string, g *game) error {
+	moves := []string{}
+	if !g.Over() {
+		for _, m := range g.LegalMoves() {
+			moves = append(moves, m.String())
+		}
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Moves []string `json:"moves"`
+	}{moves})
+	return nil
+}
+
+// move makes the move in the request, which is in coordinate notation
+// or SAN.
+func (s *Server) move(w http.ResponseWriter, r *http.Request, id string, g *game) error {
+	var req struct {
+		Move string `json:"move"`
+	}
+	if err := decodeRequest(r, &req); err != nil {
+		return err
+	}
+	if req.Move == "" {
+		return ErrInvalidRequest
+	}
+	if g.Over() {
+		return engine.ErrGameOver
+	}
+
+	moveWithPromotion, moveSAN := g.MoveWithPromotion, g.MoveSAN
+	if g.timed != nil {
+		moveWithPromotion, moveSAN = g.timed.MoveWithPromotion, g.timed.MoveSAN
+	}
+	var err error
+	switch m, perr := engine.ParseMove(req.Move); {
+	case perr == nil:
+		err = moveWithPromotion(m.From, m.To, m.Promotion)
+	case perr == engine.ErrInvalidPromotion:
+		// The move is in coordinate notation, but with a piece that
+		// a pawn can't be promoted to, such as a7a8k.
+		err = perr
+	default:
+		err = moveSAN(req.Move)
+	}
+	if err != nil {
+		return err
+	}
+	return writeGame(w, http.StatusOK, id, g)
+}
+
+// undo takes back the last move.
+func (s *Server) undo(w http.ResponseWriter, r *http.Request, id string, g *game) error {
+	undoMove := g.UndoMove
+	if g.timed != nil {
+		undoMove = g.timed.UndoMove
+	}
+	if err := undoMove(); err != nil {
+		return err
+	}
+	return writeGame(w, http.StatusOK, id, g)
+}
+
+// history responds with the moves that have been made in coordinate
+// notation and in SAN.
+func (s *Server) history(w http.ResponseWriter, r *http.Request, id string, g *game) error {
+	resp := struct {
+		Moves []string `json:"moves"`
+		SAN   []string `json:"san"`
+	}{[]string{}, []string{}}
+
+	// The SAN of each move is found by replaying the moves on a copy of
+	// the starting position.
+	b, err := engine.ParseFEN(g.InitialFEN())
+	if err != nil {
+		return err
+	}
+	for _, m := range g.Moves() {
+		if err := b.MoveWithPromotion(m.From, m.To, m.Promotion); err != nil {
+			return err
+		}
+		info, err := b.LastMove()
+		if err != nil {
+			return err
+		}
+		resp.Moves = append(resp.Moves, m.String())
+		resp.SAN = append(resp.SAN, info.SAN)
+	}
+	writeJSON(w, http.StatusOK, resp)
+	return nil
+}
+
+// pgn responds with the game in PGN.
+func (s *Server) pgn(w http.ResponseWriter, r *http.Request, id string, g *game) error {
+	w.Header().Set("Content-Type", "application/x-chess-pgn")
+	return pgn.Write(w, g.Board, pgn.Tag{Name: "Result", Value: pgn.GameResult(g.Game)})
+}
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/radovskyb/chess/engine"
+)
+
+// do sends a request to s and decodes the JSON response into v, if v
+// isn't nil, and returns the response's status code.
+func do(t *testing.T, s *Server, method, path, body string, v interface{}) int {
+	t.Helper()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if v != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s %s: %v: %q", method, path, err, w.Body.String())
+		}
+	}
+	return w.Code
+}
+
+// errorResponse is the body of a response for a request that failed.
+type errorResponse struct {
+	Error Error `json:"error"`
+}
+
+func TestCreateGame(t *testing.T) {
+	s := NewServer()
+
+	var g Game
+	if code := do(t, s, "POST", "/games", "", &g); code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, code)
+	}
+	if g.ID != "1" || g.FEN != engine.StartFEN || g.Turn != "white" || g.Status != "ongoing" {
+		t.Errorf("expected a new game from the starting position, got %+v", g)
+	}
+
+	fen := "4k3/8/8/8/8/8/8/4K2R b K - 0 1"
+	if code := do(t, s, "POST", "/games", `{"fen": "`+fen+`"}`, &g); code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, code)
+	}
+	if g.ID != "2" || g.FEN != fen || g.Turn != "black" {
+		t.Errorf("expected a new game from %s, got %+v", fen, g)
+	}
+
+	if code := do(t, s, "GET", "/games/2", "", &g); code != http.StatusOK || g.FEN != fen {
+		t.Errorf("expected to get game 2, got %d %+v", code, g)
+	}
+	if code := do(t, s, "DELETE", "/games/2", "", nil); code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, code)
+	}
+
+	testCases := []struct {
+		method, path, body string
+		status             int
+		code               string
+	}{
+		{"GET", "/games/2", "", http.StatusNotFound, "game_not_found"},
+		{"POST", "/games", `{"fen": "8/8/8/8/8/8/8/8 w - - 0 1"}`, http.StatusUnprocessableEntity, "invalid_fen"},
+		{"POST", "/games", `{"fen": `, http.StatusBadRequest, "invalid_request"},
+		{"POST", "/games", `{"time_control": "5+x"}`, http.StatusUnprocessableEntity, "invalid_time_control"},
+	}
+	for _, tc := range testCases {
+		var resp errorResponse
+		if status := do(t, s, tc.method, tc.path, tc.body, &resp); status != tc.status ||
+			resp.Error.Code != tc.code || resp.Error.Message == "" {
+			t.Errorf("%s %s: expected %d %s, got %d %+v", tc.method, tc.path,
+				tc.status, tc.code, status, resp)
+		}
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	s := NewServer()
+	do(t, s, "POST", "/games", "", nil)
+
+	testCases := []struct {
+		method, path string
+		status       int
+		allow        string
+	}{
+		{"GET", "/games", http.StatusMethodNotAllowed, "POST"},
+		{"PUT", "/games/1", http.StatusMethodNotAllowed, "DELETE, GET"},
+		{"DELETE", "/games/1/moves", http.StatusMethodNotAllowed, "GET, POST"},
+		{"GET", "/games/1/unknown", http.StatusNotFound, ""},
+		{"GET", "/players", http.StatusNotFound, ""},
+	}
+	for _, tc := range testCases {
+		r := httptest.NewRequest(tc.method, tc.path, nil)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != tc.status || w.Header().Get("Allow") != tc.allow {
+			t.Errorf("%s %s: expected %d with Allow %q, got %d with Allow %q", tc.method, tc.path,
+				tc.status, tc.allow, w.Code, w.Header().Get("Allow"))
+		}
+	}
+}
+
+func TestMove(t *testing.T) {
+	s := NewServer()
+	do(t, s, "POST", "/games", "", nil)
+
+	var moves struct{ Moves []string }
+	do(t, s, "GET", "/games/1/moves", "", &moves)
+	if len(moves.Moves) != 20 {
+		t.Errorf("expected 20 legal moves, got %q", moves.Moves)
+	}
+
+	// Moves in coordinate notation and SAN.
+	var g Game
+	for _, move := range []string{"f2f3", "e5", "g4"} {
+		if code := do(t, s, "POST", "/games/1/moves", `{"move": "`+move+`"}`, &g); code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d", move, http.StatusOK, code)
+		}
+	}
+	var last engine.MoveInfo
+	if err := last.Decode(g.LastMove); err != nil {
+		t.Fatal(err)
+	}
+	if last.SAN != "g4" || g.Turn != "black" {
+		t.Errorf("expected g4 to be the last move, got %+v", last)
+	}
+
+	// Illegal moves are rejected with the engine's errors.
+	testCases := []struct {
+		body   string
+		status int
+		code   string
+	}{
+		{`{"move": "d8d4"}`, http.StatusUnprocessableEntity, "move_blocked"},
+		{`{"move": "e2e4"}`, http.StatusUnprocessableEntity, "opponents_piece"},
+		{`{"move": "Qh9"}`, http.StatusUnprocessableEntity, "invalid_san"},
+		{`{"move": "h7h8k"}`, http.StatusUnprocessableEntity, "invalid_promotion"},
+		{`{"move": ""}`, http.StatusBadRequest, "invalid_request"},
+		{`move`, http.StatusBadRequest, "invalid_request"},
+	}
+	for _, tc := range testCases {
+		var resp errorResponse
+		if status := do(t, s, "POST", "/games/1/moves", tc.body, &resp); status != tc.status ||
+			resp.Error.Code != tc.code {
+			t.Errorf("%s: expected %d %s, got %d %+v", tc.body, tc.status, tc.code, status, resp)
+		}
+	}
+
+	do(t, s, "POST", "/games/1/moves", `{"move": "Qh4#"}`, &g)
+	if g.Status != "checkmate" || g.Winner != "black" || g.Message != "white is in checkmate" {
+		t.Errorf("expected black to checkmate, got %+v", g)
+	}
+	if do(t, s, "GET", "/games/1/moves", "", &moves); len(moves.Moves) != 0 {
+		t.Errorf("expected no legal moves after checkmate, got %q", moves.Moves)
+	}
+	var resp errorResponse
+	do(t, s, "POST", "/games/1/moves", `{"move": "a2a3"}`, &resp)
+	if resp.Error.Code != "game_over" {
+		t.Errorf("expected game_over error, got %+v", resp)
+	}
+}
+
+func TestUndoAndHistory(t *testing.T) {
+	s := NewServer()
+	do(t, s, "POST", "/games", "", nil)
+
+	var resp errorResponse
+	if status := do(t, s, "POST", "/games/1/undo", "", &resp); status != http.StatusUnprocessableEntity ||
+		resp.Error.Code != "no_previous_move" {
+		t.Errorf("expected no_previous_move error, got %d %+v", status, resp)
+	}
+
+	for _, move := range []string{"e4", "d5", "exd5", "Qxd5", "Nc3"} {
+		do(t, s, "POST", "/games/1/moves", `{"move": "`+move+`"}`, nil)
+	}
+	var g Game
+	if do(t, s, "POST", "/games/1/undo", "", &g); g.Turn != "white" {
+		t.Errorf("expected white to move after undo, got %+v", g)
+	}
+
+	var history struct{ Moves, SAN []string }
+	do(t, s, "GET", "/games/1/history", "", &history)
+	if strings.Join(history.Moves, " ") != "e2e4 d7d5 e4d5 d8d5" ||
+		strings.Join(history.SAN, " ") != "e4 d5 exd5 Qxd5" {
+		t.Errorf("expected history of 4 moves, got %+v", history)
+	}
+
+	r := httptest.NewRequest("GET", "/games/1/pgn", nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	body, _ := io.ReadAll(w.Body)
+	if w.Header().Get("Content-Type") != "application/x-chess-pgn" ||
+		!strings.Contains(string(body), "1. e4 d5 2. exd5 Qxd5 *") {
+		t.Errorf("expected the game in PGN, got %q", body)
+	}
+}
+
+func TestTimedGame(t *testing.T) {
+	s := NewServer()
+
+	var g Game
+	if do(t, s, "POST", "/games", "", &g); g.Clock != nil {
+		t.Errorf("expected an untimed game without a clock, got %+v", g.Clock)
+	}
+	do(t, s, "POST", "/games", `{"time_control": "1+2"}`, &g)
+	if g.Clock == nil || g.Clock.Control != "1+2" || g.Clock.White <= 59000 || g.Clock.Black != 60000 {
+		t.Fatalf("expected a clock with a minute each, got %+v", g.Clock)
+	}
+	do(t, s, "POST", "/games/2/moves", `{"move": "e4"}`, &g)
+	if g.Clock.White <= 61000 || g.Clock.White > 62000 {
+		t.Errorf("expected white to get a 2 second increment, got %+v", g.Clock)
+	}
+
+	// A player whose flag falls loses on the next request.
+	do(t, s, "POST", "/games", `{"time_control": "50ms"}`, &g)
+	time.Sleep(100 * time.Millisecond)
+	do(t, s, "GET", "/games/3", "", &g)
+	if g.Status != "timeout" || g.Winner != "black" || g.Clock.White != 0 {
+		t.Errorf("expected white to run out of time, got %+v", g)
+	}
+	var resp errorResponse
+	do(t, s, "POST", "/games/3/moves", `{"move": "e4"}`, &resp)
+	if resp.Error.Code != "game_over" {
+		t.Errorf("expected game_over error, got %+v", resp)
+	}
+
+	// The PGN keeps the result of a game lost on time.
+	r := httptest.NewRequest("GET", "/games/3/pgn", nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if body := w.Body.String(); !strings.Contains(body, `[Result "0-1"]`) ||
+		!strings.HasSuffix(body, "0-1\n\n") {
+		t.Errorf("expected black to win in the PGN, got %q", body)
+	}
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/radovskyb/chess/clock"
+	"github.com/radovskyb/chess/engine"
+)
+
+var (
+	ErrGameNotFound   = errors.New("api: game not found")
+	ErrInvalidRequest = errors.New("api: invalid request body")
+)
+
+// errorCodes holds the code that each error is sent with, so that
+// clients can tell the errors apart without matching their messages.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrGameNotFound, "game_not_found"},
+	{ErrInvalidRequest, "invalid_request"},
+	{engine.ErrInvalidLocation, "invalid_location"},
+	{engine.ErrNoPieceAtPosition, "no_piece_at_position"},
+	{engine.ErrOpponentsPiece, "opponents_piece"},
+	{engine.ErrInvalidPieceMove, "invalid_piece_move"},
+	{engine.ErrOccupiedPosition, "occupied_position"},
+	{engine.ErrMoveBlocked, "move_blocked"},
+	{engine.ErrMovingIntoCheck, "moving_into_check"},
+	{engine.ErrMoveWhileInCheck, "move_while_in_check"},
+	{engine.ErrNoRookToCastleWith, "no_rook_to_castle_with"},
+	{engine.ErrKingOrRookMoved, "king_or_rook_moved"},
+	{engine.ErrCastleWithKingInCheck, "castle_with_king_in_check"},
+	{engine.ErrCastleWithPieceBetween, "castle_with_piece_between"},
+	{engine.ErrCastleMoveThroughCheck, "castle_move_through_check"},
+	{engine.ErrNoPreviousMove, "no_previous_move"},
+	{engine.ErrNoNextMove, "no_next_move"},
+	{engine.ErrNoPawnToPromote, "no_pawn_to_promote"},
+	{engine.ErrPromotionPending, "promotion_pending"},
+	{engine.ErrInvalidPromotion, "invalid_promotion"},
+	{engine.ErrKingTooCloseToKing, "king_too_close_to_king"},
+	{engine.ErrInvalidFEN, "invalid_fen"},
+	{engine.ErrInvalidSAN, "invalid_san"},
+	{engine.ErrAmbiguousSAN, "ambiguous_san"},
+	{engine.ErrGameOver, "game_over"},
+	{engine.ErrNoDrawToClaim, "no_draw_to_claim"},
+	{engine.ErrNoDrawOffer, "no_draw_offer"},
+	{engine.ErrOwnDrawOffer, "own_draw_offer"},
+	{clock.ErrInvalidControl, "invalid_time_control"},
+}
+
+// An Error is the body of a response for a request that failed.
+type Error struct {
+	// Code holds a code for the error, such as move_blocked for
+	// engine.ErrMoveBlocked, or internal_error for an unknown error.
+	Code string `json:"code"`
+
+	// Message holds the error's message.
+	Message string `json:"message"`
+}
+
+// errorCode returns the code for err, or internal_error if it's not
+// one of the errors in errorCodes.
+func errorCode(err error) string {
+	for _, ec := range errorCodes {
+		if errors.Is(err, ec.err) {
+			return ec.code
+		}
+	}
+	return "internal_error"
+}
+
+// errorStatus returns the HTTP status code to respond to a request with
+// when it fails with err.
+func errorStatus(err error) int {
+	switch code := errorCode(err); {
+	case code == "game_not_found":
+		return http.StatusNotFound
+	case code == "invalid_request":
+		return http.StatusBadRequest
+	case code == "internal_error":
+		return http.StatusInternalServerError
+	}
+	// Everything else is a move or position that the engine rejected.
+	return http.StatusUnprocessableEntity
+}
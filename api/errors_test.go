@@ -0,0 +1,39 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/radovskyb/chess/engine"
+)
+
+func TestErrorCodes(t *testing.T) {
+	codes := make(map[string]bool)
+	for _, ec := range errorCodes {
+		if codes[ec.code] {
+			t.Errorf("duplicate error code %s", ec.code)
+		}
+		codes[ec.code] = true
+	}
+
+	testCases := []struct {
+		err    error
+		code   string
+		status int
+	}{
+		{engine.ErrMoveBlocked, "move_blocked", http.StatusUnprocessableEntity},
+		{fmt.Errorf("%w: ambiguous", engine.ErrAmbiguousSAN), "ambiguous_san", http.StatusUnprocessableEntity},
+		{ErrGameNotFound, "game_not_found", http.StatusNotFound},
+		{errors.New("something else"), "internal_error", http.StatusInternalServerError},
+	}
+	for _, tc := range testCases {
+		if code := errorCode(tc.err); code != tc.code {
+			t.Errorf("%v: expected code %s, got %s", tc.err, tc.code, code)
+		}
+		if status := errorStatus(tc.err); status != tc.status {
+			t.Errorf("%v: expected status %d, got %d", tc.err, tc.status, status)
+		}
+	}
+}
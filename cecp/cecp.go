@@ -0,0 +1,312 @@
+// Package cecp implements the Chess Engine Communication Protocol, which
+// is used by xboard, WinBoard and older GUIs and test harnesses to talk
+// to chess engines.
+package cecp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/radovskyb/chess/ai"
+	"github.com/radovskyb/chess/engine"
+	"github.com/radovskyb/chess/uci"
+)
+
+// features holds the protocol version 2 features that are sent to the
+// GUI. Moves are sent in coordinate notation after usermove, and new
+// positions are set up with setboard.
+var features = []string{
+	`myname="` + uci.Name + `"`,
+	"usermove=1",
+	"setboard=1",
+	"ping=1",
+	"san=0",
+	"colors=0",
+	"playother=0",
+	"analyze=0",
+	"sigint=0",
+	"sigterm=0",
+	"done=1",
+}
+
+// defaultMovesToGo is the number of moves that the remaining time is
+// shared between when the time control doesn't have a number of moves.
+const defaultMovesToGo = 30
+
+// Run runs the engine's side of CECP, reading commands from r and writing
+// responses to w until the quit command is read or r has no more input.
+func Run(r io.Reader, w io.Writer) error {
+	e := &session{w: w}
+	e.newGame()
+	defer e.stop(false)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		args := fields[1:]
+		switch fields[0] {
+		case "protover":
+			e.println("feature " + strings.Join(features, " "))
+		case "new":
+			e.stop(false)
+			e.newGame()
+		case "force", "result":
+			// After a result, the engine waits for the next game
+			// in force mode.
+			e.stop(false)
+			e.engine = 2
+		case "go":
+			e.stop(false)
+			e.engine = e.board.Turn()
+			e.think()
+		case "?":
+			e.stop(true)
+		case "usermove":
+			e.stop(false)
+			if len(args) == 0 {
+				e.println("Error (missing move): " + line)
+				continue
+			}
+			if err := e.userMove(args[0]); err != nil {
+				e.println("Illegal move: " + args[0])
+				continue
+			}
+			// The result is only sent when the engine is playing,
+			// which think does before searching.
+			if e.board.Turn() == e.engine {
+				e.think()
+			}
+		case "undo", "remove":
+			// Remove takes back the last moves of both sides, so the
+			// same side gets to move again.
+			e.stop(false)
+			e.reported = false
+			n := 1
+			if fields[0] == "remove" {
+				n = 2
+			}
+			for i := 0; i < n; i++ {
+				if err := e.board.UndoMove(); err != nil {
+					e.println("Error (" + err.Error() + "): " + line)
+					break
+				}
+			}
+		case "setboard":
+			e.stop(false)
+			b, err := engine.ParseFEN(strings.Join(args, " "))
+			if err != nil {
+				e.println("tellusererror Illegal position: " + err.Error())
+				continue
+			}
+			e.board, e.reported = b, false
+		case "ping":
+			e.println("pong " + strings.Join(args, " "))
+		case "sd":
+			e.limits.Depth = intArg(args, 0)
+		case "st":
+			e.limits.Time = time.Duration(intArg(args, 0)) * time.Second
+		case "level":
+			// The base time is used as the time left until the GUI
+			// sends the engine's time.
+			e.movesPerSession = intArg(args, 0)
+			if len(args) > 1 {
+				e.timeLeft = baseTime(args[1])
+			}
+			e.inc = time.Duration(intArg(args, 2)) * time.Second
+		case "time":
+			// The time left on the engine's clock is in centiseconds.
+			e.timeLeft = time.Duration(intArg(args, 0)) * 10 * time.Millisecond
+		case "quit":
+			return nil
+		}
+		// Any other commands, such as xboard, accepted, otim and post,
+		// don't need a response, so they're ignored.
+	}
+	return scanner.Err()
+}
+
+// intArg returns args[i] as an int, or 0 if it isn't one.
+func intArg(args []string, i int) int {
+	if i >= len(args) {
+		return 0
+	}
+	n, _ := strconv.Atoi(args[i])
+	return n
+}
+
+// baseTime returns the base time of a level command, which is either a
+// number of minutes, or minutes and seconds such as 2:30.
+func baseTime(s string) time.Duration {
+	m, sec, _ := strings.Cut(s, ":")
+	minutes, _ := strconv.Atoi(m)
+	seconds, _ := strconv.Atoi(sec)
+	return time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second
+}
+
+// A session holds the state of the engine while it's running CECP.
+type session struct {
+	mu sync.Mutex // Guards writes to w and discard.
+	w  io.Writer
+
+	board *engine.Board
+
+	// reported is set once the game's result has been sent, so that it's
+	// only sent once for each game.
+	reported bool
+
+	// engine holds the color that the engine plays, or 2 in force mode
+	// when it doesn't play either color.
+	engine engine.Color
+
+	// limits holds the depth and time per move set by sd and st.
+	limits ai.Limits
+
+	// movesPerSession and inc hold the number of moves in each time
+	// control and the increment after each move set by level, and
+	// timeLeft holds the time left on the engine's clock.
+	movesPerSession int
+	inc             time.Duration
+	timeLeft        time.Duration
+
+	// cancel stops the search that's currently running and done is
+	// closed when it has finished, or they're nil if there's no search
+	// running. If discard is set when the search stops, it doesn't make
+	// it's move.
+	cancel  context.CancelFunc
+	done    chan struct{}
+	discard bool
+}
+
+// println writes a line to the GUI.
+func (e *session) println(line string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fmt.Fprintln(e.w, line)
+}
+
+// newGame sets up a new game where the engine plays black, and removes
+// the depth limit like the new command should.
+func (e *session) newGame() {
+	e.board, e.reported = engine.NewBoard(), false
+	e.engine = engine.Black
+	e.limits.Depth = 0
+}
+
+// userMove makes the move m in coordinate notation for the user.
+func (e *session) userMove(m string) error {
+	if len(m) < 4 {
+		return fmt.Errorf("invalid move %q", m)
+	}
+	return e.board.MoveByLocation(m[:2], m[2:])
+}
+
+// gameOver reports whether the game has ended and sends it's result if
+// it has, unless it's already been sent. The engine claims a draw as soon
+// as it's allowed to.
+func (e *session) gameOver() bool {
+	g := engine.NewGame(e.board)
+	status, winner := g.Status()
+	if status == engine.StatusOngoing && !e.board.DrawReason().Claimable() {
+		return false
+	}
+	if e.reported {
+		return true
+	}
+	e.reported = true
+
+	switch status {
+	case engine.StatusCheckmate:
+		if winner == engine.White {
+			e.println("1-0 {White mates}")
+		} else {
+			e.println("0-1 {Black mates}")
+		}
+	case engine.StatusStalemate:
+		e.println("1/2-1/2 {Stalemate}")
+	default:
+		// Including a draw that the engine claims.
+		e.println("1/2-1/2 {Draw by " + e.board.DrawReason().String() + "}")
+	}
+	return true
+}
+
+// think starts searching for the engine's move in the background. The
+// move is made and sent to the GUI when the search finishes, or when it's
+// told to move now.
+func (e *session) think() {
+	if e.gameOver() {
+		return
+	}
+	limits := e.searchLimits()
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel, e.done, e.discard = cancel, make(chan struct{}), false
+
+	done := e.done
+	go func() {
+		defer close(done)
+		result, err := ai.SearchContext(ctx, e.board, limits, nil)
+
+		e.mu.Lock()
+		discard := e.discard
+		e.mu.Unlock()
+		if discard {
+			return
+		}
+		if err != nil {
+			e.println("Error (" + err.Error() + "): go")
+			return
+		}
+		m := result.Move
+		if err := e.board.MoveWithPromotion(m.From, m.To, m.Promotion); err != nil {
+			e.println("Error (" + err.Error() + "): go")
+			return
+		}
+		e.println("move " + m.String())
+		e.gameOver()
+	}()
+}
+
+// stop stops the search that's currently running, if there is one, and
+// waits for it to finish. The engine makes the move it has found so far
+// if move is true.
+func (e *session) stop(move bool) {
+	if e.cancel == nil {
+		return
+	}
+	e.mu.Lock()
+	e.discard = !move
+	e.mu.Unlock()
+	e.cancel()
+	<-e.done
+	e.cancel, e.done = nil, nil
+}
+
+// searchLimits returns the limits for the engine's next search. When
+// there's no time per move set with st, an even share of the time left
+// for the moves until the next time control is used, plus most of the
+// increment.
+func (e *session) searchLimits() ai.Limits {
+	limits := e.limits
+	if limits.Time > 0 || e.timeLeft <= 0 {
+		return limits
+	}
+	movesToGo := defaultMovesToGo
+	if e.movesPerSession > 0 {
+		movesToGo = e.movesPerSession - (e.board.FullMoveNumber()-1)%e.movesPerSession
+	}
+	limits.Time = e.timeLeft/time.Duration(movesToGo) + e.inc*3/4
+	if limits.Time >= e.timeLeft {
+		limits.Time = e.timeLeft / 2
+	}
+	return limits
+}
@@ -0,0 +1,216 @@
+package cecp
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/radovskyb/chess/ai"
+	"github.com/radovskyb/chess/engine"
+)
+
+// A testGUI sends commands to an engine running Run and reads it's
+// responses.
+type testGUI struct {
+	t    *testing.T
+	in   *io.PipeWriter
+	out  *bufio.Scanner
+	done chan error
+}
+
+func newTestGUI(t *testing.T) *testGUI {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	g := &testGUI{t: t, in: inW, out: bufio.NewScanner(outR), done: make(chan error)}
+	go func() {
+		err := Run(inR, outW)
+		outW.Close()
+		g.done <- err
+	}()
+	return g
+}
+
+func (g *testGUI) send(cmd string) {
+	g.t.Helper()
+	if _, err := io.WriteString(g.in, cmd+"\n"); err != nil {
+		g.t.Fatal(err)
+	}
+}
+
+// expect reads the next line from the engine and checks that it's
+// expected.
+func (g *testGUI) expect(expected string) {
+	g.t.Helper()
+	if !g.out.Scan() {
+		g.t.Fatalf("expected %q, got no more lines", expected)
+	}
+
+	if line := g.out.Text(); line != expected {
+		g.t.Errorf("expected %q, got %q", expected, line)
+	}
+}
+
+// sync waits for the engine to finish handling the commands sent before
+// it, using ping.
+func (g *testGUI) sync() {
+	g.t.Helper()
+	g.send("ping 1")
+	g.expect("pong 1")
+}
+
+func (g *testGUI) quit() {
+	g.t.Helper()
+	g.send("quit")
+	if err := <-g.done; err != nil {
+		g.t.Error(err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	g := newTestGUI(t)
+
+	g.send("xboard")
+	g.send("protover 2")
+	if !g.out.Scan() || !strings.HasPrefix(g.out.Text(), "feature ") ||
+		!strings.HasSuffix(g.out.Text(), " done=1") {
+		t.Fatalf("expected features ending with done=1, got %q", g.out.Text())
+	}
+	g.send("accepted usermove")
+
+	// The engine plays black in a new game, and answers the user's moves.
+	g.send("new")
+	g.send("sd 1")
+	g.send("usermove e2e4")
+	if !g.out.Scan() || !strings.HasPrefix(g.out.Text(), "move ") {
+		t.Fatalf("expected the engine to move, got %q", g.out.Text())
+	}
+
+	// An illegal move is rejected.
+	g.send("usermove e4e6")
+	g.expect("Illegal move: e4e6")
+
+	// In force mode the engine only checks the user's moves, and undo
+	// and remove take them back.
+	g.send("force")
+	g.send("usermove g1f3")
+	g.send("usermove h7h5")
+	g.send("undo")
+	g.send("remove")
+	g.sync()
+
+	// Black is to move after 1. e4 and the engine's move.
+	g.send("usermove g1f3")
+	g.expect("Illegal move: g1f3")
+
+	g.send("new")
+	g.send("undo")
+	g.expect("Error (error: no previous move available): undo")
+
+	g.quit()
+}
+
+func TestRunMates(t *testing.T) {
+	g := newTestGUI(t)
+
+	// Black to move can checkmate with Qh4#.
+	g.send("force")
+	g.send("setboard rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq g3 0 2")
+	g.send("sd 2")
+	g.send("go")
+	g.expect("move d8h4")
+	g.expect("0-1 {Black mates}")
+
+	// The result is only sent once.
+	g.send("go")
+	g.sync()
+
+	// The user's move can end the game too, which is sent when the
+	// engine is playing the other side, but not in force mode.
+	g.send("force")
+	g.send("setboard rnbqkbnr/ppppp2p/5p2/6p1/4P3/3P4/PPP2PPP/RNBQKBNR w KQkq g6 0 3")
+	g.send("usermove d1h5")
+	g.sync()
+	g.send("new")
+	g.send("setboard rnbqkbnr/ppppp2p/5p2/6p1/4P3/3P4/PPP2PPP/RNBQKBNR w KQkq g6 0 3")
+	g.send("usermove d1h5")
+	g.expect("1-0 {White mates}")
+	g.send("go")
+	g.sync()
+
+	g.send("setboard 8/8/8/8/8/8/8/8 w - - 0 1")
+	if !g.out.Scan() || !strings.HasPrefix(g.out.Text(), "tellusererror Illegal position") {
+		t.Errorf("expected an illegal position error, got %q", g.out.Text())
+	}
+
+	g.quit()
+}
+
+func TestRunMoveNow(t *testing.T) {
+	g := newTestGUI(t)
+
+	// The engine is told to move now long before it runs out of time.
+	g.send("st 100")
+	g.send("go")
+	time.Sleep(50 * time.Millisecond)
+	g.sync()
+	g.send("?")
+	if !g.out.Scan() || len(g.out.Text()) != len("move e2e4") {
+		t.Errorf("expected a move, got %q", g.out.Text())
+	}
+
+	// A search that's stopped by force doesn't make it's move.
+	g.send("go")
+	g.send("force")
+	g.sync()
+
+	g.quit()
+}
+
+func TestBaseTime(t *testing.T) {
+	testCases := []struct {
+		base     string
+		expected time.Duration
+	}{
+		{"5", 5 * time.Minute},
+		{"2:30", 2*time.Minute + 30*time.Second},
+		{"0:15", 15 * time.Second},
+	}
+	for _, tc := range testCases {
+		if d := baseTime(tc.base); d != tc.expected {
+			t.Errorf("%s: expected %s, got %s", tc.base, tc.expected, d)
+		}
+	}
+}
+
+func TestSearchLimits(t *testing.T) {
+	testCases := []struct {
+		fen      string
+		e        *session
+		expected ai.Limits
+	}{
+		{"", &session{limits: ai.Limits{Depth: 5}}, ai.Limits{Depth: 5}},
+		{"", &session{limits: ai.Limits{Time: time.Second}, timeLeft: time.Minute},
+			ai.Limits{Time: time.Second}},
+		{"", &session{timeLeft: time.Minute}, ai.Limits{Time: 2 * time.Second}},
+		{"", &session{timeLeft: time.Minute, movesPerSession: 40, inc: 2 * time.Second},
+			ai.Limits{Time: 3 * time.Second}},
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 36", &session{timeLeft: time.Minute, movesPerSession: 40},
+			ai.Limits{Time: 12 * time.Second}},
+		{"", &session{timeLeft: 100 * time.Millisecond, inc: time.Second},
+			ai.Limits{Time: 50 * time.Millisecond}},
+	}
+	for _, tc := range testCases {
+		tc.e.board = engine.NewBoard()
+		if tc.fen != "" {
+			var err error
+			if tc.e.board, err = engine.ParseFEN(tc.fen); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if limits := tc.e.searchLimits(); limits != tc.expected {
+			t.Errorf("%+v: expected %+v, got %+v", tc.e, tc.expected, limits)
+		}
+	}
+}
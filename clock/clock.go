@@ -0,0 +1,167 @@
+package clock
+
+import (
+	"time"
+
+	"github.com/radovskyb/chess/engine"
+)
+
+// defaultMovesToGo is the number of moves that the remaining time is
+// shared between by Budget when a stage lasts for the rest of the game.
+const defaultMovesToGo = 30
+
+// A Clock is a chess clock that keeps each player's time for a time
+// control. Only one player's clock runs at a time, and pressing the clock
+// after a move switches it to the other player.
+type Clock struct {
+	control Control
+
+	// remaining holds each player's time left, not counting the time
+	// that's been used since the running clock was started.
+	remaining [2]time.Duration
+
+	// stage holds the index of each player's current stage in control,
+	// and moves holds the number of moves they've made in it.
+	stage [2]int
+	moves [2]int
+
+	// running holds the color whose clock is running, or 2 if the clock
+	// is stopped, and started holds when it was started.
+	running engine.Color
+	started time.Time
+
+	// now returns the current time, and is only replaced by tests.
+	now func() time.Time
+}
+
+// New creates a stopped Clock for control, which must have at least one
+// stage, with each player's time set to the first stage's time.
+func New(control Control) *Clock {
+	c := &Clock{control: control, running: 2, now: time.Now}
+	c.remaining[engine.Black] = control[0].Time
+	c.remaining[engine.White] = control[0].Time
+	return c
+}
+
+// Control returns the clock's time control.
+func (c *Clock) Control() Control {
+	return c.control
+}
+
+// Start starts color's clock, stopping the other player's clock first if
+// it's running. No moves are counted and no increments are added.
+func (c *Clock) Start(color engine.Color) {
+	c.Stop()
+	c.running, c.started = color, c.now()
+}
+
+// Stop stops the clock, charging the running player for the time that
+// they've used. No moves are counted and no increments are added.
+func (c *Clock) Stop() {
+	if c.running > engine.White {
+		return
+	}
+	c.remaining[c.running] = c.Remaining(c.running)
+	c.running = 2
+}
+
+// Running returns the color whose clock is running, or 2 if the clock is
+// stopped.
+func (c *Clock) Running() engine.Color {
+	return c.running
+}
+
+// Press is called after the running player makes a move. It charges them
+// for the time that they used, adds any increment or Bronstein delay,
+// moves them on to the next stage when they've made the stage's moves,
+// and starts the other player's clock.
+//
+// If the running player has run out of time, the clock is stopped
+// instead.
+func (c *Clock) Press() {
+	color := c.running
+	if color > engine.White {
+		return
+	}
+	now := c.now()
+	elapsed := now.Sub(c.started)
+	stage := c.control[c.stage[color]]
+
+	c.remaining[color] -= used(stage, elapsed)
+	if c.remaining[color] <= 0 {
+		c.remaining[color], c.running = 0, 2
+		return
+	}
+	if stage.Bronstein {
+		c.remaining[color] += min(elapsed, stage.Delay)
+	}
+	c.remaining[color] += stage.Increment
+
+	c.moves[color]++
+	if stage.Moves > 0 && c.moves[color] == stage.Moves {
+		c.moves[color] = 0
+		// The last stage starts again once it's moves are made.
+		if c.stage[color] < len(c.control)-1 {
+			c.stage[color]++
+		}
+		c.remaining[color] += c.control[c.stage[color]].Time
+	}
+	c.running, c.started = color^1, now
+}
+
+// Remaining returns the time that color has left.
+func (c *Clock) Remaining(color engine.Color) time.Duration {
+	remaining := c.remaining[color]
+	if color == c.running {
+		elapsed := c.now().Sub(c.started)
+		remaining -= used(c.control[c.stage[color]], elapsed)
+	}
+	return max(remaining, 0)
+}
+
+// Flagged reports whether color has run out of time.
+func (c *Clock) Flagged(color engine.Color) bool {
+	return c.Remaining(color) == 0
+}
+
+// MovesToGo returns the number of moves that color has left to make in
+// their current stage, or 0 if the stage lasts for the rest of the game.
+func (c *Clock) MovesToGo(color engine.Color) int {
+	stage := c.control[c.stage[color]]
+	if stage.Moves == 0 {
+		return 0
+	}
+	return stage.Moves - c.moves[color]
+}
+
+// Increment returns the increment that color gets after each move in
+// their current stage.
+func (c *Clock) Increment(color engine.Color) time.Duration {
+	return c.control[c.stage[color]].Increment
+}
+
+// Budget returns the time for color to spend on their next move, which
+// is an even share of their remaining time for each of the moves left in
+// the stage, plus most of the increment.
+func (c *Clock) Budget(color engine.Color) time.Duration {
+	movesToGo := c.MovesToGo(color)
+	if movesToGo == 0 {
+		movesToGo = defaultMovesToGo
+	}
+	remaining := c.Remaining(color)
+	budget := remaining/time.Duration(movesToGo) + c.Increment(color)*3/4
+	if budget >= remaining {
+		budget = remaining / 2
+	}
+	return budget
+}
+
+// used returns the time that's taken off a player's clock for a move
+// that took elapsed in stage. With a simple delay, the clock doesn't
+// count down until the delay has passed.
+func used(stage Stage, elapsed time.Duration) time.Duration {
+	if stage.Bronstein {
+		return elapsed
+	}
+	return max(elapsed-stage.Delay, 0)
+}
@@ -0,0 +1,134 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/radovskyb/chess/engine"
+)
+
+// fakeClock is a time source for tests that only moves when it's told to.
+type fakeClock struct {
+	t time.Time
+}
+
+func (f *fakeClock) now() time.Time {
+	return f.t
+}
+
+func (f *fakeClock) advance(d time.Duration) {
+	f.t = f.t.Add(d)
+}
+
+// newTestClock creates a Clock for the time control s that uses a fake
+// time source.
+func newTestClock(t *testing.T, s string) (*Clock, *fakeClock) {
+	t.Helper()
+	control, err := Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeClock{t: time.Unix(0, 0)}
+	c := New(control)
+	c.now = f.now
+	return c, f
+}
+
+func TestClock(t *testing.T) {
+	// Each move is made after the time in moves, starting with white.
+	testCases := []struct {
+		control      string
+		moves        []time.Duration
+		white, black time.Duration
+	}{
+		// Sudden death.
+		{"5", []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second},
+			4*time.Minute + 20*time.Second, 4*time.Minute + 40*time.Second},
+		// A Fischer increment is added after every move.
+		{"3+2", []time.Duration{10 * time.Second, 1 * time.Second},
+			2*time.Minute + 52*time.Second, 3*time.Minute + time.Second},
+		// A simple delay isn't taken off the clock.
+		{"5+d3", []time.Duration{2 * time.Second, 10 * time.Second},
+			5 * time.Minute, 4*time.Minute + 53*time.Second},
+		// A Bronstein delay gives back up to the delay.
+		{"5+b3", []time.Duration{2 * time.Second, 10 * time.Second},
+			5 * time.Minute, 4*time.Minute + 53*time.Second},
+		// The next stage's time is added after the stage's moves.
+		{"2/10+1:5", []time.Duration{time.Minute, time.Minute, time.Minute, time.Minute, time.Minute},
+			12*time.Minute + 2*time.Second, 13*time.Minute + 2*time.Second},
+		// The last stage starts again after it's moves.
+		{"1/1:1/2", []time.Duration{30 * time.Second, 30 * time.Second, 30 * time.Second},
+			4 * time.Minute, 2*time.Minute + 30*time.Second},
+	}
+	for _, tc := range testCases {
+		c, f := newTestClock(t, tc.control)
+		c.Start(engine.White)
+		for _, d := range tc.moves {
+			f.advance(d)
+			c.Press()
+		}
+		if white, black := c.Remaining(engine.White), c.Remaining(engine.Black); white != tc.white || black != tc.black {
+			t.Errorf("%s: expected %v and %v remaining, got %v and %v",
+				tc.control, tc.white, tc.black, white, black)
+		}
+	}
+}
+
+func TestClockRunning(t *testing.T) {
+	c, f := newTestClock(t, "1/1:1+d5")
+	if running := c.Running(); running != 2 {
+		t.Errorf("expected the clock to be stopped, got %s running", running)
+	}
+
+	c.Start(engine.White)
+	f.advance(20 * time.Second)
+	if remaining := c.Remaining(engine.White); remaining != 40*time.Second {
+		t.Errorf("expected 40s remaining, got %v", remaining)
+	}
+	if movesToGo := c.MovesToGo(engine.White); movesToGo != 1 {
+		t.Errorf("expected 1 move to go, got %d", movesToGo)
+	}
+	c.Press()
+	if running := c.Running(); running != engine.Black {
+		t.Errorf("expected black's clock to be running, got %s", running)
+	}
+	if movesToGo := c.MovesToGo(engine.White); movesToGo != 0 {
+		t.Errorf("expected no moves to go in the last stage, got %d", movesToGo)
+	}
+
+	// Stopping and starting the clock doesn't count a move.
+	f.advance(10 * time.Second)
+	c.Stop()
+	f.advance(time.Hour)
+	c.Start(engine.Black)
+	if remaining, movesToGo := c.Remaining(engine.Black), c.MovesToGo(engine.Black); remaining != 50*time.Second || movesToGo != 1 {
+		t.Errorf("expected 50s remaining and 1 move to go, got %v and %d", remaining, movesToGo)
+	}
+
+	f.advance(50 * time.Second)
+	if !c.Flagged(engine.Black) || c.Flagged(engine.White) {
+		t.Error("expected black to have run out of time")
+	}
+	c.Press()
+	if running, remaining := c.Running(), c.Remaining(engine.Black); running != 2 || remaining != 0 {
+		t.Errorf("expected the clock to stop with no time left, got %s running and %v left", running, remaining)
+	}
+}
+
+func TestClockBudget(t *testing.T) {
+	testCases := []struct {
+		control string
+		budget  time.Duration
+	}{
+		{"5", 10 * time.Second},
+		{"5+2", 11500 * time.Millisecond},
+		{"40/90+30:30+30", 2*time.Minute + 37500*time.Millisecond},
+		{"1/1s+1", 500 * time.Millisecond},
+	}
+	for _, tc := range testCases {
+		c, _ := newTestClock(t, tc.control)
+		if budget := c.Budget(engine.White); budget != tc.budget {
+			t.Errorf("%s: expected a budget of %v, got %v", tc.control, tc.budget, budget)
+		}
+	}
+}
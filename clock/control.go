@@ -0,0 +1,157 @@
+// Package clock implements chess clocks and time controls, including
+// sudden death, Fischer increments, Bronstein and simple delays, and
+// time controls with more than one stage.
+package clock
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidControl = errors.New("clock: invalid time control")
+
+// A Stage is one stage of a time control.
+type Stage struct {
+	// Moves holds the number of moves that each player has to make in
+	// the stage, or 0 if the stage lasts for the rest of the game.
+	Moves int
+
+	// Time holds the time that's added to each player's clock at the
+	// start of the stage.
+	Time time.Duration
+
+	// Increment holds the time that's added to a player's clock after
+	// each of their moves, which is a Fischer increment.
+	Increment time.Duration
+
+	// Delay holds the time at the start of each move before a player's
+	// clock starts counting down, which is a simple delay. If Bronstein
+	// is set, the clock counts down straight away instead, and up to
+	// Delay of the time used for each move is added back after it.
+	Delay     time.Duration
+	Bronstein bool
+}
+
+// A Control is a time control, made of one or more stages. After the
+// last stage's moves are made, the last stage starts again.
+type Control []Stage
+
+// Parse parses a time control, with each stage separated by a colon.
+// A stage is written as an optional number of moves and a slash, the
+// stage's time, and an optional plus sign followed by an increment, d and
+// a simple delay, or b and a Bronstein delay.
+//
+// Times are written as durations, such as 90m or 1h30m, or as a number of
+// minutes for the stage's time and seconds for increments and delays.
+// For example:
+//
+//	5          5 minutes sudden death
+//	3+2        3 minutes with a 2 second increment
+//	5m+d3s     5 minutes with a 3 second simple delay
+//	25+b10     25 minutes with a 10 second Bronstein delay
+//	40/90+30:30+30
+//	           90 minutes for 40 moves then 30 minutes for the rest of
+//	           the game, with a 30 second increment
+func Parse(s string) (Control, error) {
+	var control Control
+	stages := strings.Split(s, ":")
+	for i, field := range stages {
+		stage, err := parseStage(strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+		// Only the last stage can last for the rest of the game.
+		if stage.Moves == 0 && i < len(stages)-1 {
+			return nil, fmt.Errorf("%w: stage %q must have a number of moves", ErrInvalidControl, field)
+		}
+		control = append(control, stage)
+	}
+	if control[0].Time <= 0 {
+		return nil, fmt.Errorf("%w: first stage must have some time", ErrInvalidControl)
+	}
+	return control, nil
+}
+
+// parseStage parses a single stage of a time control.
+func parseStage(s string) (Stage, error) {
+	var stage Stage
+	if moves, rest, found := strings.Cut(s, "/"); found {
+		n, err := strconv.Atoi(moves)
+		if err != nil || n <= 0 {
+			return Stage{}, fmt.Errorf("%w: invalid number of moves %q", ErrInvalidControl, moves)
+		}
+		stage.Moves, s = n, rest
+	}
+
+	base, extra, found := strings.Cut(s, "+")
+	var err error
+	if stage.Time, err = parseDuration(base, time.Minute); err != nil {
+		return Stage{}, err
+	}
+	if !found {
+		return stage, nil
+	}
+
+	d := &stage.Increment
+	switch {
+	case strings.HasPrefix(extra, "d"):
+		d, extra = &stage.Delay, extra[1:]
+	case strings.HasPrefix(extra, "b"):
+		d, extra = &stage.Delay, extra[1:]
+		stage.Bronstein = true
+	}
+	if *d, err = parseDuration(extra, time.Second); err != nil {
+		return Stage{}, err
+	}
+	return stage, nil
+}
+
+// parseDuration parses a duration, or a number of units.
+func parseDuration(s string, unit time.Duration) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		n, nerr := strconv.Atoi(s)
+		if nerr != nil {
+			return 0, fmt.Errorf("%w: invalid time %q", ErrInvalidControl, s)
+		}
+		d = time.Duration(n) * unit
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%w: negative time %q", ErrInvalidControl, s)
+	}
+	return d, nil
+}
+
+// String returns a time control in the format that Parse parses.
+func (c Control) String() string {
+	stages := make([]string, len(c))
+	for i, stage := range c {
+		var s string
+		if stage.Moves > 0 {
+			s = strconv.Itoa(stage.Moves) + "/"
+		}
+		s += formatDuration(stage.Time, time.Minute)
+		switch {
+		case stage.Increment > 0:
+			s += "+" + formatDuration(stage.Increment, time.Second)
+		case stage.Delay > 0 && stage.Bronstein:
+			s += "+b" + formatDuration(stage.Delay, time.Second)
+		case stage.Delay > 0:
+			s += "+d" + formatDuration(stage.Delay, time.Second)
+		}
+		stages[i] = s
+	}
+	return strings.Join(stages, ":")
+}
+
+// formatDuration returns d as a number of units if it's a whole number
+// of them, or as a duration otherwise.
+func formatDuration(d, unit time.Duration) string {
+	if d%unit == 0 {
+		return strconv.Itoa(int(d / unit))
+	}
+	return d.String()
+}
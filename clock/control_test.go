@@ -0,0 +1,50 @@
+package clock
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		s       string
+		control Control
+		str     string
+	}{
+		{"5", Control{{Time: 5 * time.Minute}}, "5"},
+		{"3+2", Control{{Time: 3 * time.Minute, Increment: 2 * time.Second}}, "3+2"},
+		{"90s+500ms", Control{{Time: 90 * time.Second, Increment: 500 * time.Millisecond}}, "1m30s+500ms"},
+		{"5m+d3s", Control{{Time: 5 * time.Minute, Delay: 3 * time.Second}}, "5+d3"},
+		{"25+b10", Control{{Time: 25 * time.Minute, Delay: 10 * time.Second, Bronstein: true}}, "25+b10"},
+		{"40/90+30:30+30", Control{
+			{Moves: 40, Time: 90 * time.Minute, Increment: 30 * time.Second},
+			{Time: 30 * time.Minute, Increment: 30 * time.Second},
+		}, "40/90+30:30+30"},
+		{"40/2h:20/1h:30m", Control{
+			{Moves: 40, Time: 2 * time.Hour},
+			{Moves: 20, Time: time.Hour},
+			{Time: 30 * time.Minute},
+		}, "40/120:20/60:30"},
+	}
+	for _, tc := range testCases {
+		control, err := Parse(tc.s)
+		if err != nil {
+			t.Errorf("%s: %v", tc.s, err)
+			continue
+		}
+		if !reflect.DeepEqual(control, tc.control) {
+			t.Errorf("%s: expected %+v, got %+v", tc.s, tc.control, control)
+		}
+		if str := control.String(); str != tc.str {
+			t.Errorf("%s: expected string %q, got %q", tc.s, tc.str, str)
+		}
+	}
+
+	for _, s := range []string{"", "0", "x", "5+x", "-5", "0/5", "40/90:x/30", "40/90+d", "90:30"} {
+		if _, err := Parse(s); !errors.Is(err, ErrInvalidControl) {
+			t.Errorf("%q: expected error %v, got %v", s, ErrInvalidControl, err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package clock
+
+import "github.com/radovskyb/chess/engine"
+
+// A Game is a game of chess that's played with a clock. The clock runs
+// for the side to move, and is pressed after each move that's made with
+// the Game's own move methods. Moves made straight on the Board don't
+// press the clock.
+type Game struct {
+	*engine.Game
+	Clock *Clock
+}
+
+// NewGame creates a Game for g and c, and starts c for the side to move.
+func NewGame(g *engine.Game, c *Clock) *Game {
+	c.Start(g.Turn())
+	return &Game{Game: g, Clock: c}
+}
+
+// CheckTime ends the game if the side to move has run out of time, and
+// reports whether the game is over. The clock is stopped once the game
+// is over.
+func (g *Game) CheckTime() bool {
+	if !g.Over() && g.Clock.Flagged(g.Turn()) {
+		g.Timeout(g.Turn())
+	}
+	if g.Over() {
+		g.Clock.Stop()
+		return true
+	}
+	return false
+}
+
+// move makes a move with f, if the side to move hasn't run out of time,
+// and presses the clock after it.
+func (g *Game) move(f func() error) error {
+	if g.CheckTime() {
+		return engine.ErrGameOver
+	}
+	color := g.Clock.Running()
+	if err := f(); err != nil {
+		return err
+	}
+	// A move isn't finished until a pawn that reached the last rank has
+	// been promoted.
+	if mustPromote, _ := g.MustPromote(); !mustPromote {
+		g.Clock.Press()
+		// A player whose time ran out during their move loses on time,
+		// unless the move ended the game first.
+		if g.Clock.Flagged(color) && !g.Over() {
+			g.Timeout(color)
+		}
+	}
+	g.CheckTime()
+	return nil
+}
+
+// Move moves the piece at p1 to p2 and presses the clock.
+func (g *Game) Move(p1, p2 engine.Pos) error {
+	return g.move(func() error { return g.Game.Move(p1, p2) })
+}
+
+// MoveWithPromotion moves the piece at p1 to p2, promoting a pawn to
+// promo, and presses the clock.
+func (g *Game) MoveWithPromotion(p1, p2 engine.Pos, promo engine.PieceName) error {
+	return g.move(func() error { return g.Game.MoveWithPromotion(p1, p2, promo) })
+}
+
+// MoveByLocation makes a move from loc1 to loc2 and presses the clock.
+func (g *Game) MoveByLocation(loc1, loc2 string) error {
+	return g.move(func() error { return g.Game.MoveByLocation(loc1, loc2) })
+}
+
+// MoveSAN makes a move in SAN and presses the clock.
+func (g *Game) MoveSAN(san string) error {
+	return g.move(func() error { return g.Game.MoveSAN(san) })
+}
+
+// PromotePawn promotes the pawn that's waiting to be promoted and
+// presses the clock.
+func (g *Game) PromotePawn(to engine.PieceName) error {
+	return g.move(func() error { return g.Game.PromotePawn(to) })
+}
+
+// UndoMove takes back the last move and switches the clock back to the
+// side to move, without giving back any time.
+func (g *Game) UndoMove() error {
+	if err := g.Game.UndoMove(); err != nil {
+		return err
+	}
+	g.Clock.Start(g.Turn())
+	g.CheckTime()
+	return nil
+}
+
+// RedoMove makes the last move that was taken back again and switches
+// the clock to the side to move.
+func (g *Game) RedoMove() error {
+	if err := g.Game.RedoMove(); err != nil {
+		return err
+	}
+	g.Clock.Start(g.Turn())
+	g.CheckTime()
+	return nil
+}
@@ -0,0 +1,112 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/radovskyb/chess/engine"
+)
+
+// newTestGame creates a Game from fen for the time control s that uses a
+// fake time source.
+func newTestGame(t *testing.T, fen, s string) (*Game, *fakeClock) {
+	t.Helper()
+	b, err := engine.ParseFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, f := newTestClock(t, s)
+	return NewGame(engine.NewGame(b), c), f
+}
+
+func TestGame(t *testing.T) {
+	g, f := newTestGame(t, engine.StartFEN, "1+1")
+	if running := g.Clock.Running(); running != engine.White {
+		t.Fatalf("expected white's clock to be running, got %s", running)
+	}
+
+	f.advance(10 * time.Second)
+	if err := g.MoveSAN("e4"); err != nil {
+		t.Fatal(err)
+	}
+	if running, remaining := g.Clock.Running(), g.Clock.Remaining(engine.White); running != engine.Black ||
+		remaining != 51*time.Second {
+		t.Errorf("expected black's clock to be running with white on 51s, got %s running and %v", running, remaining)
+	}
+
+	// An illegal move doesn't press the clock.
+	if err := g.MoveSAN("e4"); err == nil {
+		t.Fatal("expected an error for an illegal move")
+	}
+	if running := g.Clock.Running(); running != engine.Black {
+		t.Errorf("expected black's clock to still be running, got %s", running)
+	}
+
+	// Taking back a move switches the clock back without giving back any
+	// time.
+	f.advance(5 * time.Second)
+	if err := g.UndoMove(); err != nil {
+		t.Fatal(err)
+	}
+	if running, remaining := g.Clock.Running(), g.Clock.Remaining(engine.Black); running != engine.White ||
+		remaining != 55*time.Second {
+		t.Errorf("expected white's clock to be running with black on 55s, got %s running and %v", running, remaining)
+	}
+
+	// Running out of time ends the game, and no more moves can be made.
+	f.advance(time.Minute)
+	if !g.CheckTime() {
+		t.Fatal("expected the game to be over")
+	}
+	if status, winner := g.Status(); status != engine.StatusTimeout || winner != engine.Black {
+		t.Errorf("expected black to win on time, got %s and %s", status, winner)
+	}
+	if err := g.MoveSAN("e4"); err != engine.ErrGameOver {
+		t.Errorf("expected error %v, got %v", engine.ErrGameOver, err)
+	}
+	if running := g.Clock.Running(); running != 2 {
+		t.Errorf("expected the clock to be stopped, got %s running", running)
+	}
+}
+
+func TestGameTimeout(t *testing.T) {
+	testCases := []struct {
+		fen    string
+		status engine.Status
+		winner engine.Color
+	}{
+		{engine.StartFEN, engine.StatusTimeout, engine.Black},
+		// The game is drawn if the opponent can't checkmate.
+		{"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", engine.StatusDraw, 2},
+	}
+	for _, tc := range testCases {
+		g, f := newTestGame(t, tc.fen, "1")
+		f.advance(time.Minute + time.Second)
+		if err := g.MoveSAN("e4"); err != engine.ErrGameOver {
+			t.Errorf("%s: expected error %v, got %v", tc.fen, engine.ErrGameOver, err)
+		}
+		if status, winner := g.Status(); status != tc.status || winner != tc.winner {
+			t.Errorf("%s: expected %s and winner %d, got %s and %d", tc.fen, tc.status, tc.winner, status, winner)
+		}
+	}
+}
+
+func TestGamePromotion(t *testing.T) {
+	g, f := newTestGame(t, "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "1+1")
+	f.advance(10 * time.Second)
+	if err := g.MoveByLocation("a7", "a8"); err != nil {
+		t.Fatal(err)
+	}
+	// The clock isn't pressed until the pawn is promoted.
+	if running := g.Clock.Running(); running != engine.White {
+		t.Errorf("expected white's clock to still be running, got %s", running)
+	}
+	f.advance(5 * time.Second)
+	if err := g.PromotePawn(engine.Queen); err != nil {
+		t.Fatal(err)
+	}
+	if running, remaining := g.Clock.Running(), g.Clock.Remaining(engine.White); running != engine.Black ||
+		remaining != 46*time.Second {
+		t.Errorf("expected black's clock to be running with white on 46s, got %s running and %v", running, remaining)
+	}
+}
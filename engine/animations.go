@@ -10,6 +10,7 @@ import (
 // each piece in an animated fashion by sleeping for duration d
 // in between printing the board after each move is made.
 func Animate(b *Board, d time.Duration, movesStr string) error {
+	g := NewGame(b)
 	moves := strings.Split(movesStr, ",")
 	for _, move := range moves {
 		if err := b.MoveByLocation(move[0:2], move[2:]); err != nil {
@@ -17,15 +18,10 @@ func Animate(b *Board, d time.Duration, movesStr string) error {
 		}
 		time.Sleep(d)
 		b.Print()
-		if hasCheck, color := b.HasCheck(); hasCheck {
-			if b.InCheckmate(color) {
-				fmt.Printf("%s is in checkmate\n", color)
-				break
-			}
-			fmt.Printf("%s is in check\n", color)
+		if msg := g.Message(); msg != "" {
+			fmt.Println(msg)
 		}
-		if b.HasStalemate(b.Turn()) {
-			fmt.Println("stalemate")
+		if g.Over() {
 			break
 		}
 	}
@@ -0,0 +1,147 @@
+package engine
+
+import "math/bits"
+
+// A bitboard holds a set of positions on the board using one bit for
+// each position, where bit Y*8+X is set for Pos{X, Y}.
+type bitboard uint64
+
+// square returns the index of position p's bit in a bitboard.
+func (p Pos) square() int {
+	return p.Y*8 + p.X
+}
+
+// squarePos returns the position for the bitboard bit index sq.
+func squarePos(sq int) Pos {
+	return Pos{sq % 8, sq / 8}
+}
+
+// posBit returns a bitboard with only position p set.
+func posBit(p Pos) bitboard {
+	return 1 << uint(p.square())
+}
+
+// has reports whether position p is set in bb.
+func (bb bitboard) has(p Pos) bool {
+	return !offBoard(p) && bb&posBit(p) != 0
+}
+
+// count returns the number of positions set in bb.
+func (bb bitboard) count() int {
+	return bits.OnesCount64(uint64(bb))
+}
+
+// pop removes the lowest position set in bb and returns it's square.
+func (bb *bitboard) pop() int {
+	sq := bits.TrailingZeros64(uint64(*bb))
+	*bb &= *bb - 1
+	return sq
+}
+
+// lightSquares holds every light colored position on the board.
+const lightSquares bitboard = 0x55aa55aa55aa55aa
+
+// Directions that sliding pieces can move in. The first 4 directions
+// move towards higher squares and the last 4 towards lower squares.
+const (
+	north = iota
+	east
+	northEast
+	northWest
+	south
+	west
+	southWest
+	southEast
+)
+
+// directions holds the X and Y steps for each direction.
+var directions = [8]Pos{
+	north: {0, 1}, east: {1, 0}, northEast: {1, 1}, northWest: {-1, 1},
+	south: {0, -1}, west: {-1, 0}, southWest: {-1, -1}, southEast: {1, -1},
+}
+
+var (
+	// knightAttacks and kingAttacks hold the positions that a knight
+	// or king attacks from each square.
+	knightAttacks, kingAttacks [64]bitboard
+
+	// pawnAttacks holds the positions that a pawn of each color
+	// attacks from each square.
+	pawnAttacks [2][64]bitboard
+
+	// rays holds every position from each square to the edge of the
+	// board in each direction, not including the square itself.
+	rays [8][64]bitboard
+
+	// between holds the positions strictly between any 2 squares that
+	// share a line or diagonal, or no positions if they don't.
+	between [64][64]bitboard
+)
+
+func init() {
+	// offsetBits returns a bitboard with every position that's on the
+	// board out of the positions offset from p by offsets.
+	offsetBits := func(p Pos, offsets ...Pos) bitboard {
+		var bb bitboard
+		for _, o := range offsets {
+			if to := (Pos{p.X + o.X, p.Y + o.Y}); !offBoard(to) {
+				bb |= posBit(to)
+			}
+		}
+		return bb
+	}
+
+	for sq := 0; sq < 64; sq++ {
+		p := squarePos(sq)
+		knightAttacks[sq] = offsetBits(p, Pos{1, 2}, Pos{2, 1}, Pos{2, -1},
+			Pos{1, -2}, Pos{-1, -2}, Pos{-2, -1}, Pos{-2, 1}, Pos{-1, 2})
+		kingAttacks[sq] = offsetBits(p, directions[:]...)
+		pawnAttacks[White][sq] = offsetBits(p, Pos{-1, 1}, Pos{1, 1})
+		pawnAttacks[Black][sq] = offsetBits(p, Pos{-1, -1}, Pos{1, -1})
+
+		for dir, d := range directions {
+			for to := (Pos{p.X + d.X, p.Y + d.Y}); !offBoard(to); to = (Pos{to.X + d.X, to.Y + d.Y}) {
+				// Everything already on the ray is between p and to.
+				between[sq][to.square()] = rays[dir][sq]
+				rays[dir][sq] |= posBit(to)
+			}
+		}
+	}
+}
+
+// offBoard reports whether position p is off of the board.
+func offBoard(p Pos) bool {
+	return p.X < 0 || p.X > 7 || p.Y < 0 || p.Y > 7
+}
+
+// slidingAttacks returns the positions attacked from square sq in the
+// directions dirs, stopping at the first occupied position in occupied
+// for each direction.
+func slidingAttacks(sq int, occupied bitboard, dirs ...int) bitboard {
+	var attacks bitboard
+	for _, dir := range dirs {
+		ray := rays[dir][sq]
+		attacks |= ray
+		blockers := uint64(ray & occupied)
+		if blockers == 0 {
+			continue
+		}
+		first := bits.TrailingZeros64(blockers)
+		if dir >= south {
+			first = 63 - bits.LeadingZeros64(blockers)
+		}
+		attacks &^= rays[dir][first]
+	}
+	return attacks
+}
+
+// bishopAttacks returns the positions that a bishop attacks from
+// square sq.
+func bishopAttacks(sq int, occupied bitboard) bitboard {
+	return slidingAttacks(sq, occupied, northEast, northWest, southWest, southEast)
+}
+
+// rookAttacks returns the positions that a rook attacks from square sq.
+func rookAttacks(sq int, occupied bitboard) bitboard {
+	return slidingAttacks(sq, occupied, north, east, south, west)
+}
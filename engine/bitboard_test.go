@@ -0,0 +1,69 @@
+package engine
+
+import "testing"
+
+func TestAttackTables(t *testing.T) {
+	testCases := []struct {
+		name     string
+		attacks  bitboard
+		expected []Pos
+	}{
+		{"knight on a1", knightAttacks[Pos{0, 0}.square()], []Pos{{1, 2}, {2, 1}}},
+		{"knight on h8", knightAttacks[Pos{7, 7}.square()], []Pos{{6, 5}, {5, 6}}},
+		{"king on a8", kingAttacks[Pos{0, 7}.square()], []Pos{{1, 7}, {0, 6}, {1, 6}}},
+		{"white pawn on a2", pawnAttacks[White][Pos{0, 1}.square()], []Pos{{1, 2}}},
+		{"black pawn on e7", pawnAttacks[Black][Pos{4, 6}.square()], []Pos{{3, 5}, {5, 5}}},
+		{"between a1 and d4", between[Pos{0, 0}.square()][Pos{3, 3}.square()], []Pos{{1, 1}, {2, 2}}},
+		{"between h8 and h5", between[Pos{7, 7}.square()][Pos{7, 4}.square()], []Pos{{7, 6}, {7, 5}}},
+		{"between a1 and b3", between[Pos{0, 0}.square()][Pos{1, 2}.square()], nil},
+	}
+	for _, tc := range testCases {
+		if tc.attacks.count() != len(tc.expected) {
+			t.Errorf("%s: expected %d positions, got %d",
+				tc.name, len(tc.expected), tc.attacks.count())
+		}
+		for _, pos := range tc.expected {
+			if !tc.attacks.has(pos) {
+				t.Errorf("%s: expected to find pos %v", tc.name, pos)
+			}
+		}
+	}
+}
+
+func TestSlidingAttacks(t *testing.T) {
+	// A rook on d4 with pieces on d6, b4 and d1.
+	occupied := posBit(Pos{3, 5}) | posBit(Pos{1, 3}) | posBit(Pos{3, 0})
+	attacks := rookAttacks(Pos{3, 3}.square(), occupied)
+
+	expected := []Pos{
+		{3, 4}, {3, 5}, // Up to and including d6.
+		{2, 3}, {1, 3}, // Left to and including b4.
+		{3, 2}, {3, 1}, {3, 0}, // Down to and including d1.
+		{4, 3}, {5, 3}, {6, 3}, {7, 3}, // Right to the edge.
+	}
+	if attacks.count() != len(expected) {
+		t.Errorf("expected %d positions, got %d", len(expected), attacks.count())
+	}
+	for _, pos := range expected {
+		if !attacks.has(pos) {
+			t.Errorf("expected to find pos %v", pos)
+		}
+	}
+
+	// A bishop on c1 blocked by a piece on d2.
+	attacks = bishopAttacks(Pos{2, 0}.square(), posBit(Pos{3, 1}))
+	if attacks.count() != 3 || !attacks.has(Pos{3, 1}) || !attacks.has(Pos{0, 2}) {
+		t.Errorf("expected bishop on c1 to attack b2, a3 and d2, got %d positions",
+			attacks.count())
+	}
+}
+
+func TestLightSquares(t *testing.T) {
+	for sq := 0; sq < 64; sq++ {
+		pos := squarePos(sq)
+		light := (pos.X+pos.Y)%2 == 1
+		if lightSquares.has(pos) != light {
+			t.Errorf("expected %s to be light: %t", pos, light)
+		}
+	}
+}
@@ -23,7 +23,18 @@ var (
 	ErrCastleWithPieceBetween = errors.New("error: castle with pieces between king and rook")
 	ErrCastleMoveThroughCheck = errors.New("error: castle moving king through check")
 	ErrNoPreviousMove         = errors.New("error: no previous move available")
+	ErrNoNextMove             = errors.New("error: no next move available")
+	ErrNoPawnToPromote        = errors.New("error: no pawn needs to be promoted")
+	ErrPromotionPending       = errors.New("error: pawn must be promoted first")
+	ErrInvalidPromotion       = errors.New("error: invalid promotion piece")
 	ErrKingTooCloseToKing     = errors.New("error: king can't be that close to another king")
+	ErrInvalidFEN             = errors.New("error: invalid FEN string")
+	ErrInvalidSAN             = errors.New("error: invalid SAN move")
+	ErrAmbiguousSAN           = errors.New("error: SAN move is ambiguous")
+	ErrGameOver               = errors.New("error: game is already over")
+	ErrNoDrawToClaim          = errors.New("error: no draw can be claimed")
+	ErrNoDrawOffer            = errors.New("error: no draw has been offered")
+	ErrOwnDrawOffer           = errors.New("error: can't answer your own draw offer")
 )
 
 type Color uint8
@@ -45,30 +56,30 @@ func (c Color) String() string {
 	}
 }
 
-// piecePos contains a *Piece and it's position on the board.
-type piecePos struct {
-	*Piece
-	Pos
-}
-
 // A Board describes a chess board.
+//
+// A Board isn't safe for concurrent use, even though querying it doesn't
+// change it, since moves can be made between or during queries. A game
+// that's shared between goroutines can be accessed through a SyncGame.
 type Board struct {
 	// turn holds a color value for who's turn it is.
 	turn Color
 
-	// posToPiece holds a map of positions to pieces on the board.
-	posToPiece map[Pos]*Piece
+	// squares holds the piece on each of the board's positions, indexed
+	// by Pos.square, or nil for empty positions.
+	squares [64]*Piece
+
+	// pieces holds a bitboard of positions for each color's pieces of
+	// each piece name.
+	pieces [2][6]bitboard
+
+	// colors holds a bitboard of positions for all of each color's pieces.
+	colors [2]bitboard
 
 	// check holds a true or false based on whether either king
 	// is currently in check or not.
 	check [2]bool
 
-	// kings holds both of the king's positions on the board.
-	kings [2]Pos
-
-	// kingLos holds pieces that have a line of sight to a king.
-	kingLos [2]map[piecePos]struct{}
-
 	// history contains a slice of all moves that have occurred
 	// on the board.
 	history []*MoveInfo
@@ -81,6 +92,34 @@ type Board struct {
 
 	// mustPromote holds which color needs to promote a pawn.
 	mustPromote [2]bool
+
+	// startEnPassant holds the en passant target position that the
+	// board was set up with, which only applies before any moves
+	// have been made.
+	startEnPassant Pos
+
+	// startHalfMoves and startFullMove hold the halfmove clock and
+	// fullmove number that the board was set up with.
+	startHalfMoves, startFullMove int
+
+	// startFEN holds the FEN string of the position that the board
+	// was set up with.
+	startFEN string
+
+	// hash holds the board's Zobrist hash, which is returned by Hash.
+	hash uint64
+
+	// hashState holds the part of hash that's from the side to move,
+	// the castling rights and the en passant file.
+	hashState uint64
+
+	// startHash holds the hash of the board's starting position, which
+	// is used when looking for repetitions.
+	startHash uint64
+
+	// observers holds the functions that are subscribed to the board's
+	// events.
+	observers []*observer
 }
 
 func (b *Board) Turn() Color {
@@ -122,51 +161,133 @@ func (b *Board) MustPromote() (bool, Color) {
 
 // NewBoard creates an initializes a new chess board.
 func NewBoard() *Board {
-	posToPiece := map[Pos]*Piece{
-		// White
-		{0, 0}: {Rook, White},
-		{1, 0}: {Knight, White},
-		{2, 0}: {Bishop, White},
-		{3, 0}: {Queen, White},
-		{4, 0}: {King, White},
-		{5, 0}: {Bishop, White},
-		{6, 0}: {Knight, White},
-		{7, 0}: {Rook, White},
-		// Black
-		{0, 7}: {Rook, Black},
-		{1, 7}: {Knight, Black},
-		{2, 7}: {Bishop, Black},
-		{3, 7}: {Queen, Black},
-		{4, 7}: {King, Black},
-		{5, 7}: {Bishop, Black},
-		{6, 7}: {Knight, Black},
-		{7, 7}: {Rook, Black},
-	}
-	for i := 0; i < 8; i++ {
-		posToPiece[Pos{i, 1}] = &Piece{Pawn, White}
-		posToPiece[Pos{i, 6}] = &Piece{Pawn, Black}
-	}
-	// Create and initialize a new map for b.hasMoved.
-	hasMoved := make(map[*Piece]int)
-	for _, piece := range posToPiece {
-		hasMoved[piece] = 0
-	}
-	return &Board{
-		turn:       White,
-		posToPiece: posToPiece,
-		kings:      [2]Pos{White: {4, 0}, Black: {4, 7}},
-		kingLos:    [2]map[piecePos]struct{}{White: {}, Black: {}},
-		history:    []*MoveInfo{}, // Create a new blank history.
-		moveNum:    -1,
-		hasMoved:   hasMoved,
+	b := &Board{
+		turn:     White,
+		history:  []*MoveInfo{}, // Create a new blank history.
+		moveNum:  -1,
+		hasMoved: make(map[*Piece]int),
+
+		startEnPassant: Pos{-1, -1},
+		startFullMove:  1,
+		startFEN:       StartFEN,
+	}
+	backRank := []PieceName{Rook, Knight, Bishop, Queen, King, Bishop, Knight, Rook}
+	for x, name := range backRank {
+		b.setPiece(Pos{x, 0}, &Piece{name, White})
+		b.setPiece(Pos{x, 1}, &Piece{Pawn, White})
+		b.setPiece(Pos{x, 6}, &Piece{Pawn, Black})
+		b.setPiece(Pos{x, 7}, &Piece{name, Black})
+	}
+	// Initialize b.hasMoved for every piece.
+	for _, piece := range b.squares {
+		if piece != nil {
+			b.hasMoved[piece] = 0
+		}
 	}
+	b.updateHash()
+	return b
+}
+
+// Clone returns a deep copy of the board, including it's history, so
+// that moves can be made on the copy without changing the board.
+func (b *Board) Clone() *Board {
+	c := *b
+	c.observers = nil
+
+	// Every piece gets a new copy that's shared between the squares,
+	// b.hasMoved and the history, since b.hasMoved is keyed by the
+	// pieces' pointers.
+	copies := make(map[*Piece]*Piece, len(b.hasMoved))
+	clonePiece := func(piece *Piece) *Piece {
+		if piece == nil {
+			return nil
+		}
+		if cp, found := copies[piece]; found {
+			return cp
+		}
+		cp := &Piece{piece.Name, piece.Color}
+		copies[piece] = cp
+		return cp
+	}
+
+	for sq, piece := range b.squares {
+		c.squares[sq] = clonePiece(piece)
+	}
+
+	c.hasMoved = make(map[*Piece]int, len(b.hasMoved))
+	for piece, moved := range b.hasMoved {
+		c.hasMoved[clonePiece(piece)] = moved
+	}
+
+	c.history = make([]*MoveInfo, len(b.history))
+	for i, move := range b.history {
+		m := *move
+		m.Piece = clonePiece(move.Piece)
+		m.Captured = clonePiece(move.Captured)
+		m.Promotion = clonePiece(move.Promotion)
+		c.history[i] = &m
+	}
+
+	return &c
+}
+
+// pieceAt returns the piece at position pos and true, or false if
+// there's no piece at pos.
+func (b *Board) pieceAt(pos Pos) (*Piece, bool) {
+	if offBoard(pos) {
+		return nil, false
+	}
+	piece := b.squares[pos.square()]
+	return piece, piece != nil
+}
+
+// setPiece puts piece at position pos, replacing any piece that's
+// already there.
+func (b *Board) setPiece(pos Pos, piece *Piece) {
+	b.removePiece(pos)
+	bit := posBit(pos)
+	b.squares[pos.square()] = piece
+	b.pieces[piece.Color][piece.Name] |= bit
+	b.colors[piece.Color] |= bit
+	b.hash ^= zobristPieces[piece.Color][piece.Name][pos.square()]
+}
+
+// removePiece removes the piece at position pos from the board and
+// returns it, or nil if there was no piece at pos.
+func (b *Board) removePiece(pos Pos) *Piece {
+	piece := b.squares[pos.square()]
+	if piece == nil {
+		return nil
+	}
+	bit := posBit(pos)
+	b.squares[pos.square()] = nil
+	b.pieces[piece.Color][piece.Name] &^= bit
+	b.colors[piece.Color] &^= bit
+	b.hash ^= zobristPieces[piece.Color][piece.Name][pos.square()]
+	return piece
+}
+
+// occupied returns a bitboard of every position that has a piece on it.
+func (b *Board) occupied() bitboard {
+	return b.colors[White] | b.colors[Black]
+}
+
+// kingPos returns the position of color's king, or an invalid position
+// if color doesn't have a king on the board.
+func (b *Board) kingPos(color Color) Pos {
+	kings := b.pieces[color][King]
+	if kings == 0 {
+		return Pos{-1, -1}
+	}
+	return squarePos(kings.pop())
 }
 
 // clear removes all pieces from a board and is useful for testing.
 func (b *Board) clear() {
-	for k := range b.posToPiece {
-		delete(b.posToPiece, k)
-	}
+	b.squares = [64]*Piece{}
+	b.pieces = [2][6]bitboard{}
+	b.colors = [2]bitboard{}
+	b.hash, b.hashState = 0, 0
 }
 
 var (
@@ -180,7 +301,7 @@ func (b *Board) Print() {
 	for i1 := 0; i1 < 8; i1++ {
 		fmt.Print(color.RedString(" %d ", 8-i1))
 		for i2 := 0; i2 < 8; i2++ {
-			if piece, found := b.posToPiece[Pos{i2, 7 - i1}]; found {
+			if piece, found := b.pieceAt(Pos{i2, 7 - i1}); found {
 				if i2%2 == i1%2 {
 					printCyanBg("%s", piece)
 				} else {
@@ -208,7 +329,7 @@ func (b *Board) GetPieceAt(loc string) (*Piece, error) {
 	if err != nil {
 		return nil, err
 	}
-	piece, found := b.posToPiece[pos]
+	piece, found := b.pieceAt(pos)
 	if !found {
 		return nil, ErrNoPieceAtPosition
 	}
@@ -11,8 +11,8 @@ func TestNewBoard(t *testing.T) {
 	}
 
 	// Check if there are the correct amount of pieces on the new board.
-	if numPieces := len(b.posToPiece); numPieces != 32 {
-		t.Errorf("expected len(posToPiece) to be 32, got %d", numPieces)
+	if numPieces := b.occupied().count(); numPieces != 32 {
+		t.Errorf("expected 32 pieces on the board, got %d", numPieces)
 	}
 
 	// Make sure that starting positions match the correct piece name.
@@ -36,7 +36,7 @@ func TestNewBoard(t *testing.T) {
 		{Pos{4, 7}, King},
 	}
 	for _, tc := range posToPieceNames {
-		piece, found := b.posToPiece[tc.pos]
+		piece, found := b.pieceAt(tc.pos)
 		if !found {
 			t.Errorf("piece not found at position %v", tc.pos)
 		}
@@ -78,3 +78,51 @@ func TestGetPieceAt(t *testing.T) {
 		}
 	}
 }
+
+func TestClone(t *testing.T) {
+	b := NewBoard()
+	for _, move := range []string{"e4", "d5", "exd5", "Qxd5", "Ke2", "Qe5+"} {
+		if err := b.MoveSAN(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fen, history := b.FEN(), b.HistorySAN()
+
+	c := b.Clone()
+	if c.FEN() != fen || c.Hash() != b.Hash() || c.HistorySAN() != history {
+		t.Fatal("expected clone to have the same position and history as the board")
+	}
+
+	// The clone's pieces shouldn't be shared with the board.
+	for sq, piece := range b.squares {
+		if piece != nil && c.squares[sq] == piece {
+			t.Fatalf("expected piece at %s to be copied", squarePos(sq))
+		}
+	}
+
+	// Moves, undos and redos on the clone shouldn't change the board.
+	if err := c.MoveSAN("Kf3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.GoToMove(0); err != nil {
+		t.Fatal(err)
+	}
+	if c.FEN() != StartFEN {
+		t.Errorf("expected clone to undo back to %q, got %q", StartFEN, c.FEN())
+	}
+	if b.FEN() != fen || b.HistorySAN() != history {
+		t.Error("expected board to be unchanged by moves on the clone")
+	}
+
+	// The clone should still know that white's king has moved when
+	// redoing the moves.
+	if err := c.GoToMove(7); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UndoMove(); err != nil {
+		t.Fatal(err)
+	}
+	if c.FEN() != fen {
+		t.Errorf("expected clone to redo to %q, got %q", fen, c.FEN())
+	}
+}
@@ -0,0 +1,162 @@
+package engine
+
+// A DrawReason describes why a game is drawn.
+type DrawReason uint8
+
+const (
+	// NoDraw means that the game isn't drawn.
+	NoDraw DrawReason = iota
+
+	// Stalemate means that the side to move isn't in check but
+	// doesn't have any legal moves.
+	Stalemate
+
+	// InsufficientMaterial means that neither side has enough pieces
+	// left to checkmate the other.
+	InsufficientMaterial
+
+	// FivefoldRepetition means that the same position has occurred
+	// 5 times, which draws the game automatically.
+	FivefoldRepetition
+
+	// SeventyFiveMoveRule means that 75 moves have been made by each
+	// side without a capture or pawn move, which draws the game
+	// automatically.
+	SeventyFiveMoveRule
+
+	// ThreefoldRepetition means that the same position has occurred
+	// 3 times, which lets either player claim a draw.
+	ThreefoldRepetition
+
+	// FiftyMoveRule means that 50 moves have been made by each side
+	// without a capture or pawn move, which lets either player claim
+	// a draw.
+	FiftyMoveRule
+)
+
+// String returns a string for a DrawReason.
+func (d DrawReason) String() string {
+	switch d {
+	case NoDraw:
+		return "no draw"
+	case Stalemate:
+		return "stalemate"
+	case InsufficientMaterial:
+		return "insufficient material"
+	case FivefoldRepetition:
+		return "fivefold repetition"
+	case SeventyFiveMoveRule:
+		return "seventy-five move rule"
+	case ThreefoldRepetition:
+		return "threefold repetition"
+	case FiftyMoveRule:
+		return "fifty move rule"
+	default:
+		return "invalid draw reason"
+	}
+}
+
+// Claimable reports whether a draw for reason d has to be claimed by
+// a player, rather than ending the game automatically.
+func (d DrawReason) Claimable() bool {
+	return d == ThreefoldRepetition || d == FiftyMoveRule
+}
+
+// DrawReason returns the reason that the game on the board is drawn,
+// or NoDraw if it isn't.
+//
+// When more than one reason applies, the reasons that end the game
+// automatically are returned before the ones that can be claimed.
+func (b *Board) DrawReason() DrawReason {
+	if mustPromote, _ := b.MustPromote(); mustPromote {
+		return NoDraw
+	}
+
+	// A checkmate takes priority over everything else, including a
+	// move that reaches the seventy-five move rule.
+	if b.InCheckmate(b.turn) {
+		return NoDraw
+	}
+
+	halfMoves := b.HalfMoveClock()
+	switch repetitions := b.repetitions(); {
+	case b.HasStalemate(b.turn):
+		return Stalemate
+	case b.insufficientMaterial():
+		return InsufficientMaterial
+	case repetitions >= 5:
+		return FivefoldRepetition
+	case halfMoves >= 150:
+		return SeventyFiveMoveRule
+	case repetitions >= 3:
+		return ThreefoldRepetition
+	case halfMoves >= 100:
+		return FiftyMoveRule
+	}
+	return NoDraw
+}
+
+// insufficientMaterial reports whether neither side has enough pieces
+// left to ever checkmate the other. That's when besides the kings,
+// there's only a single knight, or only bishops that are all on the
+// same colored positions.
+func (b *Board) insufficientMaterial() bool {
+	var knights, bishops bitboard
+	for _, color := range []Color{White, Black} {
+		pieces := &b.pieces[color]
+		if pieces[Pawn]|pieces[Rook]|pieces[Queen] != 0 {
+			return false
+		}
+		knights |= pieces[Knight]
+		bishops |= pieces[Bishop]
+	}
+	if knights == 0 {
+		return bishops&lightSquares == 0 || bishops&^lightSquares == 0
+	}
+	return knights.count() == 1 && bishops == 0
+}
+
+// canCheckmate reports whether color could checkmate it's opponent by
+// any series of legal moves, even with the opponent's help. It can't with
+// only it's king, or with it's king and a single knight or bishop against
+// a lone king, but the opponent's other pieces can block their own king
+// in for a single knight or bishop to checkmate it.
+func (b *Board) canCheckmate(color Color) bool {
+	pieces := &b.pieces[color]
+	if pieces[Pawn]|pieces[Rook]|pieces[Queen] != 0 {
+		return true
+	}
+	switch (pieces[Knight] | pieces[Bishop]).count() {
+	case 0:
+		return false
+	case 1:
+		return b.colors[color^1].count() > 1
+	}
+	return true
+}
+
+// repetitions returns the number of times that the board's current
+// position has occurred, including the current position itself.
+func (b *Board) repetitions() int {
+	hashAt := func(i int) uint64 {
+		if i < 0 {
+			return b.startHash
+		}
+		return b.history[i].hash
+	}
+
+	// Positions from before the last capture or pawn move can't
+	// occur again, so there's no need to look any further back.
+	oldest := b.moveNum - b.HalfMoveClock()
+	if oldest < -1 {
+		oldest = -1
+	}
+
+	hash, count := hashAt(b.moveNum), 1
+	for i := b.moveNum - 2; i >= oldest; i -= 2 {
+		if hashAt(i) == hash {
+			count++
+		}
+	}
+	return count
+}
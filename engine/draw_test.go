@@ -0,0 +1,185 @@
+package engine
+
+import "testing"
+
+func TestDrawByRepetition(t *testing.T) {
+	b := NewBoard()
+
+	shuffle := [][2]string{{"g1", "f3"}, {"g8", "f6"}, {"f3", "g1"}, {"f6", "g8"}}
+	expected := []DrawReason{NoDraw, ThreefoldRepetition, ThreefoldRepetition, FivefoldRepetition}
+	for _, reason := range expected {
+		for _, move := range shuffle {
+			if err := b.MoveByLocation(move[0], move[1]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := b.DrawReason(); got != reason {
+			t.Errorf("expected draw reason to be %s, got %s", reason, got)
+		}
+	}
+
+	// Undoing a move goes back to a position that has only occurred
+	// 4 times.
+	if err := b.UndoMove(); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.repetitions(); got != 4 {
+		t.Errorf("expected 4 repetitions, got %d", got)
+	}
+	if got := b.DrawReason(); got != ThreefoldRepetition {
+		t.Errorf("expected draw reason to be %s, got %s", ThreefoldRepetition, got)
+	}
+	if err := b.RedoMove(); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.DrawReason(); got != FivefoldRepetition {
+		t.Errorf("expected draw reason to be %s, got %s", FivefoldRepetition, got)
+	}
+}
+
+func TestRepetitionNeedsSameCastlingRights(t *testing.T) {
+	b, err := ParseFEN("r3k3/8/8/8/8/8/8/4K2R w Kq - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first time the rook moves back, white has lost it's castling
+	// rights so the position is different to the starting position.
+	shuffle := [][2]string{{"h1", "h2"}, {"a8", "a7"}, {"h2", "h1"}, {"a7", "a8"}}
+	for i := 0; i < 3; i++ {
+		for _, move := range shuffle {
+			if err := b.MoveByLocation(move[0], move[1]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if got := b.repetitions(); got != 3 {
+		t.Errorf("expected 3 repetitions, got %d", got)
+	}
+}
+
+func TestRepetitionEnPassant(t *testing.T) {
+	// Black can capture en passant, so the starting position isn't the
+	// same as it is after the knights move back and forth.
+	b, err := ParseFEN("4k1n1/8/8/8/3pP3/8/8/4K1N1 b - e3 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shuffle := [][2]string{{"g8", "f6"}, {"g1", "f3"}, {"f6", "g8"}, {"f3", "g1"}}
+	for i := 0; i < 2; i++ {
+		for _, move := range shuffle {
+			if err := b.MoveByLocation(move[0], move[1]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if got := b.repetitions(); got != 2 {
+		t.Errorf("expected 2 repetitions, got %d", got)
+	}
+
+	// Without a pawn that can capture en passant, the en passant target
+	// doesn't change the position.
+	b, err = ParseFEN("4k1n1/8/8/8/4P3/8/8/4K1N1 b - e3 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		for _, move := range shuffle {
+			if err := b.MoveByLocation(move[0], move[1]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if got := b.repetitions(); got != 3 {
+		t.Errorf("expected 3 repetitions, got %d", got)
+	}
+}
+
+func TestDrawByMoveRules(t *testing.T) {
+	testCases := []struct {
+		fen    string
+		reason DrawReason
+	}{
+		{"4k3/8/8/8/8/8/4P3/4K1N1 w - - 98 80", NoDraw},
+		{"4k3/8/8/8/8/8/4P3/4K1N1 w - - 99 80", FiftyMoveRule},
+		{"4k3/8/8/8/8/8/4P3/4K1N1 w - - 148 80", FiftyMoveRule},
+		{"4k3/8/8/8/8/8/4P3/4K1N1 w - - 149 80", SeventyFiveMoveRule},
+	}
+	for _, tc := range testCases {
+		b, err := ParseFEN(tc.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := b.MoveByLocation("g1", "f3"); err != nil {
+			t.Fatal(err)
+		}
+		if got := b.DrawReason(); got != tc.reason {
+			t.Errorf("%s: expected draw reason to be %s, got %s", tc.fen, tc.reason, got)
+		}
+	}
+
+	// A pawn move resets the halfmove clock.
+	b, err := ParseFEN("4k3/8/8/8/8/8/4P3/4K1N1 w - - 149 80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.MoveByLocation("e2", "e4"); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.DrawReason(); got != NoDraw {
+		t.Errorf("expected no draw, got %s", got)
+	}
+}
+
+func TestCheckmateBeatsMoveRules(t *testing.T) {
+	b, err := ParseFEN("7k/8/6K1/8/8/8/8/R7 w - - 149 80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.MoveByLocation("a1", "a8"); err != nil {
+		t.Fatal(err)
+	}
+	if !b.InCheckmate(Black) {
+		t.Fatal("expected black to be in checkmate")
+	}
+	if got := b.DrawReason(); got != NoDraw {
+		t.Errorf("expected no draw, got %s", got)
+	}
+}
+
+func TestDrawByInsufficientMaterial(t *testing.T) {
+	testCases := []struct {
+		fen    string
+		reason DrawReason
+	}{
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", InsufficientMaterial},
+		{"4k3/8/8/8/8/8/8/4KB2 w - - 0 1", InsufficientMaterial},
+		{"4k3/8/8/8/8/8/8/4KN2 w - - 0 1", InsufficientMaterial},
+		{"4kb2/8/8/8/8/8/8/2B1K3 w - - 0 1", InsufficientMaterial},
+		{"4k3/8/8/8/8/8/8/2B1KB2 w - - 0 1", NoDraw},
+		{"4kb2/8/8/8/8/8/8/3BK3 w - - 0 1", NoDraw},
+		{"4kn2/8/8/8/8/8/8/4KN2 w - - 0 1", NoDraw},
+		{"4kn2/8/8/8/8/8/8/4KB2 w - - 0 1", NoDraw},
+		{"4k3/8/8/8/8/8/8/4KR2 w - - 0 1", NoDraw},
+		{"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", NoDraw},
+	}
+	for _, tc := range testCases {
+		b, err := ParseFEN(tc.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := b.DrawReason(); got != tc.reason {
+			t.Errorf("%s: expected draw reason to be %s, got %s", tc.fen, tc.reason, got)
+		}
+	}
+}
+
+func TestDrawByStalemate(t *testing.T) {
+	b, err := ParseFEN("7k/5K2/6Q1/8/8/8/8/8 b - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.DrawReason(); got != Stalemate {
+		t.Errorf("expected draw reason to be %s, got %s", Stalemate, got)
+	}
+}
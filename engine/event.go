@@ -0,0 +1,153 @@
+package engine
+
+// An EventType is a type of Event that happens on a board.
+type EventType uint8
+
+const (
+	// EventMove is sent after a move is made, including a move that's
+	// redone after being undone.
+	EventMove EventType = iota
+
+	// EventUndo is sent after a move is undone.
+	EventUndo
+
+	// EventCapture is sent after a move that captures a piece, after
+	// the move's EventMove.
+	EventCapture
+
+	// EventCheck is sent when a move puts the side to move in check.
+	EventCheck
+
+	// EventCheckmate is sent when a move puts the side to move in
+	// checkmate.
+	EventCheckmate
+
+	// EventStalemate is sent when a move leaves the side to move without
+	// any legal moves while it isn't in check.
+	EventStalemate
+
+	// EventPromotionPending is sent when a pawn reaches the last rank
+	// and is waiting to be promoted with PromotePawn.
+	EventPromotionPending
+
+	// EventPromotion is sent after a pawn is promoted.
+	EventPromotion
+)
+
+// String returns a string for an EventType.
+func (t EventType) String() string {
+	switch t {
+	case EventMove:
+		return "move"
+	case EventUndo:
+		return "undo"
+	case EventCapture:
+		return "capture"
+	case EventCheck:
+		return "check"
+	case EventCheckmate:
+		return "checkmate"
+	case EventStalemate:
+		return "stalemate"
+	case EventPromotionPending:
+		return "promotion pending"
+	case EventPromotion:
+		return "promotion"
+	default:
+		return "invalid event"
+	}
+}
+
+// An Event describes something that happened on a board.
+type Event struct {
+	Type EventType
+
+	// Move holds a copy of the move that the event is about, which is
+	// the move that was undone for EventUndo, and the last move that
+	// was made for every other event.
+	Move *MoveInfo
+
+	// Color holds the color that's in check, checkmate or stalemate for
+	// EventCheck, EventCheckmate and EventStalemate, and the color that
+	// made the move for every other event.
+	Color Color
+}
+
+// An observer is a function that's subscribed to a board's events.
+type observer struct {
+	f func(Event)
+}
+
+// Subscribe calls f with each event that happens on the board from now
+// on, until the returned unsubscribe function is called. The events for
+// each change to the board are sent in order once the change is
+// finished, such as EventMove, then EventCapture, then EventCheck.
+//
+// f is called on the goroutine that changed the board, and can query the
+// board but must not change it. A copy of the board made by Clone doesn't
+// send events to the board's subscribers.
+func (b *Board) Subscribe(f func(Event)) (unsubscribe func()) {
+	o := &observer{f}
+	b.observers = append(b.observers, o)
+	return func() {
+		for i, other := range b.observers {
+			if other == o {
+				// Copy the rest of the subscribers, so that events
+				// that are being sent aren't affected.
+				b.observers = append(b.observers[:i:i], b.observers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// emit sends an event about the move m to the board's subscribers.
+func (b *Board) emit(t EventType, m *MoveInfo, color Color) {
+	move := *m
+	e := Event{Type: t, Move: &move, Color: color}
+	for _, o := range b.observers {
+		o.f(e)
+	}
+}
+
+// emitMove sends the events for the move m after it's been made.
+func (b *Board) emitMove(m *MoveInfo) {
+	if len(b.observers) == 0 {
+		return
+	}
+	b.emit(EventMove, m, m.Piece.Color)
+	if m.Captured != nil {
+		b.emit(EventCapture, m, m.Piece.Color)
+	}
+	if b.mustPromote[m.Piece.Color] {
+		b.emit(EventPromotionPending, m, m.Piece.Color)
+		return
+	}
+	if m.Promotion != nil {
+		b.emit(EventPromotion, m, m.Piece.Color)
+	}
+	b.emitPosition(m)
+}
+
+// emitPosition sends an event if the side to move is in check, checkmate
+// or stalemate after the move m.
+func (b *Board) emitPosition(m *MoveInfo) {
+	switch turn := b.turn; {
+	case b.InCheckmate(turn):
+		b.emit(EventCheckmate, m, turn)
+	case b.check[turn]:
+		b.emit(EventCheck, m, turn)
+	case b.HasStalemate(turn):
+		b.emit(EventStalemate, m, turn)
+	}
+}
+
+// muteEvents stops the board's events from being sent, and returns a
+// function that starts sending them again.
+func (b *Board) muteEvents() func() {
+	observers := b.observers
+	b.observers = nil
+	return func() {
+		b.observers = observers
+	}
+}
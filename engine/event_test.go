@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// recordEvents subscribes to b's events and returns a function that
+// returns the events sent since it was last called, such as "move white".
+func recordEvents(b *Board) func() []string {
+	var events []string
+	b.Subscribe(func(e Event) {
+		events = append(events, fmt.Sprintf("%s %s", e.Type, e.Color))
+	})
+	return func() []string {
+		recorded := events
+		events = nil
+		return recorded
+	}
+}
+
+func TestEvents(t *testing.T) {
+	testCases := []struct {
+		fen    string
+		moves  []string
+		events []string
+	}{
+		{StartFEN, []string{"e4", "d5", "exd5"},
+			[]string{"move white", "move black", "move white", "capture white"}},
+		{StartFEN, []string{"e4", "f5", "Qh5+"},
+			[]string{"move white", "move black", "move white", "check black"}},
+		{StartFEN, []string{"f3", "e5", "g4", "Qh4#"},
+			[]string{"move white", "move black", "move white", "move black", "checkmate white"}},
+		{"7k/5K2/8/6Q1/8/8/8/8 w - - 0 1", []string{"Qg6"},
+			[]string{"move white", "stalemate black"}},
+		{"1r2k3/P7/8/8/8/8/8/4K3 w - - 0 1", []string{"axb8=Q+"},
+			[]string{"move white", "capture white", "promotion white", "check black"}},
+	}
+	for _, tc := range testCases {
+		b, err := ParseFEN(tc.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events := recordEvents(b)
+		for _, san := range tc.moves {
+			if err := b.MoveSAN(san); err != nil {
+				t.Fatalf("%s: %s: %v", tc.fen, san, err)
+			}
+		}
+		if got := events(); !reflect.DeepEqual(got, tc.events) {
+			t.Errorf("%s: expected events %q, got %q", tc.fen, tc.events, got)
+		}
+	}
+}
+
+func TestEventsPromotion(t *testing.T) {
+	b, err := ParseFEN("4k3/P7/8/8/8/8/8/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := recordEvents(b)
+
+	var last Event
+	b.Subscribe(func(e Event) { last = e })
+
+	if err := b.MoveByLocation("a7", "a8"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := events(), []string{"move white", "promotion pending white"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected events %q, got %q", want, got)
+	}
+	if err := b.PromotePawn(Queen); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := events(), []string{"promotion white", "check black"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected events %q, got %q", want, got)
+	}
+	if last.Move.SAN != "a8=Q+" || last.Move.Promotion.Name != Queen {
+		t.Errorf("expected the event's move to be a8=Q+, got %+v", last.Move)
+	}
+
+	if err := b.UndoMove(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := events(), []string{"undo white"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected events %q, got %q", want, got)
+	}
+	if err := b.RedoMove(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := events(), []string{"move white", "promotion white", "check black"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected events %q, got %q", want, got)
+	}
+}
+
+func TestEventsUnsubscribe(t *testing.T) {
+	b := NewBoard()
+	events := recordEvents(b)
+	var n int
+	unsubscribe := b.Subscribe(func(Event) { n++ })
+
+	// Clones, perft and subscribers that have unsubscribed aren't sent
+	// any events.
+	if err := b.Clone().MoveSAN("e4"); err != nil {
+		t.Fatal(err)
+	}
+	Perft(b, 2)
+	PerftDivide(b, 2)
+	if got := events(); len(got) != 0 || n != 0 {
+		t.Errorf("expected no events, got %q and %d", got, n)
+	}
+
+	b.MoveSAN("e4")
+	unsubscribe()
+	b.MoveSAN("e5")
+	if got := events(); len(got) != 2 || n != 1 {
+		t.Errorf("expected 2 events and 1 event after unsubscribing, got %q and %d", got, n)
+	}
+}
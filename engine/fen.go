@@ -0,0 +1,252 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StartFEN is the FEN string for the standard starting position.
+const StartFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// fenPieces holds the FEN letters for each piece name, indexed by
+// their PieceName values.
+const fenPieces = "pnbrqk"
+
+// fenLetter returns the FEN letter for a piece, which is upper case
+// for white pieces and lower case for black pieces.
+func fenLetter(piece *Piece) byte {
+	letter := fenPieces[piece.Name]
+	if piece.Color == White {
+		return letter - 'a' + 'A'
+	}
+	return letter
+}
+
+// ParseFEN creates a new board set up from a FEN string.
+//
+// If the FEN string is invalid or describes an impossible position,
+// an error wrapping ErrInvalidFEN is returned.
+func ParseFEN(fen string) (*Board, error) {
+	fields := strings.Fields(fen)
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("%w: expected 6 fields, got %d",
+			ErrInvalidFEN, len(fields))
+	}
+
+	b := &Board{
+		history:        []*MoveInfo{},
+		moveNum:        -1,
+		hasMoved:       make(map[*Piece]int),
+		startEnPassant: Pos{-1, -1},
+		startFEN:       strings.Join(fields, " "),
+	}
+
+	// Piece placement, starting from rank 8 down to rank 1.
+	ranks := strings.Split(fields[0], "/")
+	if len(ranks) != 8 {
+		return nil, fmt.Errorf("%w: expected 8 ranks, got %d",
+			ErrInvalidFEN, len(ranks))
+	}
+	numKings := [2]int{}
+	for i, rank := range ranks {
+		y, x := 7-i, 0
+		for j := 0; j < len(rank); j++ {
+			c := rank[j]
+			if c >= '1' && c <= '8' {
+				x += int(c - '0')
+				continue
+			}
+			color := White
+			if c >= 'a' && c <= 'z' {
+				color = Black
+			} else {
+				c = c - 'A' + 'a'
+			}
+			idx := strings.IndexByte(fenPieces, c)
+			if idx < 0 {
+				return nil, fmt.Errorf("%w: invalid piece %q",
+					ErrInvalidFEN, rank[j])
+			}
+			if x > 7 {
+				return nil, fmt.Errorf("%w: rank %d has too many files",
+					ErrInvalidFEN, y+1)
+			}
+			name := PieceName(idx)
+			if name == Pawn && (y == 0 || y == 7) {
+				return nil, fmt.Errorf("%w: pawn on rank %d",
+					ErrInvalidFEN, y+1)
+			}
+			if name == King {
+				numKings[color]++
+			}
+			b.setPiece(Pos{x, y}, &Piece{name, color})
+			x++
+		}
+		if x != 8 {
+			return nil, fmt.Errorf("%w: rank %d doesn't have 8 files",
+				ErrInvalidFEN, y+1)
+		}
+	}
+	if numKings[White] != 1 || numKings[Black] != 1 {
+		return nil, fmt.Errorf("%w: each side must have exactly one king",
+			ErrInvalidFEN)
+	}
+
+	// Active color.
+	switch fields[1] {
+	case "w":
+		b.turn = White
+	case "b":
+		b.turn = Black
+	default:
+		return nil, fmt.Errorf("%w: invalid active color %q",
+			ErrInvalidFEN, fields[1])
+	}
+
+	// Castling rights.
+	//
+	// Every piece starts off as having moved, then kings and rooks
+	// that can still castle are marked as not having moved.
+	for _, piece := range b.squares {
+		if piece != nil {
+			b.hasMoved[piece] = 1
+		}
+	}
+	if fields[2] != "-" {
+		for i := 0; i < len(fields[2]); i++ {
+			var color Color
+			var rookX int
+			switch fields[2][i] {
+			case 'K':
+				color, rookX = White, 7
+			case 'Q':
+				color, rookX = White, 0
+			case 'k':
+				color, rookX = Black, 7
+			case 'q':
+				color, rookX = Black, 0
+			default:
+				return nil, fmt.Errorf("%w: invalid castling rights %q",
+					ErrInvalidFEN, fields[2])
+			}
+			y := homeRank(color)
+			king, found := b.pieceAt(Pos{4, y})
+			if !found || king.Name != King || king.Color != color {
+				return nil, fmt.Errorf("%w: %s king can't castle",
+					ErrInvalidFEN, color)
+			}
+			rook, found := b.pieceAt(Pos{rookX, y})
+			if !found || rook.Name != Rook || rook.Color != color {
+				return nil, fmt.Errorf("%w: %s rook not found to castle with",
+					ErrInvalidFEN, color)
+			}
+			b.hasMoved[king], b.hasMoved[rook] = 0, 0
+		}
+	}
+
+	// En passant target.
+	if fields[3] != "-" {
+		pos, err := locToPos(fields[3])
+		if err != nil || (b.turn == White && pos.Y != 5) ||
+			(b.turn == Black && pos.Y != 2) {
+			return nil, fmt.Errorf("%w: invalid en passant target %q",
+				ErrInvalidFEN, fields[3])
+		}
+		b.startEnPassant = pos
+	}
+
+	// Halfmove clock and fullmove number.
+	halfMoves, err := strconv.Atoi(fields[4])
+	if err != nil || halfMoves < 0 {
+		return nil, fmt.Errorf("%w: invalid halfmove clock %q",
+			ErrInvalidFEN, fields[4])
+	}
+	fullMove, err := strconv.Atoi(fields[5])
+	if err != nil || fullMove < 1 {
+		return nil, fmt.Errorf("%w: invalid fullmove number %q",
+			ErrInvalidFEN, fields[5])
+	}
+	b.startHalfMoves, b.startFullMove = halfMoves, fullMove
+
+	// The side that isn't moving can't already be in check.
+	if b.kingInCheck(b.turn ^ 1) {
+		return nil, fmt.Errorf("%w: %s is in check but it's %s's turn",
+			ErrInvalidFEN, b.turn^1, b.turn)
+	}
+
+	// Set up the checks for both kings.
+	b.updateChecks()
+
+	// Add the side to move, castling rights and en passant file
+	// to the hash.
+	b.updateHash()
+
+	return b, nil
+}
+
+// FEN returns the board's current position as a FEN string.
+func (b *Board) FEN() string {
+	return b.Position().FEN()
+}
+
+// InitialFEN returns the FEN string of the position that the board
+// was set up with before any moves were made.
+func (b *Board) InitialFEN() string {
+	return b.startFEN
+}
+
+// canStillCastle reports whether color's king and the rook on file
+// rookX are both still on their starting positions without having
+// moved.
+func (b *Board) canStillCastle(color Color, rookX int) bool {
+	y := homeRank(color)
+	king, found := b.pieceAt(Pos{4, y})
+	if !found || king.Name != King || king.Color != color || b.hasMoved[king] > 0 {
+		return false
+	}
+	rook, found := b.pieceAt(Pos{rookX, y})
+	if !found || rook.Name != Rook || rook.Color != color || b.hasMoved[rook] > 0 {
+		return false
+	}
+	return true
+}
+
+// enPassantTarget returns the position that a pawn passed over by
+// moving two positions forward on the previous move, or an invalid
+// position if the previous move wasn't a pawn moving two positions.
+func (b *Board) enPassantTarget() Pos {
+	move, err := b.prevMove()
+	if err != nil {
+		return b.startEnPassant
+	}
+	if move.Piece.Name != Pawn || (move.From.Y-move.To.Y != 2 &&
+		move.To.Y-move.From.Y != 2) {
+		return Pos{-1, -1}
+	}
+	return Pos{move.From.X, (move.From.Y + move.To.Y) / 2}
+}
+
+// HalfMoveClock returns the number of half moves made since the last
+// capture or pawn move.
+func (b *Board) HalfMoveClock() int {
+	for i := b.moveNum; i >= 0; i-- {
+		if m := b.history[i]; m.Piece.Name == Pawn || m.Captured != nil {
+			return b.moveNum - i
+		}
+	}
+	return b.startHalfMoves + b.moveNum + 1
+}
+
+// FullMoveNumber returns the current move number, which starts at 1
+// and is incremented after each of black's moves.
+func (b *Board) FullMoveNumber() int {
+	plies, first := b.moveNum+1, b.turn
+	if plies > 0 {
+		first = b.history[0].Piece.Color
+	}
+	if first == Black {
+		plies++
+	}
+	return b.startFullMove + plies/2
+}
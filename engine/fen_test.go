@@ -0,0 +1,195 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseFENStartPosition(t *testing.T) {
+	b, err := ParseFEN(StartFEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b.turn != White {
+		t.Error("expected turn to be white")
+	}
+	if numPieces := b.occupied().count(); numPieces != 32 {
+		t.Errorf("expected 32 pieces on the board, got %d", numPieces)
+	}
+	if b.kingPos(White) != (Pos{4, 0}) || b.kingPos(Black) != (Pos{4, 7}) {
+		t.Errorf("expected kings to be at e1 and e8, got %s and %s",
+			b.kingPos(White), b.kingPos(Black))
+	}
+
+	// The parsed board should match a new board exactly.
+	if fen := NewBoard().FEN(); fen != StartFEN {
+		t.Errorf("expected new board's FEN to be %q, got %q", StartFEN, fen)
+	}
+	if fen := b.FEN(); fen != StartFEN {
+		t.Errorf("expected FEN to be %q, got %q", StartFEN, fen)
+	}
+}
+
+func TestFENRoundTrip(t *testing.T) {
+	testCases := []string{
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+		"rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 3",
+		"4k3/8/8/8/8/8/8/4K2R b K - 12 40",
+	}
+	for _, fen := range testCases {
+		b, err := ParseFEN(fen)
+		if err != nil {
+			t.Errorf("parsing %q failed: %s", fen, err)
+			continue
+		}
+		if got := b.FEN(); got != fen {
+			t.Errorf("expected FEN to be %q, got %q", fen, got)
+		}
+	}
+}
+
+func TestParseFENInvalid(t *testing.T) {
+	testCases := []string{
+		"",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNRR w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNX w KQkq - 0 1",
+		"rnbq1bnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQ - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBN1 w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq e4 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - -1 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 0",
+		"pnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		// White to move while black is already in check.
+		"4k3/8/8/8/8/8/8/4R1K1 w - - 0 1",
+	}
+	for _, fen := range testCases {
+		if _, err := ParseFEN(fen); !errors.Is(err, ErrInvalidFEN) {
+			t.Errorf("expected parsing %q to fail with ErrInvalidFEN, got %v",
+				fen, err)
+		}
+	}
+}
+
+func TestFENAfterMoves(t *testing.T) {
+	b := NewBoard()
+
+	moves := []struct {
+		from, to string
+		fen      string
+	}{
+		{"e2", "e4", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"},
+		{"c7", "c5", "rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq c6 0 2"},
+		{"g1", "f3", "rnbqkbnr/pp1ppppp/8/2p5/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2"},
+	}
+
+	for _, move := range moves {
+		if err := b.MoveByLocation(move.from, move.to); err != nil {
+			t.Fatalf("moving from %s to %s failed: %s",
+				move.from, move.to, err.Error())
+		}
+		if fen := b.FEN(); fen != move.fen {
+			t.Errorf("expected FEN to be %q, got %q", move.fen, fen)
+		}
+	}
+
+	// Moving the king loses black's castling rights.
+	if err := b.MoveByLocation("d7", "d6"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.MoveByLocation("f1", "e2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.MoveByLocation("e8", "d7"); err != nil {
+		t.Fatal(err)
+	}
+	expected := "rnbq1bnr/pp1kpppp/3p4/2p5/4P3/5N2/PPPPBPPP/RNBQK2R w KQ - 2 4"
+	if fen := b.FEN(); fen != expected {
+		t.Errorf("expected FEN to be %q, got %q", expected, fen)
+	}
+
+	// Undoing the king move gives black back it's castling rights.
+	if err := b.UndoMove(); err != nil {
+		t.Fatal(err)
+	}
+	expected = "rnbqkbnr/pp2pppp/3p4/2p5/4P3/5N2/PPPPBPPP/RNBQK2R b KQkq - 1 3"
+	if fen := b.FEN(); fen != expected {
+		t.Errorf("expected FEN to be %q, got %q", expected, fen)
+	}
+}
+
+func TestParseFENEnPassant(t *testing.T) {
+	b, err := ParseFEN("rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.MoveByLocation("e5", "d6"); err != nil {
+		t.Fatalf("expected en passant from e5 to d6 to work: %s", err)
+	}
+	if _, err := b.GetPieceAt("d5"); err == nil {
+		t.Error("expected there to be no pawn at location d5")
+	}
+
+	// Without the en passant target, the same move isn't allowed.
+	b, err = ParseFEN("rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR w KQkq - 0 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.MoveByLocation("e5", "d6"); err == nil {
+		t.Error("expected en passant from e5 to d6 not to work")
+	}
+}
+
+func TestParseFENCastlingRights(t *testing.T) {
+	b, err := ParseFEN("r3k2r/8/8/8/8/8/8/R3K2R w Kq - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// White can't castle queen-side.
+	if err := b.MoveByLocation("e1", "c1"); err != ErrKingOrRookMoved {
+		t.Errorf("expected ErrKingOrRookMoved error, got %v", err)
+	}
+	// White can castle king-side.
+	if err := b.MoveByLocation("e1", "g1"); err != nil {
+		t.Error(err)
+	}
+	// Black can't castle king-side.
+	if err := b.MoveByLocation("e8", "g8"); err != ErrKingOrRookMoved {
+		t.Errorf("expected ErrKingOrRookMoved error, got %v", err)
+	}
+	// Black can castle queen-side.
+	if err := b.MoveByLocation("e8", "c8"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseFENCheckmateAndStalemate(t *testing.T) {
+	// Fool's mate.
+	b, err := ParseFEN("rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hasCheck, color := b.HasCheck()
+	if !hasCheck || color != White {
+		t.Error("expected white to be in check")
+	}
+	if !b.InCheckmate(White) {
+		t.Error("expected white to be in checkmate")
+	}
+
+	b, err = ParseFEN("7k/5K2/6Q1/8/8/8/8/8 b - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.HasStalemate(b.Turn()) {
+		t.Error("expected there to be a stalemate")
+	}
+}
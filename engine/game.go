@@ -0,0 +1,345 @@
+package engine
+
+import "fmt"
+
+// A Status describes whether a game is still being played, or how it
+// ended.
+type Status uint8
+
+const (
+	// StatusOngoing means that the game hasn't ended yet.
+	StatusOngoing Status = iota
+
+	// StatusCheckmate means that the side to move is in checkmate.
+	StatusCheckmate
+
+	// StatusStalemate means that the side to move isn't in check but
+	// doesn't have any legal moves.
+	StatusStalemate
+
+	// StatusDraw means that the game is drawn for a reason other than
+	// a stalemate or an agreement, such as a repetition.
+	StatusDraw
+
+	// StatusResignation means that a player resigned.
+	StatusResignation
+
+	// StatusTimeout means that a player ran out of time.
+	StatusTimeout
+
+	// StatusAgreement means that both players agreed to a draw.
+	StatusAgreement
+)
+
+// String returns a string for a Status.
+func (s Status) String() string {
+	switch s {
+	case StatusOngoing:
+		return "ongoing"
+	case StatusCheckmate:
+		return "checkmate"
+	case StatusStalemate:
+		return "stalemate"
+	case StatusDraw:
+		return "draw"
+	case StatusResignation:
+		return "resignation"
+	case StatusTimeout:
+		return "timeout"
+	case StatusAgreement:
+		return "draw by agreement"
+	default:
+		return "invalid status"
+	}
+}
+
+// A Game describes a game of chess being played on a board, and is
+// the one place that decides whether and how the game has ended.
+type Game struct {
+	*Board
+
+	// status holds a status that ended the game from outside of the
+	// board, such as a resignation, or StatusOngoing if there isn't
+	// one.
+	status Status
+
+	// winner holds the color that won the game when status ended it.
+	winner Color
+
+	// drawReason holds the reason for a draw that's been claimed.
+	drawReason DrawReason
+
+	// drawOffer holds the color that offered a draw, or an invalid
+	// color if there's no draw offer.
+	drawOffer Color
+
+	// drawOfferUntil holds the last move index in the board's history
+	// that the draw offer is still open for. The offer lapses as soon
+	// as the opponent makes a move.
+	drawOfferUntil int
+}
+
+// NewGame creates a new game that's played on board b.
+func NewGame(b *Board) *Game {
+	// 2 for invalid color.
+	return &Game{Board: b, winner: 2, drawOffer: 2}
+}
+
+// Status returns the game's status and the color of the winner, or an
+// invalid color if the game hasn't ended or is drawn.
+func (g *Game) Status() (Status, Color) {
+	if g.status != StatusOngoing {
+		return g.status, g.winner
+	}
+
+	// The game can't end while a pawn is waiting to be promoted.
+	if mustPromote, _ := g.MustPromote(); mustPromote {
+		return StatusOngoing, 2
+	}
+
+	turn := g.Turn()
+	if g.InCheckmate(turn) {
+		return StatusCheckmate, turn ^ 1
+	}
+	switch reason := g.Board.DrawReason(); {
+	case reason == Stalemate:
+		return StatusStalemate, 2
+	case reason != NoDraw && !reason.Claimable():
+		return StatusDraw, 2
+	}
+	return StatusOngoing, 2 // 2 for invalid color.
+}
+
+// Over reports whether the game has ended.
+func (g *Game) Over() bool {
+	status, _ := g.Status()
+	return status != StatusOngoing
+}
+
+// Move moves the piece at p1 to p2, or returns ErrGameOver if the game
+// has ended.
+func (g *Game) Move(p1, p2 Pos) error {
+	if g.Over() {
+		return ErrGameOver
+	}
+	return g.Board.Move(p1, p2)
+}
+
+// MoveWithPromotion moves the piece at p1 to p2, promoting a pawn to
+// promo, or returns ErrGameOver if the game has ended.
+func (g *Game) MoveWithPromotion(p1, p2 Pos, promo PieceName) error {
+	if g.Over() {
+		return ErrGameOver
+	}
+	return g.Board.MoveWithPromotion(p1, p2, promo)
+}
+
+// MoveByLocation makes a move from loc1 to loc2, such as e2 to e4, or
+// returns ErrGameOver if the game has ended.
+func (g *Game) MoveByLocation(loc1, loc2 string) error {
+	if g.Over() {
+		return ErrGameOver
+	}
+	return g.Board.MoveByLocation(loc1, loc2)
+}
+
+// MoveSAN makes a move in SAN, such as Nf3, or returns ErrGameOver if
+// the game has ended.
+func (g *Game) MoveSAN(san string) error {
+	if g.Over() {
+		return ErrGameOver
+	}
+	return g.Board.MoveSAN(san)
+}
+
+// PromotePawn promotes the pawn that's waiting to be promoted to to, or
+// returns ErrGameOver if the game has ended.
+func (g *Game) PromotePawn(to PieceName) error {
+	if g.Over() {
+		return ErrGameOver
+	}
+	return g.Board.PromotePawn(to)
+}
+
+// UndoMove takes back the last move. A game that ended off the board,
+// such as by a resignation, can't be taken back, and returns
+// ErrGameOver. Any draw offer is withdrawn.
+func (g *Game) UndoMove() error {
+	return g.step(g.Board.UndoMove)
+}
+
+// RedoMove makes the last move that was taken back again, or returns
+// ErrGameOver if the game ended off the board. Any draw offer is
+// withdrawn.
+func (g *Game) RedoMove() error {
+	return g.step(g.Board.RedoMove)
+}
+
+// GoToMove undoes or redoes moves until n moves from the start of the
+// history have been made, or returns ErrGameOver if the game ended off
+// the board. Any draw offer is withdrawn.
+func (g *Game) GoToMove(n int) error {
+	return g.step(func() error { return g.Board.GoToMove(n) })
+}
+
+// step moves through the board's history with f. A game that ended by
+// checkmate or a draw on the board is decided by the position, so it
+// can be stepped through, but one that ended off the board can't be.
+//
+// A draw offer is withdrawn, since it only lasts until a later move.
+func (g *Game) step(f func() error) error {
+	if g.status != StatusOngoing {
+		return ErrGameOver
+	}
+	if err := f(); err != nil {
+		return err
+	}
+	g.drawOffer = 2
+	return nil
+}
+
+// DrawReason returns the reason that the game is drawn, including a
+// draw that's been claimed, or NoDraw if it isn't drawn.
+func (g *Game) DrawReason() DrawReason {
+	if g.status == StatusDraw {
+		return g.drawReason
+	}
+	switch status, _ := g.Status(); status {
+	case StatusStalemate, StatusDraw:
+		return g.Board.DrawReason()
+	}
+	return NoDraw
+}
+
+// ClaimDraw ends the game in a draw if the side to move can claim one,
+// either by a threefold repetition or by the fifty move rule.
+func (g *Game) ClaimDraw() error {
+	if g.Over() {
+		return ErrGameOver
+	}
+	reason := g.Board.DrawReason()
+	if !reason.Claimable() {
+		return ErrNoDrawToClaim
+	}
+	g.drawReason = reason
+	g.end(StatusDraw, 2)
+	return nil
+}
+
+// Resign ends the game with color resigning.
+func (g *Game) Resign(color Color) error {
+	if g.Over() {
+		return ErrGameOver
+	}
+	g.end(StatusResignation, color^1)
+	return nil
+}
+
+// Timeout ends the game with color running out of time. The opponent
+// wins, unless no series of legal moves could lead to them checkmating,
+// in which case the game is drawn by insufficient material.
+func (g *Game) Timeout(color Color) error {
+	if g.Over() {
+		return ErrGameOver
+	}
+	if !g.Board.canCheckmate(color ^ 1) {
+		g.drawReason = InsufficientMaterial
+		g.end(StatusDraw, 2)
+		return nil
+	}
+	g.end(StatusTimeout, color^1)
+	return nil
+}
+
+// OfferDraw offers a draw from color to it's opponent. The offer stays
+// open until the opponent accepts it, declines it or makes a move.
+func (g *Game) OfferDraw(color Color) error {
+	if g.Over() {
+		return ErrGameOver
+	}
+	g.drawOffer, g.drawOfferUntil = color, g.moveNum
+
+	// If it's color's turn, the offer stays open for color's own move
+	// as well, so the opponent can answer it on their turn.
+	if g.Turn() == color {
+		g.drawOfferUntil++
+	}
+	return nil
+}
+
+// DrawOffer returns true and the color that offered a draw if there's
+// an open draw offer, or false if there isn't one.
+func (g *Game) DrawOffer() (bool, Color) {
+	if g.drawOffer == 2 || g.moveNum > g.drawOfferUntil || g.Over() {
+		return false, 2 // 2 for invalid color.
+	}
+	return true, g.drawOffer
+}
+
+// AcceptDraw accepts an open draw offer for color, ending the game in a
+// draw. Only the opponent of the color that offered the draw can accept
+// it.
+func (g *Game) AcceptDraw(color Color) error {
+	if g.Over() {
+		return ErrGameOver
+	}
+	if err := g.answerDraw(color); err != nil {
+		return err
+	}
+	g.drawOffer = 2
+	g.end(StatusAgreement, 2)
+	return nil
+}
+
+// DeclineDraw declines an open draw offer for color. Only the opponent
+// of the color that offered the draw can decline it.
+func (g *Game) DeclineDraw(color Color) error {
+	if err := g.answerDraw(color); err != nil {
+		return err
+	}
+	g.drawOffer = 2
+	return nil
+}
+
+// answerDraw returns an error if there's no open draw offer for color
+// to answer.
+func (g *Game) answerDraw(color Color) error {
+	offered, offeredBy := g.DrawOffer()
+	if !offered {
+		return ErrNoDrawOffer
+	}
+	if offeredBy == color {
+		return ErrOwnDrawOffer
+	}
+	return nil
+}
+
+// end ends the game with the specified status and winner.
+func (g *Game) end(status Status, winner Color) {
+	g.status, g.winner = status, winner
+}
+
+// Message returns a message describing the game's status, such as
+// "white is in check" or "black is in checkmate", or an empty string
+// if there's nothing to report.
+func (g *Game) Message() string {
+	status, winner := g.Status()
+	switch status {
+	case StatusCheckmate:
+		return fmt.Sprintf("%s is in checkmate", winner^1)
+	case StatusStalemate:
+		return "stalemate"
+	case StatusDraw:
+		return fmt.Sprintf("draw by %s", g.DrawReason())
+	case StatusResignation:
+		return fmt.Sprintf("%s resigned", winner^1)
+	case StatusTimeout:
+		return fmt.Sprintf("%s ran out of time", winner^1)
+	case StatusAgreement:
+		return "draw by agreement"
+	}
+	if hasCheck, color := g.HasCheck(); hasCheck {
+		return fmt.Sprintf("%s is in check", color)
+	}
+	return ""
+}
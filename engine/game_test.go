@@ -0,0 +1,328 @@
+package engine
+
+import "testing"
+
+func TestGameStatus(t *testing.T) {
+	testCases := []struct {
+		fen    string
+		status Status
+		winner Color
+		msg    string
+	}{
+		{StartFEN, StatusOngoing, 2, ""},
+		{"rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3",
+			StatusCheckmate, Black, "white is in checkmate"},
+		{"4k3/8/8/8/8/8/8/R3K3 b - - 0 1", StatusOngoing, 2, ""},
+		{"4k3/8/8/8/8/8/8/4RK2 b - - 0 1", StatusOngoing, 2, "black is in check"},
+		{"7k/5K2/6Q1/8/8/8/8/8 b - - 0 1", StatusStalemate, 2, "stalemate"},
+		{"4k3/8/8/8/8/8/8/4KB2 w - - 0 1", StatusDraw, 2, "draw by insufficient material"},
+		// A claimable draw doesn't end the game.
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 100 80", StatusOngoing, 2, ""},
+	}
+	for _, tc := range testCases {
+		b, err := ParseFEN(tc.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := NewGame(b)
+		status, winner := g.Status()
+		if status != tc.status || winner != tc.winner {
+			t.Errorf("%s: expected status %s and winner %d, got %s and %d",
+				tc.fen, tc.status, tc.winner, status, winner)
+		}
+		if g.Over() != (tc.status != StatusOngoing) {
+			t.Errorf("%s: expected Over to be %t", tc.fen, !g.Over())
+		}
+		if msg := g.Message(); msg != tc.msg {
+			t.Errorf("%s: expected message %q, got %q", tc.fen, tc.msg, msg)
+		}
+	}
+}
+
+func TestGameStatusAfterMoves(t *testing.T) {
+	g := NewGame(NewBoard())
+	for _, move := range []string{"e2e4", "e7e5", "f1c4", "b8c6", "d1h5", "d7d6"} {
+		if err := g.MoveByLocation(move[0:2], move[2:]); err != nil {
+			t.Fatal(err)
+		}
+		if g.Over() {
+			t.Fatalf("expected game not to be over after %s", move)
+		}
+	}
+	if err := g.MoveByLocation("h5", "f7"); err != nil {
+		t.Fatal(err)
+	}
+	if status, winner := g.Status(); status != StatusCheckmate || winner != White {
+		t.Errorf("expected white to win by checkmate, got %s and %d", status, winner)
+	}
+
+	// Undoing the checkmate carries the game on.
+	if err := g.UndoMove(); err != nil {
+		t.Fatal(err)
+	}
+	if g.Over() {
+		t.Error("expected game not to be over")
+	}
+}
+
+func TestGameStatusWhilePromoting(t *testing.T) {
+	b, err := ParseFEN("k7/2P5/1K6/8/8/8/8/8 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(b)
+
+	// Black has no legal moves while white is promoting, but that
+	// isn't a stalemate.
+	if err := g.MoveByLocation("c7", "c8"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Over() {
+		t.Error("expected game not to be over while promoting")
+	}
+	if err := g.PromotePawn(Rook); err != nil {
+		t.Fatal(err)
+	}
+	if status, winner := g.Status(); status != StatusCheckmate || winner != White {
+		t.Errorf("expected white to win by checkmate, got %s and %d", status, winner)
+	}
+}
+
+func TestGameClaimDraw(t *testing.T) {
+	g := NewGame(NewBoard())
+	if err := g.ClaimDraw(); err != ErrNoDrawToClaim {
+		t.Errorf("expected ErrNoDrawToClaim error, got %v", err)
+	}
+
+	shuffle := []string{"g1f3", "g8f6", "f3g1", "f6g8"}
+	for i := 0; i < 2; i++ {
+		for _, move := range shuffle {
+			if err := g.MoveByLocation(move[0:2], move[2:]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if g.Over() {
+		t.Error("expected game not to be over before claiming a draw")
+	}
+	if err := g.ClaimDraw(); err != nil {
+		t.Fatal(err)
+	}
+	if status, winner := g.Status(); status != StatusDraw || winner != 2 {
+		t.Errorf("expected a draw, got %s and %d", status, winner)
+	}
+	if reason := g.DrawReason(); reason != ThreefoldRepetition {
+		t.Errorf("expected draw reason to be %s, got %s", ThreefoldRepetition, reason)
+	}
+	if msg := g.Message(); msg != "draw by threefold repetition" {
+		t.Errorf("expected message %q, got %q", "draw by threefold repetition", msg)
+	}
+	if err := g.ClaimDraw(); err != ErrGameOver {
+		t.Errorf("expected ErrGameOver error, got %v", err)
+	}
+}
+
+func TestGameResign(t *testing.T) {
+	g := NewGame(NewBoard())
+	if err := g.Resign(White); err != nil {
+		t.Fatal(err)
+	}
+	if status, winner := g.Status(); status != StatusResignation || winner != Black {
+		t.Errorf("expected black to win by resignation, got %s and %d", status, winner)
+	}
+	if msg := g.Message(); msg != "white resigned" {
+		t.Errorf("expected message %q, got %q", "white resigned", msg)
+	}
+	if err := g.Resign(Black); err != ErrGameOver {
+		t.Errorf("expected ErrGameOver error, got %v", err)
+	}
+
+	// No more moves can be made once the game has ended.
+	if err := g.MoveSAN("e4"); err != ErrGameOver {
+		t.Errorf("expected ErrGameOver error, got %v", err)
+	}
+	if err := g.MoveByLocation("e2", "e4"); err != ErrGameOver {
+		t.Errorf("expected ErrGameOver error, got %v", err)
+	}
+	if err := g.Move(Pos{4, 1}, Pos{4, 3}); err != ErrGameOver {
+		t.Errorf("expected ErrGameOver error, got %v", err)
+	}
+	if fen := g.FEN(); fen != StartFEN {
+		t.Errorf("expected the starting position, got %s", fen)
+	}
+}
+
+func TestGameUndoAfterEnd(t *testing.T) {
+	g := NewGame(NewBoard())
+	if err := g.MoveSAN("e4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Resign(Black); err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range []func() error{g.UndoMove, g.RedoMove, func() error { return g.GoToMove(0) }} {
+		if err := step(); err != ErrGameOver {
+			t.Errorf("expected ErrGameOver error, got %v", err)
+		}
+	}
+	if status, winner := g.Status(); status != StatusResignation || winner != White || len(g.Moves()) != 1 {
+		t.Errorf("expected the game to stay resigned after e4, got %s and %d", status, winner)
+	}
+
+	// A checkmate is decided by the position, so it can be taken back.
+	g = NewGame(NewBoard())
+	for _, san := range []string{"f3", "e5", "g4", "Qh4#"} {
+		if err := g.MoveSAN(san); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.UndoMove(); err != nil {
+		t.Fatal(err)
+	}
+	if g.Over() {
+		t.Error("expected the game to go on after taking back the checkmate")
+	}
+}
+
+func TestGameUndoDrawOffer(t *testing.T) {
+	g := NewGame(NewBoard())
+
+	// White's offer during black's turn lapses when black moves, and
+	// doesn't come back when black's move is taken back.
+	if err := g.MoveSAN("e4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.OfferDraw(White); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveSAN("e5"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.UndoMove(); err != nil {
+		t.Fatal(err)
+	}
+	if offered, _ := g.DrawOffer(); offered {
+		t.Error("expected the lapsed draw offer to stay lapsed after undoing")
+	}
+	if err := g.AcceptDraw(Black); err != ErrNoDrawOffer {
+		t.Errorf("expected ErrNoDrawOffer error, got %v", err)
+	}
+
+	// An open offer is withdrawn by taking a move back.
+	if err := g.OfferDraw(Black); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.UndoMove(); err != nil {
+		t.Fatal(err)
+	}
+	if offered, _ := g.DrawOffer(); offered {
+		t.Error("expected the draw offer to be withdrawn after undoing")
+	}
+}
+
+func TestGameTimeout(t *testing.T) {
+	testCases := []struct {
+		fen    string
+		status Status
+		winner Color
+		msg    string
+	}{
+		{StartFEN, StatusTimeout, Black, "white ran out of time"},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 0 1", StatusDraw, 2, "draw by insufficient material"},
+		// White's own pieces could help a single knight to checkmate.
+		{"4k3/8/8/8/8/8/5n2/R3K3 w - - 0 1", StatusTimeout, Black, "white ran out of time"},
+		{"4k3/8/8/8/8/8/P4n2/4K3 w - - 0 1", StatusTimeout, Black, "white ran out of time"},
+		{"4k3/8/8/8/8/8/4bn2/R3K3 w - - 0 1", StatusTimeout, Black, "white ran out of time"},
+		{"4k3/4p3/8/8/8/8/8/R3K3 w - - 0 1", StatusTimeout, Black, "white ran out of time"},
+	}
+	for _, tc := range testCases {
+		b, err := ParseFEN(tc.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := NewGame(b)
+		if err := g.Timeout(White); err != nil {
+			t.Fatal(err)
+		}
+		status, winner := g.Status()
+		if status != tc.status || winner != tc.winner {
+			t.Errorf("%s: expected status %s and winner %d, got %s and %d",
+				tc.fen, tc.status, tc.winner, status, winner)
+		}
+		if msg := g.Message(); msg != tc.msg {
+			t.Errorf("%s: expected message %q, got %q", tc.fen, tc.msg, msg)
+		}
+		if err := g.Timeout(Black); err != ErrGameOver {
+			t.Errorf("%s: expected error %v, got %v", tc.fen, ErrGameOver, err)
+		}
+	}
+}
+
+func TestGameDrawOffer(t *testing.T) {
+	g := NewGame(NewBoard())
+	if err := g.AcceptDraw(Black); err != ErrNoDrawOffer {
+		t.Errorf("expected ErrNoDrawOffer error, got %v", err)
+	}
+
+	// White offers a draw and then makes a move, which keeps the offer
+	// open for black to answer.
+	if err := g.OfferDraw(White); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveByLocation("e2", "e4"); err != nil {
+		t.Fatal(err)
+	}
+	if offered, color := g.DrawOffer(); !offered || color != White {
+		t.Error("expected white's draw offer to be open")
+	}
+
+	// Black declines it.
+	if err := g.DeclineDraw(Black); err != nil {
+		t.Fatal(err)
+	}
+	if offered, _ := g.DrawOffer(); offered {
+		t.Error("expected no open draw offer")
+	}
+	if err := g.DeclineDraw(Black); err != ErrNoDrawOffer {
+		t.Errorf("expected ErrNoDrawOffer error, got %v", err)
+	}
+
+	// White offers a draw during black's turn, so it lapses as soon
+	// as black moves.
+	if err := g.OfferDraw(White); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveByLocation("e7", "e5"); err != nil {
+		t.Fatal(err)
+	}
+	if offered, _ := g.DrawOffer(); offered {
+		t.Error("expected the draw offer to lapse after black's move")
+	}
+	if err := g.AcceptDraw(Black); err != ErrNoDrawOffer {
+		t.Errorf("expected ErrNoDrawOffer error, got %v", err)
+	}
+
+	// White offers a draw again and black accepts it.
+	if err := g.OfferDraw(White); err != nil {
+		t.Fatal(err)
+	}
+	// White can't answer it's own offer.
+	if err := g.AcceptDraw(White); err != ErrOwnDrawOffer {
+		t.Errorf("expected ErrOwnDrawOffer error, got %v", err)
+	}
+	if err := g.DeclineDraw(White); err != ErrOwnDrawOffer {
+		t.Errorf("expected ErrOwnDrawOffer error, got %v", err)
+	}
+	if err := g.MoveByLocation("g1", "f3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AcceptDraw(Black); err != nil {
+		t.Fatal(err)
+	}
+	if status, winner := g.Status(); status != StatusAgreement || winner != 2 {
+		t.Errorf("expected a draw by agreement, got %s and %d", status, winner)
+	}
+	if err := g.OfferDraw(Black); err != ErrGameOver {
+		t.Errorf("expected ErrGameOver error, got %v", err)
+	}
+}
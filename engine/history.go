@@ -10,63 +10,53 @@ func (b *Board) UndoMove() error {
 	}
 
 	// Put the move's piece back to position from.
-	b.posToPiece[move.From] = move.Piece
+	b.setPiece(move.From, move.Piece)
 
 	// Delete the piece from position to.
-	delete(b.posToPiece, move.To)
+	b.removePiece(move.To)
 
 	// Put anything that was captured, back at position to.
 	if move.Captured != nil {
 		if move.EnPassant {
-			b.posToPiece[Pos{move.To.X, move.From.Y}] = move.Captured
+			b.setPiece(Pos{move.To.X, move.From.Y}, move.Captured)
 		} else {
-			b.posToPiece[move.To] = move.Captured
+			b.setPiece(move.To, move.Captured)
 		}
 	}
 
 	// Decrement b.hasMoved for move.Piece.
 	b.hasMoved[move.Piece]--
 
-	// If piece is a king, set it's position back to from.
+	// If piece is a king, check if the move was a castling move.
 	if move.Piece.Name == King {
-		b.kings[move.Piece.Color] = move.From
-
-		// Check if the move was a castling move.
-		//
 		// King side castling.
 		if move.From.X == move.To.X+2 {
 			// Put the rook back to Pos{0, move.To.Y}
-			rook, found := b.posToPiece[Pos{move.To.X + 1, move.To.Y}]
+			rook, found := b.pieceAt(Pos{move.To.X + 1, move.To.Y})
 			if !found {
 				return fmt.Errorf("error: undo castling, rook not found")
 			}
-			b.posToPiece[Pos{0, move.To.Y}] = rook
+			b.setPiece(Pos{0, move.To.Y}, rook)
 
 			// Delete the rook from it's castled position.
-			delete(b.posToPiece, Pos{move.To.X + 1, move.To.Y})
+			b.removePiece(Pos{move.To.X + 1, move.To.Y})
 		}
 		// Queen side castling.
 		if move.From.X == move.To.X-2 {
 			// Put the rook back to Pos{8, move.To.Y}
-			rook, found := b.posToPiece[Pos{move.To.X - 1, move.To.Y}]
+			rook, found := b.pieceAt(Pos{move.To.X - 1, move.To.Y})
 			if !found {
 				return fmt.Errorf("error: undo castling, rook not found")
 			}
-			b.posToPiece[Pos{7, move.To.Y}] = rook
+			b.setPiece(Pos{7, move.To.Y}, rook)
 
 			// Delete the rook from it's castled position.
-			delete(b.posToPiece, Pos{move.To.X - 1, move.To.Y})
+			b.removePiece(Pos{move.To.X - 1, move.To.Y})
 		}
 	}
 
 	// Set the checks on the board back to the previous move's checks.
-	b.check[White], b.check[Black] = false, false
-	if b.kingInCheck(White) {
-		b.check[White] = true
-	}
-	if b.kingInCheck(Black) {
-		b.check[Black] = true
-	}
+	b.updateChecks()
 
 	// Set the turn to piece's color.
 	b.turn = move.Piece.Color
@@ -74,6 +64,15 @@ func (b *Board) UndoMove() error {
 	// Decrement b.moveNum.
 	b.moveNum--
 
+	// Since the move being undone can't need promoting anymore, only
+	// the move before it might still need promoting.
+	b.updateMustPromote()
+
+	// Update the board's hash for the previous position.
+	b.updateHash()
+
+	b.emit(EventUndo, move, move.Piece.Color)
+
 	return nil
 }
 
@@ -84,5 +83,144 @@ func (b *Board) prevMove() (*MoveInfo, error) {
 	return b.history[b.moveNum], nil
 }
 
-// TODO: Redo move in history.
-// func (b *Board) RedoMove() error {}
+// updateMustPromote sets which color needs to promote a pawn based on
+// whether the previous move was a pawn reaching the last rank without
+// having been promoted yet.
+func (b *Board) updateMustPromote() {
+	b.mustPromote[White], b.mustPromote[Black] = false, false
+	move, err := b.prevMove()
+	if err != nil {
+		return
+	}
+	if move.Piece.Name == Pawn && move.Promotion == nil &&
+		(move.To.Y == 7 || move.To.Y == 0) {
+		b.mustPromote[move.Piece.Color] = true
+	}
+}
+
+func (b *Board) nextMove() (*MoveInfo, error) {
+	if b.moveNum+1 >= len(b.history) {
+		return nil, ErrNoNextMove
+	}
+	return b.history[b.moveNum+1], nil
+}
+
+// RedoMove re-applies the next move in the board's history after
+// it's been undone by UndoMove.
+func (b *Board) RedoMove() error {
+	// Get the next move from b.history.
+	move, err := b.nextMove()
+	if err != nil {
+		return err
+	}
+
+	// Remove the piece from position from.
+	b.removePiece(move.From)
+
+	// If the move was an en passant, remove the captured pawn.
+	if move.EnPassant {
+		b.removePiece(Pos{move.To.X, move.From.Y})
+	}
+
+	// Put the move's piece at position to, or the piece it was
+	// promoted to if it was a promotion.
+	b.setPiece(move.To, move.Piece)
+	if move.Promotion != nil {
+		b.setPiece(move.To, move.Promotion)
+	}
+
+	// If piece is a king, check if the move was a castling move and
+	// if it was, move the rook to it's castled position.
+	if move.Piece.Name == King {
+		// Queen side castling.
+		if move.From.X == move.To.X+2 {
+			rook, found := b.pieceAt(Pos{0, move.To.Y})
+			if !found {
+				return fmt.Errorf("error: redo castling, rook not found")
+			}
+			b.setPiece(Pos{move.To.X + 1, move.To.Y}, rook)
+			b.removePiece(Pos{0, move.To.Y})
+		}
+		// King side castling.
+		if move.From.X == move.To.X-2 {
+			rook, found := b.pieceAt(Pos{7, move.To.Y})
+			if !found {
+				return fmt.Errorf("error: redo castling, rook not found")
+			}
+			b.setPiece(Pos{move.To.X - 1, move.To.Y}, rook)
+			b.removePiece(Pos{7, move.To.Y})
+		}
+	}
+
+	// Increment b.hasMoved for move.Piece.
+	b.hasMoved[move.Piece]++
+
+	// Update the checks for both kings.
+	b.updateChecks()
+
+	// Set the turn to the opponent of piece's color.
+	b.turn = move.Piece.Color ^ 1
+
+	// Increment b.moveNum.
+	b.moveNum++
+
+	// If the move was a pawn reaching the last rank without being
+	// promoted yet, it still needs to be promoted.
+	b.updateMustPromote()
+
+	// Update the board's hash for the next position.
+	b.updateHash()
+
+	b.emitMove(move)
+
+	return nil
+}
+
+// GoToMove undoes or redoes moves in the board's history until n
+// moves from the start of the history have been made on the board.
+//
+// GoToMove(0) goes back to the board's starting position.
+func (b *Board) GoToMove(n int) error {
+	if n < 0 {
+		return ErrNoPreviousMove
+	}
+	if n > len(b.history) {
+		return ErrNoNextMove
+	}
+	for b.moveNum+1 > n {
+		if err := b.UndoMove(); err != nil {
+			return err
+		}
+	}
+	for b.moveNum+1 < n {
+		if err := b.RedoMove(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Moves returns the moves that have been made on the board from it's
+// starting position up to the current move, not including any moves
+// that have been undone.
+func (b *Board) Moves() []Move {
+	moves := make([]Move, b.moveNum+1)
+	for i, m := range b.history[:b.moveNum+1] {
+		moves[i] = Move{From: m.From, To: m.To}
+		if m.Promotion != nil {
+			moves[i].Promotion = m.Promotion.Name
+		}
+	}
+	return moves
+}
+
+// LastMove returns a copy of the last move that was made on the board,
+// or ErrNoPreviousMove if no moves have been made.
+func (b *Board) LastMove() (*MoveInfo, error) {
+	move, err := b.prevMove()
+	if err != nil {
+		return nil, err
+	}
+	m := *move
+	return &m, nil
+}
@@ -1,6 +1,9 @@
 package engine
 
-import "testing"
+import (
+	"math/rand"
+	"testing"
+)
 
 func TestHistoryEmpty(t *testing.T) {
 	b := NewBoard()
@@ -17,7 +20,7 @@ func TestHistoryEmpty(t *testing.T) {
 
 	// Undo the previous move.
 	if err := b.UndoMove(); err != nil {
-		t.Error("expected undo to work: %s", err.Error())
+		t.Errorf("expected undo to work: %s", err.Error())
 	}
 
 	// Undo a second time, which this time, shouldn't work.
@@ -79,9 +82,9 @@ func TestUndoTakePiece(t *testing.T) {
 	}
 
 	// Check that a piece was taken.
-	if len(b.posToPiece) != 31 {
+	if b.occupied().count() != 31 {
 		t.Errorf("expected there to only be 31 pieces, got %d",
-			len(b.posToPiece))
+			b.occupied().count())
 	}
 
 	// Make sure there's no longer a pawn at location d4.
@@ -95,9 +98,9 @@ func TestUndoTakePiece(t *testing.T) {
 	}
 
 	// Check that there's 32 pieces again.
-	if len(b.posToPiece) != 32 {
+	if b.occupied().count() != 32 {
 		t.Errorf("expected there to be 32 pieces, got %d",
-			len(b.posToPiece))
+			b.occupied().count())
 	}
 
 	// Make sure there's a pawn at d4.
@@ -122,8 +125,8 @@ func TestUndoCastlingQueenSide(t *testing.T) {
 	wR := &Piece{Rook, White}
 
 	// Add king and rook on the queen-side for white.
-	b.posToPiece[Pos{4, 0}] = wK
-	b.posToPiece[Pos{0, 0}] = wR
+	b.setPiece(Pos{4, 0}, wK)
+	b.setPiece(Pos{0, 0}, wR)
 
 	// Castle white by moving the king over 2 squares queen-side.
 	if err := b.MoveByLocation("e1", "c1"); err != nil {
@@ -187,8 +190,8 @@ func TestUndoCastlingKingSide(t *testing.T) {
 	bR := &Piece{Rook, Black}
 
 	// Add king and rook on the king-side for black.
-	b.posToPiece[Pos{4, 7}] = bK
-	b.posToPiece[Pos{7, 7}] = bR
+	b.setPiece(Pos{4, 7}, bK)
+	b.setPiece(Pos{7, 7}, bR)
 
 	// Castle black by moving the king over 2 squares king-side.
 	if err := b.MoveByLocation("e8", "g8"); err != nil {
@@ -240,7 +243,381 @@ func TestUndoCastlingKingSide(t *testing.T) {
 	}
 }
 
-// TODO: Undo en passant.
-// TODO: Undo promotion.
-//
+func TestUndoEnPassant(t *testing.T) {
+	b := NewBoard()
+
+	moves := []struct {
+		from, to string
+	}{
+		{"a2", "a4"},
+		{"a7", "a6"},
+		{"a4", "a5"},
+		{"b7", "b5"},
+		{"a5", "b6"}, // En passant.
+	}
+
+	for _, move := range moves {
+		if err := b.MoveByLocation(move.from, move.to); err != nil {
+			t.Fatalf("moving from %s to %s failed: %s",
+				move.from, move.to, err.Error())
+		}
+	}
+
+	if err := b.UndoMove(); err != nil {
+		t.Error(err)
+	}
+
+	// Make sure the black pawn is back at location b5.
+	piece, err := b.GetPieceAt("b5")
+	if err != nil || piece.Name != Pawn || piece.Color != Black {
+		t.Error("expected there to be a black pawn at location b5")
+	}
+	// Make sure the white pawn is back at location a5.
+	piece, err = b.GetPieceAt("a5")
+	if err != nil || piece.Name != Pawn || piece.Color != White {
+		t.Error("expected there to be a white pawn at location a5")
+	}
+	// Make sure there's no piece at location b6.
+	if _, err := b.GetPieceAt("b6"); err == nil {
+		t.Error("expected there to be no piece at location b6")
+	}
+}
+
+func TestRedoMove(t *testing.T) {
+	b := NewBoard()
+
+	// No moves have been undone, expect an ErrNoNextMove error.
+	if err := b.RedoMove(); err != ErrNoNextMove {
+		t.Errorf("expected ErrNoNextMove error, got %v", err)
+	}
+
+	// A game with a capture, an en passant and castling on both sides.
+	moves := []string{
+		"e4", "d5", "exd5", "c5", "dxc6", "Nf6", "Nf3", "Qc7", "Bc4",
+		"Bd7", "O-O", "Na6", "d3", "O-O-O",
+	}
+
+	fens := []string{b.FEN()}
+	for _, san := range moves {
+		if err := b.MoveSAN(san); err != nil {
+			t.Fatalf("moving %s failed: %s", san, err)
+		}
+		fens = append(fens, b.FEN())
+	}
+
+	// Undo every move and then redo every move, making sure that the
+	// board is in the same position as it was after each move.
+	for i := len(moves) - 1; i >= 0; i-- {
+		if err := b.UndoMove(); err != nil {
+			t.Fatal(err)
+		}
+		if fen := b.FEN(); fen != fens[i] {
+			t.Errorf("expected FEN after undo to be %q, got %q", fens[i], fen)
+		}
+	}
+	for i := 1; i <= len(moves); i++ {
+		if err := b.RedoMove(); err != nil {
+			t.Fatal(err)
+		}
+		if fen := b.FEN(); fen != fens[i] {
+			t.Errorf("expected FEN after redo to be %q, got %q", fens[i], fen)
+		}
+	}
+
+	// All moves have been redone, expect an ErrNoNextMove error.
+	if err := b.RedoMove(); err != ErrNoNextMove {
+		t.Errorf("expected ErrNoNextMove error, got %v", err)
+	}
+
+	// Making a new move after an undo replaces the forward history.
+	if err := b.UndoMove(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.MoveSAN("Kd8"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.RedoMove(); err != ErrNoNextMove {
+		t.Errorf("expected ErrNoNextMove error, got %v", err)
+	}
+}
+
+func TestGoToMove(t *testing.T) {
+	b := NewBoard()
+
+	moves := []string{"e4", "e5", "Nf3", "Nc6", "Bb5", "a6"}
+
+	fens := []string{b.FEN()}
+	for _, san := range moves {
+		if err := b.MoveSAN(san); err != nil {
+			t.Fatalf("moving %s failed: %s", san, err)
+		}
+		fens = append(fens, b.FEN())
+	}
+
+	for _, n := range []int{0, 3, 6, 1, 4, 4, 2} {
+		if err := b.GoToMove(n); err != nil {
+			t.Fatal(err)
+		}
+		if fen := b.FEN(); fen != fens[n] {
+			t.Errorf("expected FEN at move %d to be %q, got %q", n, fens[n], fen)
+		}
+	}
+
+	if err := b.GoToMove(-1); err != ErrNoPreviousMove {
+		t.Errorf("expected ErrNoPreviousMove error, got %v", err)
+	}
+	if err := b.GoToMove(7); err != ErrNoNextMove {
+		t.Errorf("expected ErrNoNextMove error, got %v", err)
+	}
+}
+
+func TestUndoPromotion(t *testing.T) {
+	b, err := ParseFEN("1n2k3/P7/8/8/8/8/8/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Take the knight on b8 and promote to a queen, checking the king.
+	if err := b.MoveByLocation("a7", "b8"); err != nil {
+		t.Fatal(err)
+	}
+	if mustPromote, color := b.MustPromote(); !mustPromote || color != White {
+		t.Error("expected white to have to promote")
+	}
+	if err := b.PromotePawn(Queen); err != nil {
+		t.Fatal(err)
+	}
+	if hasCheck, color := b.HasCheck(); !hasCheck || color != Black {
+		t.Error("expected black to be in check")
+	}
+
+	// Undo the promotion.
+	if err := b.UndoMove(); err != nil {
+		t.Fatal(err)
+	}
+	if hasCheck, _ := b.HasCheck(); hasCheck {
+		t.Error("expected there to be no check")
+	}
+	if mustPromote, _ := b.MustPromote(); mustPromote {
+		t.Error("expected no pawn to need promoting")
+	}
+	if checkers := b.attackers(b.kingPos(Black), White, b.occupied()); checkers != 0 {
+		t.Errorf("expected black king to have no attackers, got %d",
+			checkers.count())
+	}
+	piece, err := b.GetPieceAt("a7")
+	if err != nil || piece.Name != Pawn {
+		t.Error("expected there to be a pawn at location a7")
+	}
+	piece, err = b.GetPieceAt("b8")
+	if err != nil || piece.Name != Knight {
+		t.Error("expected there to be a knight at location b8")
+	}
+
+	// Redo the promotion.
+	if err := b.RedoMove(); err != nil {
+		t.Fatal(err)
+	}
+	piece, err = b.GetPieceAt("b8")
+	if err != nil || piece.Name != Queen {
+		t.Error("expected there to be a queen at location b8")
+	}
+	if hasCheck, color := b.HasCheck(); !hasCheck || color != Black {
+		t.Error("expected black to be in check")
+	}
+
+	// Undoing a pawn move that's waiting to be promoted means that
+	// nothing needs promoting anymore.
+	b, err = ParseFEN("4k3/P7/8/8/8/8/8/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.MoveByLocation("a7", "a8"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.UndoMove(); err != nil {
+		t.Fatal(err)
+	}
+	if mustPromote, _ := b.MustPromote(); mustPromote {
+		t.Error("expected no pawn to need promoting")
+	}
+	if err := b.PromotePawn(Queen); err != ErrNoPawnToPromote {
+		t.Errorf("expected ErrNoPawnToPromote error, got %v", err)
+	}
+}
+
+// boardState holds a copy of all of a board's state that moves change.
+type boardState struct {
+	fen         string
+	squares     [64]*Piece
+	pieces      [2][6]bitboard
+	colors      [2]bitboard
+	check       [2]bool
+	hasMoved    map[*Piece]int
+	mustPromote [2]bool
+}
+
+func newBoardState(b *Board) boardState {
+	s := boardState{
+		fen:         b.FEN(),
+		squares:     b.squares,
+		pieces:      b.pieces,
+		colors:      b.colors,
+		check:       b.check,
+		hasMoved:    make(map[*Piece]int),
+		mustPromote: b.mustPromote,
+	}
+	for piece, n := range b.hasMoved {
+		// Pieces that haven't moved are the same as missing pieces.
+		if n != 0 {
+			s.hasMoved[piece] = n
+		}
+	}
+	return s
+}
+
+// diff returns a description of the first difference between s and
+// s2, or an empty string if they're identical.
+func (s boardState) diff(s2 boardState) string {
+	switch {
+	case s.fen != s2.fen:
+		return "fen: " + s.fen + " != " + s2.fen
+	case s.check != s2.check:
+		return "check"
+	case s.mustPromote != s2.mustPromote:
+		return "mustPromote"
+	case s.pieces != s2.pieces:
+		return "pieces"
+	case s.colors != s2.colors:
+		return "colors"
+	case len(s.hasMoved) != len(s2.hasMoved):
+		return "hasMoved"
+	}
+	for sq, piece := range s.squares {
+		if s2.squares[sq] != piece {
+			return "squares at " + squarePos(sq).String()
+		}
+	}
+	for piece, n := range s.hasMoved {
+		if s2.hasMoved[piece] != n {
+			return "hasMoved for " + piece.Name.String()
+		}
+	}
+	return ""
+}
+
+func TestUndoRedoRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	promotions := 0
+	for game := 0; game < 20; game++ {
+		b := NewBoard()
+
+		// Play random legal moves, preferring pawn moves so that more
+		// pawns get promoted.
+		states := []boardState{newBoardState(b)}
+		for ply := 0; ply < 150; ply++ {
+			moves := b.LegalMoves()
+			if len(moves) == 0 {
+				break
+			}
+			m := moves[r.Intn(len(moves))]
+			for i := 0; i < 3; i++ {
+				if b.squares[m.From.square()].Name == Pawn {
+					break
+				}
+				m = moves[r.Intn(len(moves))]
+			}
+			if err := b.Move(m.From, m.To); err != nil {
+				t.Fatalf("game %d: moving %s failed: %s", game, m, err)
+			}
+			if m.Promotion != Pawn {
+				if err := b.PromotePawn(m.Promotion); err != nil {
+					t.Fatalf("game %d: promoting %s failed: %s", game, m, err)
+				}
+				promotions++
+			}
+			states = append(states, newBoardState(b))
+		}
+
+		// Undo every move, then redo every move, making sure the
+		// board is identical to how it was after each move.
+		for i := len(states) - 2; i >= 0; i-- {
+			if err := b.UndoMove(); err != nil {
+				t.Fatalf("game %d: undo %d failed: %s", game, i, err)
+			}
+			if d := states[i].diff(newBoardState(b)); d != "" {
+				t.Fatalf("game %d: state after undo %d differs: %s", game, i, d)
+			}
+		}
+		for i := 1; i < len(states); i++ {
+			if err := b.RedoMove(); err != nil {
+				t.Fatalf("game %d: redo %d failed: %s", game, i, err)
+			}
+			if d := states[i].diff(newBoardState(b)); d != "" {
+				t.Fatalf("game %d: state after redo %d differs: %s", game, i, d)
+			}
+		}
+	}
+
+	if promotions == 0 {
+		t.Error("expected the random games to have at least one promotion")
+	}
+}
+
 // TODO: Test prevMove
+
+func TestMoves(t *testing.T) {
+	b := NewBoard()
+	for _, move := range []string{"e4", "d5", "exd5", "c6", "dxc6", "e5", "cxb7", "Ke7", "bxa8=N", "Qe8"} {
+		if err := b.MoveSAN(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.UndoMove(); err != nil {
+		t.Fatal(err)
+	}
+
+	moves := b.Moves()
+	if len(moves) != 9 {
+		t.Fatalf("expected 9 moves, got %d", len(moves))
+	}
+	if s := moves[0].String(); s != "e2e4" {
+		t.Errorf("expected first move to be e2e4, got %s", s)
+	}
+	if s := moves[8].String(); s != "b7a8n" {
+		t.Errorf("expected last move to be b7a8n, got %s", s)
+	}
+}
+
+func TestLastMove(t *testing.T) {
+	b := NewBoard()
+	if _, err := b.LastMove(); err != ErrNoPreviousMove {
+		t.Errorf("expected error %v, got %v", ErrNoPreviousMove, err)
+	}
+	for _, move := range []string{"e4", "d5", "exd5"} {
+		if err := b.MoveSAN(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := b.LastMove()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.SAN != "exd5" || m.Captured == nil || m.Captured.Name != Pawn {
+		t.Errorf("expected exd5 capturing a pawn, got %+v", m)
+	}
+
+	// The move returned is a copy of the board's history.
+	m.SAN = "Qh5"
+	if err := b.UndoMove(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.RedoMove(); err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := b.LastMove(); m.SAN != "exd5" {
+		t.Errorf("expected history to be unchanged, got %s", m.SAN)
+	}
+}
@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"sort"
+	"strings"
+)
+
+// A Move describes a move of a piece from one position to another.
+type Move struct {
+	From, To Pos
+
+	// Promotion holds the piece name that a pawn is promoted to
+	// when it reaches the last rank, or Pawn if the move isn't a
+	// promotion.
+	Promotion PieceName
+}
+
+// String returns a Move in coordinate notation. For example, e2e4,
+// or e7e8q for a pawn promoting to a queen.
+func (m Move) String() string {
+	s := m.From.loc() + m.To.loc()
+	if m.Promotion != Pawn {
+		s += string(fenPieces[m.Promotion])
+	}
+	return s
+}
+
+// ParseMove parses a move in coordinate notation, such as e2e4 or e7e8q,
+// without checking whether it's legal on any board.
+func ParseMove(s string) (Move, error) {
+	if len(s) != 4 && len(s) != 5 {
+		return Move{}, ErrInvalidLocation
+	}
+	from, err := locToPos(s[:2])
+	if err != nil {
+		return Move{}, err
+	}
+	to, err := locToPos(s[2:4])
+	if err != nil {
+		return Move{}, err
+	}
+	m := Move{From: from, To: to}
+	if len(s) == 5 {
+		i := strings.IndexByte(fenPieces, s[4]|0x20)
+		if i < int(Knight) || i > int(Queen) {
+			return Move{}, ErrInvalidPromotion
+		}
+		m.Promotion = PieceName(i)
+	}
+	return m, nil
+}
+
+// promotionPieces holds the piece names that a pawn can be promoted to.
+var promotionPieces = []PieceName{Queen, Rook, Bishop, Knight}
+
+// LegalMoves returns every legal move for the side to move, including
+// castling, en passant and a move for each promotion choice.
+func (b *Board) LegalMoves() []Move {
+	var moves []Move
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			moves = append(moves, b.LegalMovesFrom(Pos{x, y})...)
+		}
+	}
+	return moves
+}
+
+// LegalMovesFrom returns every legal move for the piece at position
+// pos, or nil if there's no piece at pos that belongs to the side to
+// move.
+func (b *Board) LegalMovesFrom(pos Pos) []Move {
+	piece, found := b.pieceAt(pos)
+	if !found || piece.Color != b.turn {
+		return nil
+	}
+
+	// No moves can be made while a pawn is waiting to be promoted.
+	if mustPromote, _ := b.MustPromote(); mustPromote {
+		return nil
+	}
+
+	var moves []Move
+	for positions := getMovePositions(piece, pos); positions != 0; {
+		to := squarePos(positions.pop())
+		if b.moveLegal(piece, pos, to) != nil {
+			continue
+		}
+		if piece.Name == Pawn && (to.Y == 7 || to.Y == 0) {
+			for _, name := range promotionPieces {
+				moves = append(moves, Move{pos, to, name})
+			}
+			continue
+		}
+		moves = append(moves, Move{From: pos, To: to})
+	}
+
+	// Castling.
+	if piece.Name == King && pos == (Pos{4, homeRank(piece.Color)}) {
+		for _, x := range []int{2, 6} {
+			to := Pos{x, pos.Y}
+			if _, _, _, err := b.castlingRook(piece, to); err == nil {
+				moves = append(moves, Move{From: pos, To: to})
+			}
+		}
+	}
+
+	// Sort the moves by their positions, since castling moves are
+	// added after all of the king's other moves.
+	sort.SliceStable(moves, func(i, j int) bool {
+		if moves[i].To.Y != moves[j].To.Y {
+			return moves[i].To.Y < moves[j].To.Y
+		}
+		return moves[i].To.X < moves[j].To.X
+	})
+
+	return moves
+}
+
+// homeRank returns the rank that color's pieces start on.
+func homeRank(color Color) int {
+	if color == Black {
+		return 7
+	}
+	return 0
+}
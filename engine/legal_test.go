@@ -0,0 +1,170 @@
+package engine
+
+import "testing"
+
+func TestLegalMovesStartPosition(t *testing.T) {
+	b := NewBoard()
+
+	moves := b.LegalMoves()
+	if len(moves) != 20 {
+		t.Errorf("expected 20 legal moves, got %d", len(moves))
+	}
+
+	// Every legal move must actually be allowed by b.Move.
+	for _, m := range moves {
+		if err := b.Move(m.From, m.To); err != nil {
+			t.Errorf("expected move %s to be legal: %s", m, err)
+			continue
+		}
+		if err := b.UndoMove(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLegalMovesCount(t *testing.T) {
+	testCases := []struct {
+		fen   string
+		moves int
+	}{
+		// Castling on both sides and en passant.
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", 48},
+		// Pinned pawns and a rank en passant discovered check.
+		{"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", 14},
+		// Promotions, including promoting by capturing.
+		{"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", 6},
+		{"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", 44},
+		// Checkmate and stalemate.
+		{"rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", 0},
+		{"7k/5K2/6Q1/8/8/8/8/8 b - - 0 1", 0},
+	}
+	for _, tc := range testCases {
+		b, err := ParseFEN(tc.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if moves := b.LegalMoves(); len(moves) != tc.moves {
+			t.Errorf("expected %d legal moves for %q, got %d: %v",
+				tc.moves, tc.fen, len(moves), moves)
+		}
+	}
+}
+
+func TestLegalMovesFrom(t *testing.T) {
+	b, err := ParseFEN("r3k2r/8/8/3pP3/8/8/1p6/R3K2R w KQkq d6 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		loc   string
+		moves []string
+	}{
+		// White can't castle queen-side since c1 is attacked by the
+		// pawn on b2.
+		{"e1", []string{"e1d1", "e1f1", "e1d2", "e1e2", "e1f2", "e1g1"}},
+		{"e5", []string{"e5d6", "e5e6"}},
+		{"a1", []string{"a1b1", "a1c1", "a1d1", "a1a2", "a1a3", "a1a4",
+			"a1a5", "a1a6", "a1a7", "a1a8"}},
+		// Black's pieces can't move since it's white's turn.
+		{"b2", nil},
+		// There's no piece at d4.
+		{"d4", nil},
+	}
+	for _, tc := range testCases {
+		pos, err := locToPos(tc.loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		moves := b.LegalMovesFrom(pos)
+		if len(moves) != len(tc.moves) {
+			t.Errorf("expected %d moves from %s, got %v",
+				len(tc.moves), tc.loc, moves)
+			continue
+		}
+		found := make(map[string]bool)
+		for _, m := range moves {
+			found[m.String()] = true
+		}
+		for _, m := range tc.moves {
+			if !found[m] {
+				t.Errorf("expected to find move %s from %s", m, tc.loc)
+			}
+		}
+	}
+
+	// Black's pawn on b2 can promote to 4 different pieces on both
+	// b1 and by taking the rook on a1.
+	b.turn ^= 1
+	if moves := b.LegalMovesFrom(Pos{1, 1}); len(moves) != 8 {
+		t.Errorf("expected 8 promotion moves from b2, got %v", moves)
+	}
+}
+
+func TestLegalMovesPinnedPiece(t *testing.T) {
+	b, err := ParseFEN("4k3/8/8/8/8/2b5/3N4/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The knight on d2 is pinned to the king by the bishop on c3.
+	if moves := b.LegalMovesFrom(Pos{3, 1}); len(moves) != 0 {
+		t.Errorf("expected pinned knight to have no moves, got %v", moves)
+	}
+	if err := b.MoveByLocation("d2", "f3"); err != ErrMovingIntoCheck {
+		t.Errorf("expected ErrMovingIntoCheck error, got %v", err)
+	}
+}
+
+func TestMoveString(t *testing.T) {
+	testCases := []struct {
+		move     Move
+		expected string
+	}{
+		{Move{From: Pos{4, 1}, To: Pos{4, 3}}, "e2e4"},
+		{Move{From: Pos{6, 7}, To: Pos{5, 5}}, "g8f6"},
+		{Move{Pos{0, 6}, Pos{0, 7}, Queen}, "a7a8q"},
+		{Move{Pos{7, 1}, Pos{6, 0}, Knight}, "h2g1n"},
+	}
+	for _, tc := range testCases {
+		if s := tc.move.String(); s != tc.expected {
+			t.Errorf("expected move string to be %s, got %s", tc.expected, s)
+		}
+	}
+}
+
+func TestParseMove(t *testing.T) {
+	testCases := []struct {
+		s        string
+		expected Move
+		err      error
+	}{
+		{"e2e4", Move{From: Pos{4, 1}, To: Pos{4, 3}}, nil},
+		{"A7A8Q", Move{Pos{0, 6}, Pos{0, 7}, Queen}, nil},
+		{"h2g1n", Move{Pos{7, 1}, Pos{6, 0}, Knight}, nil},
+		{"e2", Move{}, ErrInvalidLocation},
+		{"e2e9", Move{}, ErrInvalidLocation},
+		{"e7e8k", Move{}, ErrInvalidPromotion},
+		{"e7e8p", Move{}, ErrInvalidPromotion},
+	}
+	for _, tc := range testCases {
+		m, err := ParseMove(tc.s)
+		if err != tc.err {
+			t.Errorf("%s: expected error %v, got %v", tc.s, tc.err, err)
+		}
+		if m != tc.expected {
+			t.Errorf("%s: expected %v, got %v", tc.s, tc.expected, m)
+		}
+	}
+}
+
+func BenchmarkLegalMoves(b *testing.B) {
+	board, err := ParseFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		board.LegalMoves()
+	}
+}
@@ -3,6 +3,7 @@ package engine
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 type MoveInfo struct {
@@ -12,6 +13,10 @@ type MoveInfo struct {
 	Captured  *Piece `json:"captured"`
 	EnPassant bool   `json:"en_passant"`
 	Promotion *Piece `json:"promotion"`
+	SAN       string `json:"san"`
+
+	// hash holds the board's hash after the move.
+	hash uint64
 }
 
 func (m *MoveInfo) Encode() ([]byte, error) {
@@ -23,63 +28,21 @@ func (m *MoveInfo) Decode(mInfo []byte) error {
 }
 
 func (b *Board) makeMove(m *MoveInfo) {
-	// Remove the piece from the old position from over here, so it
-	// doesn't block when checking b.moveBlocked below if waiting
-	// to delete when adding piece to position to.
-	delete(b.posToPiece, m.From)
+	// Remove the piece from the old position.
+	b.removePiece(m.From)
 
-	// Move the piece to the new position.
-	b.posToPiece[m.To] = m.Piece
+	// Move the piece to the new position, replacing any piece
+	// that it captures.
+	b.setPiece(m.To, m.Piece)
 
 	// If the move is an en passant, delete the captured
 	// piece from the board.
 	if m.EnPassant {
-		delete(b.posToPiece, Pos{m.To.X, m.From.Y})
-	}
-
-	// Update current king's position and line of sights.
-	if m.Piece.Name == King {
-		b.kings[m.Piece.Color] = m.To
-
-		// If the king is moving into any opponent's piece's line
-		// of sight, add it to the b.kingLos slice.
-		for pos, piece := range b.posToPiece {
-			if m.Piece.Color != piece.Color^1 || (piece.Name == Pawn && pos.X == m.To.X) {
-				continue
-			}
-			positions := getMovePositions(piece, pos)
-			_, found := positions[m.To]
-			// Don't need to check if b.moveBlocked since already checked
-			// in b.moveLegal.
-			if found {
-				b.kingLos[m.Piece.Color][piecePos{piece, pos}] = struct{}{}
-			}
-		}
-	}
-
-	// Get the move positions for the piece now at position to.
-	positions := getMovePositions(m.Piece, m.To)
-
-	// Get the positions of the opponent's king.
-	kingPos := b.kings[m.Piece.Color^1]
-
-	// Check if the king's position is found within any of the
-	// move positions for piece at position to.
-	_, found := positions[kingPos]
-
-	// If the king's position was found as isn't blocked, it's a check.
-	if found {
-		// If the piece at it's new position is now causing the opponent's
-		// king to be in check, set b.check to true for the color.
-		if !b.moveBlocked(m.Piece, m.To, kingPos) {
-			b.check[m.Piece.Color^1] = true
-		}
-		b.kingLos[m.Piece.Color^1][piecePos{m.Piece, m.To}] = struct{}{}
+		b.removePiece(Pos{m.To.X, m.From.Y})
 	}
 
-	if m.Piece.Name == Pawn && m.To.Y == 7 || m.To.Y == 0 {
-		b.mustPromote[m.Piece.Color] = true
-	}
+	// Update the checks for both kings.
+	b.updateChecks()
 
 	// Increment b.hasMoved for piece.
 	b.hasMoved[m.Piece]++
@@ -98,13 +61,31 @@ func (b *Board) makeMove(m *MoveInfo) {
 	// Add the new move to b.history.
 	b.history = append(b.history, m)
 
+	// If the piece is a pawn that reached the last rank, it needs
+	// to be promoted.
+	b.updateMustPromote()
+
 	// Update who's turn it is.
 	b.turn ^= 1
+
+	// Add a check or checkmate suffix to the move's SAN.
+	m.SAN = strings.TrimRight(m.SAN, "+#") + b.sanSuffix()
+
+	// Update the board's hash and store it for finding repetitions.
+	b.updateHash()
+	m.hash = b.hash
+
+	b.emitMove(m)
 }
 
 // PromotePawn promotes the current pawn on the board that
 // needs to be promoted to the specified piece type.
 func (b *Board) PromotePawn(to PieceName) error {
+	// Make sure that a pawn actually needs promoting.
+	if mustPromote, _ := b.MustPromote(); !mustPromote {
+		return ErrNoPawnToPromote
+	}
+
 	// Get the previous move that just moved and now
 	// needs to promote.
 	move, err := b.prevMove()
@@ -124,41 +105,48 @@ func (b *Board) PromotePawn(to PieceName) error {
 	case Queen:
 		pc = &Piece{Queen, move.Piece.Color}
 	default:
-		return fmt.Errorf("can't promote pawn to %s", to)
+		return fmt.Errorf("%w: can't promote pawn to %s", ErrInvalidPromotion, to)
 	}
 
 	// Set the previous move's promotion piece to pc.
 	move.Promotion = pc
 
 	// Put the promoted piece where the pawn was located.
-	b.posToPiece[move.To] = pc
+	b.setPiece(move.To, pc)
 
 	// See if by promoting, the opponent is now in check.
-	if b.kingInCheck(b.turn) {
-		b.check[b.turn] = true
-	}
+	b.updateChecks()
 
-	// Get the possible move positions for pc at position move.To.
-	positions := getMovePositions(pc, move.To)
+	// Set must promote for color back to false.
+	b.mustPromote[move.Piece.Color] = false
 
-	// See if the opponent's king is within it's line of sight.
-	_, found := positions[b.kings[b.turn]]
+	// Add the promotion piece to the move's SAN along with a new
+	// check or checkmate suffix.
+	move.SAN = strings.TrimRight(move.SAN, "+#") + "=" +
+		string(sanPieces[to]) + b.sanSuffix()
 
-	// If it was found, add a piecePos for the new piece at position
-	// move.To to the opponent's king's line of sight.
-	if found {
-		b.kingLos[b.turn][piecePos{pc, move.To}] = struct{}{}
-	}
+	// The promoted piece changes the position after the move.
+	b.updateHash()
+	move.hash = b.hash
 
-	// Set must promote for color back to false.
-	b.mustPromote[move.Piece.Color] = false
+	if len(b.observers) > 0 {
+		b.emit(EventPromotion, move, move.Piece.Color)
+		b.emitPosition(move)
+	}
 
 	return nil
 }
 
 // MoveByLocation is a convenience method that makes a move based on
 // 2 location strings instead of Pos objects. For example, a2 to a4.
+//
+// loc2 can also end with a promotion piece's letter when a pawn is
+// being promoted. For example, e7 to e8q.
 func (b *Board) MoveByLocation(loc1, loc2 string) error {
+	var promo string
+	if len(loc2) == 3 {
+		loc2, promo = loc2[:2], strings.ToLower(loc2[2:])
+	}
 	pos1, err := locToPos(loc1)
 	if err != nil {
 		return err
@@ -167,9 +155,63 @@ func (b *Board) MoveByLocation(loc1, loc2 string) error {
 	if err != nil {
 		return err
 	}
+	if promo != "" {
+		// A pawn can't be promoted to a pawn or a king, and a move
+		// that isn't a promotion is rejected by MoveWithPromotion.
+		i := strings.Index(fenPieces, promo)
+		if i < int(Knight) || i > int(Queen) {
+			return ErrInvalidPromotion
+		}
+		return b.MoveWithPromotion(pos1, pos2, PieceName(i))
+	}
 	return b.Move(pos1, pos2)
 }
 
+// MoveWithPromotion moves a piece on a board from positions p1 to p2
+// and if the piece is a pawn reaching the last rank, promotes it to
+// the promo piece type, all as a single move.
+//
+// If the move isn't a promotion, promo must be Pawn.
+func (b *Board) MoveWithPromotion(p1, p2 Pos, promo PieceName) error {
+	piece, found := b.pieceAt(p1)
+	if !found {
+		return ErrNoPieceAtPosition
+	}
+
+	if piece.Name != Pawn || (p2.Y != 7 && p2.Y != 0) {
+		if promo != Pawn {
+			return ErrInvalidPromotion
+		}
+		return b.Move(p1, p2)
+	}
+
+	// Make sure the promotion piece is valid before making the move,
+	// so that the board is never left waiting for a promotion.
+	switch promo {
+	case Knight, Bishop, Rook, Queen:
+	default:
+		return ErrInvalidPromotion
+	}
+
+	// The move and the promotion are a single move, so the move's
+	// events are only sent once the pawn has been promoted.
+	unmute := b.muteEvents()
+	err := b.Move(p1, p2)
+	if err == nil {
+		err = b.PromotePawn(promo)
+	}
+	unmute()
+	if err != nil {
+		return err
+	}
+	move, err := b.prevMove()
+	if err != nil {
+		return err
+	}
+	b.emitMove(move)
+	return nil
+}
+
 // newMove creates a new move.
 func (b *Board) newMove(piece *Piece, from, to Pos, enPassant bool) *MoveInfo {
 	m := &MoveInfo{
@@ -179,10 +221,11 @@ func (b *Board) newMove(piece *Piece, from, to Pos, enPassant bool) *MoveInfo {
 		EnPassant: enPassant,
 	}
 	if enPassant {
-		m.Captured = b.posToPiece[Pos{to.X, from.Y}]
+		m.Captured = b.squares[Pos{to.X, from.Y}.square()]
 	} else {
-		m.Captured = b.posToPiece[to]
+		m.Captured = b.squares[to.square()]
 	}
+	m.SAN = b.sanPrefix(piece, from, to, enPassant)
 	return m
 }
 
@@ -191,8 +234,20 @@ func (b *Board) newMove(piece *Piece, from, to Pos, enPassant bool) *MoveInfo {
 // Move returns any errors that occur by trying to make
 // the move from p1 to p2.
 func (b *Board) Move(p1, p2 Pos) error {
+	// A pawn waiting to be promoted must be promoted before any
+	// other moves can be made.
+	if mustPromote, _ := b.MustPromote(); mustPromote {
+		return ErrPromotionPending
+	}
+
+	// Remember the starting position before the first move is made,
+	// so that it can be found when looking for repetitions.
+	if b.moveNum == -1 {
+		b.startHash = b.hash
+	}
+
 	// Get the piece at position p1.
-	piece, found := b.posToPiece[p1]
+	piece, found := b.pieceAt(p1)
 	if !found {
 		return ErrNoPieceAtPosition
 	}
@@ -203,15 +258,14 @@ func (b *Board) Move(p1, p2 Pos) error {
 	}
 
 	// Castling.
-	if piece.Name == King && p1.X == 4 &&
+	if piece.Name == King && p1.X == 4 && p1.Y == p2.Y &&
 		(p2.Y == 0 || p2.Y == 7) &&
 		(p2.X == 2 || p2.X == 6) {
 		return b.doCastling(piece, p1, p2)
 	}
 
 	// Make sure that p2 is a valid move position for piece.
-	positions := getMovePositions(piece, p1)
-	if _, ok := positions[p2]; !ok {
+	if !getMovePositions(piece, p1).has(p2) {
 		return ErrInvalidPieceMove
 	}
 
@@ -227,7 +281,7 @@ func (b *Board) Move(p1, p2 Pos) error {
 	// position p2, it's an en passant, otherwise make a
 	// normal move.
 	if piece.Name == Pawn && p1.X != p2.X {
-		_, found := b.posToPiece[p2]
+		_, found := b.pieceAt(p2)
 		if found {
 			b.makeMove(b.newMove(piece, p1, p2, false))
 		} else {
@@ -237,16 +291,16 @@ func (b *Board) Move(p1, p2 Pos) error {
 		b.makeMove(b.newMove(piece, p1, p2, false))
 	}
 
-	// If color's king was in check and the current move
-	// is legal, the king will no longer be in check.
-	b.check[piece.Color] = false
-
 	return nil
 }
 
 // InCheckmate returns a true or false based on whether the
 // color is currently in checkmate or not.
 func (b *Board) InCheckmate(color Color) bool {
+	// If the king isn't in check, it's not a checkmate.
+	if !b.kingInCheck(color) {
+		return false
+	}
 	// If the king can move, it's not a checkmate.
 	if b.kingCanMove(color) {
 		return false
@@ -261,6 +315,11 @@ func (b *Board) InCheckmate(color Color) bool {
 // HasStalemate checks if there's currently a stalemate on the
 // board.
 func (b *Board) HasStalemate(color Color) bool {
+	// If the king is in check, it's not a stalemate.
+	if b.kingInCheck(color) {
+		return false
+	}
+
 	// If the king can move, it's not a stalemate.
 	if b.kingCanMove(color) {
 		return false
@@ -279,18 +338,22 @@ func (b *Board) HasStalemate(color Color) bool {
 // anyPieceCanMove checks to see whether any piece for color
 // can make a legal move on the board or not.
 func (b *Board) anyPieceCanMove(color Color) bool {
-	for pos, pc := range b.posToPiece {
-		if pc.Color != color || pc.Name == King {
-			continue
+	for pieces := b.colors[color] &^ b.pieces[color][King]; pieces != 0; {
+		// If the piece can move to any position, return true.
+		if b.canMoveTo(squarePos(pieces.pop()), ^bitboard(0)) {
+			return true
 		}
-		// Get all possible positions for piece at pos.
-		positions := getMovePositions(pc, pos)
-		// Iterate over every position that pc can move to and if
-		// pc can move to any position, return true.
-		for p := range positions {
-			if b.moveLegal(pc, pos, p) == nil {
-				return true
-			}
+	}
+	return false
+}
+
+// canMoveTo reports whether the piece at position pos can legally move
+// to any of the positions in targets.
+func (b *Board) canMoveTo(pos Pos, targets bitboard) bool {
+	piece := b.squares[pos.square()]
+	for positions := getMovePositions(piece, pos) & targets; positions != 0; {
+		if b.moveLegal(piece, pos, squarePos(positions.pop())) == nil {
+			return true
 		}
 	}
 	return false
@@ -299,84 +362,35 @@ func (b *Board) anyPieceCanMove(color Color) bool {
 // canStopAllChecks looks to see if any piece for color can legally move
 // to any specific positions on the board that will stop all current checks.
 //
-// canStopAllChecks only tries positions between the pieces that have
-// color's king in their line of sights as well as the actual postions of
-// the piece.
+// canStopAllChecks only tries positions between the pieces that are
+// checking color's king and the king, as well as the actual positions
+// of the pieces.
 func (b *Board) canStopAllChecks(color Color) bool {
+	king := b.kingPos(color)
+
 	// Get all blocking positions between or on the line
 	// of sights and the king for color.
-	betweenOrOn := make(map[Pos]struct{})
+	var betweenOrOn bitboard
 
-	// Iterate over all pieces in king's line of sight.
-	for pp := range b.kingLos[color] {
-		// Add the piece's position to the betweenOrOn map.
-		betweenOrOn[pp.Pos] = struct{}{}
-
-		// Add all of the pieces between the king and the piece
-		// to the betweenOrOn map.
-		for k, v := range b.availBetween(pp.Piece, pp.Pos, b.kings[color]) {
-			betweenOrOn[k] = v
-		}
+	// A pawn that checks the king by moving two positions forward can
+	// also be captured by en passant.
+	if ep := b.enPassantTarget(); !b.positionOffBoard(ep) {
+		betweenOrOn |= posBit(ep)
 	}
 
-	// Try to move to all positions that any white piece shares with
-	// positions in the between map and if any positions prevent the king
-	// from being in check, it's no longer a checkmate.
-	//
-	// Iterate over all pieces for color.
-	for pos, pc := range b.posToPiece {
-		if pc.Color != color || pc.Name == King {
-			continue
-		}
-
-		// Get all possible positions for piece at pos.
-		positions := getMovePositions(pc, pos)
-
-		// Iterate over every position that pc can move to.
-		for p := range positions {
-			// If position p doesn't exist in map between, continue.
-			if _, ok := betweenOrOn[p]; !ok {
-				continue
-			}
-
-			// If piece can't move from pos to p, continue.
-			if b.moveLegal(pc, pos, p) != nil {
-				continue
-			}
-
-			// Simulate moving pc to p and then check if the
-			// king is still in check.
-			//
-			// Get the piece at p.
-			pc2 := b.posToPiece[p]
-			// Move pc to p.
-			b.posToPiece[p] = pc
-			// Delete pc from pos.
-			delete(b.posToPiece, pos)
-
-			// If the king's no longer in check, put
-			// the pieces back and return false.
-			if !b.kingInCheck(color) {
-				// Move piece back to pos.
-				b.posToPiece[pos] = pc
-				// Delete pc from p.
-				delete(b.posToPiece, p)
-				// If p originally contained a piece, put it back.
-				if pc2 != nil {
-					b.posToPiece[p] = pc2
-				}
-				return true
-			}
-
-			// Move piece back to pos.
-			b.posToPiece[pos] = pc
-			// Delete pc from p.
-			delete(b.posToPiece, p)
-			// If p originally contained a piece, put it back.
-			if pc2 != nil {
-				b.posToPiece[p] = pc2
-			}
+	// Add every piece that's checking the king, as well as all of the
+	// positions between the king and the piece.
+	for checkers := b.attackers(king, color^1, b.occupied()); checkers != 0; {
+		pos := squarePos(checkers.pop())
+		betweenOrOn |= posBit(pos) | b.availBetween(b.squares[pos.square()], pos, king)
+	}
 
+	// If any piece for color can legally move to any of the positions,
+	// it stops all checks, since b.moveLegal doesn't allow any moves
+	// that leave the king in check.
+	for pieces := b.colors[color] &^ b.pieces[color][King]; pieces != 0; {
+		if b.canMoveTo(squarePos(pieces.pop()), betweenOrOn) {
+			return true
 		}
 	}
 
@@ -387,28 +401,22 @@ func (b *Board) canStopAllChecks(color Color) bool {
 // to see if the king for color's position is currently
 // being attacked which would mean the king is in check.
 func (b *Board) kingInCheck(color Color) bool {
-	return b.positionAttacked(b.kings[color], color^1)
+	king := b.kingPos(color)
+	// If color's king isn't on the board, it can't be in check.
+	if b.positionOffBoard(king) {
+		return false
+	}
+	return b.positionAttacked(king, color^1)
 }
 
 // kingCanMove determines whether the king for color
 // has any positions that it can legally move to or not.
 func (b *Board) kingCanMove(color Color) bool {
-	// Get the positions of the opponent's king.
-	kingPos := b.kings[color]
-	// Get the king's piece.
-	king := b.posToPiece[kingPos]
-	// Get the king's potential move positions.
-	kingPositions := getMovePositions(king, kingPos)
-	// If there's any positions that the king can
-	// legally move to, return true since the king
-	// can move.
-	for pos := range kingPositions {
-		if b.moveLegal(king, kingPos, pos) == nil {
-			return true
-		}
+	king := b.kingPos(color)
+	if b.positionOffBoard(king) {
+		return false
 	}
-	// There was no legal moves for the king, return false.
-	return false
+	return b.canMoveTo(king, ^bitboard(0))
 }
 
 // moveLegal checks to see whether the specified move is legal to
@@ -422,7 +430,7 @@ func (b *Board) moveLegal(piece *Piece, p1, p2 Pos) error {
 	}
 
 	// Check if there's a piece at position p2.
-	piece2, found := b.posToPiece[p2]
+	piece2, found := b.pieceAt(p2)
 
 	// If there was a piece found at position p2.
 	if found {
@@ -463,33 +471,14 @@ func (b *Board) moveLegal(piece *Piece, p1, p2 Pos) error {
 	// being put into check and also make sure that the opponent's
 	// king is not within 1 X or Y position of position p2.
 	if piece.Name == King {
-		// Get the king's possible move positions from position p2.
-		kingPositions := getMovePositions(piece, p2)
-		// Iterate over all the possible positions from p2 and see if
-		// the opponent's king is in any of those positions.
-		for pos := range kingPositions {
-			if pc, found := b.posToPiece[pos]; found {
-				// The king will be too close to the other king if it
-				// moves to position p2.
-				if pc.Name == King && pc.Color != piece.Color {
-					return ErrKingTooCloseToKing
-				}
-			}
+		if kingAttacks[p2.square()]&b.pieces[piece.Color^1][King] != 0 {
+			return ErrKingTooCloseToKing
 		}
 
-		// Remove the king from it's current position on the board
-		// so that it doesn't block any pieces in position attacked.
-		delete(b.posToPiece, p1)
-
-		// Check if the position is being attacked by the opponent's color.
-		attacked := b.positionAttacked(p2, piece.Color^1)
-
-		// Put the king back at position p1.
-		b.posToPiece[p1] = piece
-
-		// If the position is being attacked, the move is illegal
-		// as the king would be moving into a check.
-		if attacked {
+		// Check if the position is being attacked by the opponent's
+		// color, without the king at p1 so that it doesn't block any
+		// of the attacking pieces.
+		if b.attackers(p2, piece.Color^1, b.occupied()&^posBit(p1)) != 0 {
 			return ErrMovingIntoCheck
 		}
 	}
@@ -497,100 +486,44 @@ func (b *Board) moveLegal(piece *Piece, p1, p2 Pos) error {
 	return nil
 }
 
-// pieceCausingCheckTo returns a true or false based on whether the piece
-// from piecePos pp causes the specified color's king to be in check.
-func (b *Board) pieceCausingCheckTo(pp piecePos, color Color) bool {
-	// Check if piece is still at pp.Pos. If it isn't, delete
-	// pp.Piece from the kings line of sight slice for color.
-	pc, found := b.posToPiece[pp.Pos]
-	if !found || pc != pp.Piece {
-		// Delete from b.kingLos[color]
-		delete(b.kingLos[color], pp)
-		return false
-	}
-
-	// If pp.Piece is not still attacking the king, delete it
-	// from the b.kingLos[piece.Color] slice.
-	positions := getMovePositions(pp.Piece, pp.Pos)
-	if _, kingFound := positions[b.kings[color]]; !kingFound {
-		// Delete from b.kingLos[piece.Color]
-		delete(b.kingLos[color], pp)
-		return false
+// updateChecks updates whether each king is in check.
+func (b *Board) updateChecks() {
+	for _, color := range []Color{White, Black} {
+		b.check[color] = b.kingInCheck(color)
 	}
-
-	if !b.moveBlocked(pp.Piece, pp.Pos, b.kings[color]) {
-		return true
-	}
-
-	return false
 }
 
 // moveIntoOrWhileCheck returns an error if moving piece causes the piece's color's
 // king to be in check, or if the king will still be in check if the move does not
 // uncheck the king through capture or blockage if the king is already in check.
 func (b *Board) moveIntoOrWhileCheck(piece *Piece, p1, p2 Pos) error {
-	if piece.Name != King {
-		for pp := range b.kingLos[piece.Color] {
-			// Check if piece is still at pp.Pos. If it isn't, delete
-			// pp.Piece from the kings line of sight slice for color.
-			pc, found := b.posToPiece[pp.Pos]
-			if !found || pc != pp.Piece {
-				// Delete from b.kingLos[piece.Color]
-				delete(b.kingLos[piece.Color], pp)
-				continue
-			}
-
-			// If piece is trying to take pp.Piece, continue.
-			if p2 == pp.Pos {
-				continue
-			}
-
-			// If pp.Piece is not still attacking the king, delete it
-			// from the b.kingLos[piece.Color] slice.
-			positions := getMovePositions(pp.Piece, pp.Pos)
-			if _, kingFound := positions[b.kings[piece.Color]]; !kingFound {
-				// Delete from b.kingLos[piece.Color]
-				delete(b.kingLos[piece.Color], pp)
-				continue
-			}
-
-			// If the piece isn't in pp.Piece's line of sight and
-			// it's not currently a check, the piece can move since
-			// it won't open up any new checks.
-			_, p1found := positions[p1]
-			if !b.check[piece.Color] && !p1found {
-				continue
-			}
+	if piece.Name == King {
+		return nil
+	}
+	king := b.kingPos(piece.Color)
+	if b.positionOffBoard(king) {
+		return nil
+	}
 
-			// Simulate moving piece to p2 and then check if pp.Piece is
-			// blocked to the king or not.
-			//
-			// Get the piece at p2.
-			pc2 := b.posToPiece[p2]
-			// Move p1's piece to p2.
-			b.posToPiece[p2] = piece
-			// Delete piece from p1.
-			delete(b.posToPiece, p1)
-			// Check if pp.Piece is blocked to the king.
-			blocked := b.moveBlocked(pp.Piece, pp.Pos, b.kings[piece.Color])
-			// Move p1's piece back to p1.
-			b.posToPiece[p1] = piece
-			// Delete p1's piece from p2.
-			delete(b.posToPiece, p2)
-			// If p2 originally contained a piece, put it back.
-			if pc2 != nil {
-				b.posToPiece[p2] = pc2
-			}
+	// If the move is an en passant, get the position of the pawn
+	// that will be captured, since removing it from the board can
+	// also open up a line of sight to the king.
+	captured := p2
+	if piece.Name == Pawn && p1.X != p2.X && b.squares[p2.square()] == nil {
+		captured = Pos{p2.X, p1.Y}
+	}
 
-			if !blocked {
-				if b.check[piece.Color] {
-					// If there would still be a check, return an
-					// ErrMoveWhileInCheck error.
-					return ErrMoveWhileInCheck
-				}
-				return ErrMovingIntoCheck
-			}
+	// Simulate the move by only changing which positions are occupied
+	// and then see if any of the opponent's pieces, other than one
+	// being captured, would be attacking the king.
+	occupied := b.occupied()&^posBit(p1)&^posBit(captured) | posBit(p2)
+	if b.attackers(king, piece.Color^1, occupied)&^posBit(captured) != 0 {
+		if b.kingInCheck(piece.Color) {
+			// If there would still be a check, return an
+			// ErrMoveWhileInCheck error.
+			return ErrMoveWhileInCheck
 		}
+		return ErrMovingIntoCheck
 	}
 	return nil
 }
@@ -608,15 +541,18 @@ func (b *Board) canEnPassant(piece *Piece, p1, p2 Pos) bool {
 			return false
 		}
 	}
-	pc, ok := b.posToPiece[Pos{p2.X, p1.Y}]
+	pc, ok := b.pieceAt(Pos{p2.X, p1.Y})
 	if ok && pc.Name == Pawn && pc.Color != piece.Color {
 		// If the previous move on the board is not piece pc
 		// moving from position Pos{p2.X, p1.Y - 2} for white
 		// or Pos{p2.X, p1.Y + 2} for black to it's current
 		// position at Pos{p2.X, p1.Y}, en passant is not allowed.
+		//
+		// If no moves have been made yet, use the en passant
+		// target that the board was set up with instead.
 		prevMove, err := b.prevMove()
 		if err != nil {
-			return false
+			return p2 == b.startEnPassant
 		}
 		d := 1
 		if piece.Color == Black {
@@ -636,63 +572,25 @@ func (b *Board) canEnPassant(piece *Piece, p1, p2 Pos) bool {
 // positionAttacked returns a true or false based on whether the
 // at position is being attacking by any pieces from color by.
 func (b *Board) positionAttacked(at Pos, by Color) bool {
-	for pos, piece := range b.posToPiece {
-		if piece.Color != by || (piece.Name == Pawn && pos.X == at.X) {
-			continue
-		}
-		positions := getMovePositions(piece, pos)
-		_, found := positions[at]
-		if found && !b.moveBlocked(piece, pos, at) {
-			return true
-		}
-	}
-	return false
+	return b.attackers(at, by, b.occupied()) != 0
 }
 
-// diagMoveBlocked checks whether there are any pieces between
-// positions p1 and p2 in a diagonal line direction.
-func (b *Board) diagMoveBlocked(p1, p2 Pos, xd, yd int) bool {
-	for x, y := p1.X+xd, p1.Y+yd; x != p2.X && y != p2.Y; x, y = x+xd, y+yd {
-		_, blocked := b.posToPiece[Pos{x, y}]
-		if blocked {
-			return true
-		}
-	}
-	return false
-}
-
-// lineMoveBlocked checks whether there are any pieces between
-// positions p1 and p2 in a straight line direction.
-func (b *Board) lineMoveBlocked(p1, p2 Pos, xd, yd int) bool {
-	switch {
-	case p1.Y != p2.Y:
-		for y := p1.Y + yd; y != p2.Y; y = y + yd {
-			_, blocked := b.posToPiece[Pos{p2.X, y}]
-			if blocked {
-				return true
-			}
-		}
-	case p1.X != p2.X:
-		for x := p1.X + xd; x != p2.X; x = x + xd {
-			_, blocked := b.posToPiece[Pos{x, p2.Y}]
-			if blocked {
-				return true
-			}
-		}
-	}
-	return false
+// attackers returns the positions of all of color by's pieces that
+// attack position at, as if only the positions in occupied had pieces
+// on them.
+func (b *Board) attackers(at Pos, by Color, occupied bitboard) bitboard {
+	sq := at.square()
+	pieces := &b.pieces[by]
+	return knightAttacks[sq]&pieces[Knight] |
+		kingAttacks[sq]&pieces[King] |
+		pawnAttacks[by^1][sq]&pieces[Pawn] |
+		bishopAttacks(sq, occupied)&(pieces[Bishop]|pieces[Queen]) |
+		rookAttacks(sq, occupied)&(pieces[Rook]|pieces[Queen])
 }
 
 // moveBlocked checks whether there are any pieces between piece at
 // at position p1 and position p2.
 func (b *Board) moveBlocked(piece *Piece, p1, p2 Pos) bool {
-	yd, xd := 1, 1
-	if p1.Y > p2.Y {
-		yd = -1
-	}
-	if p1.X > p2.X {
-		xd = -1
-	}
 	switch piece.Name {
 	case Pawn:
 		d := 1
@@ -702,18 +600,11 @@ func (b *Board) moveBlocked(piece *Piece, p1, p2 Pos) bool {
 		if p1.X != p2.X {
 			return false
 		}
-		if _, blocked := b.posToPiece[Pos{p1.X, p1.Y + d}]; blocked {
+		if _, blocked := b.pieceAt(Pos{p1.X, p1.Y + d}); blocked {
 			return true
 		}
-	case Rook:
-		return b.lineMoveBlocked(p1, p2, xd, yd)
-	case Bishop:
-		return b.diagMoveBlocked(p1, p2, xd, yd)
-	case Queen:
-		if p1.Y == p2.Y || p1.X == p2.X {
-			return b.lineMoveBlocked(p1, p2, xd, yd)
-		}
-		return b.diagMoveBlocked(p1, p2, xd, yd)
+	case Rook, Bishop, Queen:
+		return between[p1.square()][p2.square()]&b.occupied() != 0
 	}
 	return false
 }
@@ -722,78 +613,89 @@ func (b *Board) moveBlocked(piece *Piece, p1, p2 Pos) bool {
 // is legal and if it is, does the castling move, or returns an error
 // explaining why it's not, if it isn't.
 func (b *Board) doCastling(king *Piece, p1, p2 Pos) error {
+	rook, rookFrom, rookTo, err := b.castlingRook(king, p2)
+	if err != nil {
+		return err
+	}
+
+	// Add the rook to it's new position.
+	b.setPiece(rookTo, rook)
+
+	// Remove the rook from the old position.
+	b.removePiece(rookFrom)
+
+	// Move the king to it's new position.
+	//
+	// The history will be able to tell that it was a castling
+	// by which positions the king moved from and where to.
+	b.makeMove(b.newMove(king, p1, p2, false))
+
+	return nil
+}
+
+// castlingRook makes sure that castling king to position p2 is legal
+// without making any changes to the board.
+//
+// If castling is legal, castlingRook returns the rook to castle with,
+// as well as the rook's current position and the position it will be
+// moved to, otherwise it returns an error explaining why it's not.
+func (b *Board) castlingRook(king *Piece, p2 Pos) (*Piece, Pos, Pos, error) {
 	if b.check[king.Color] {
-		return ErrCastleWithKingInCheck
+		return nil, Pos{}, Pos{}, ErrCastleWithKingInCheck
 	}
 
 	if i, found := b.hasMoved[king]; found && i > 0 {
-		return ErrKingOrRookMoved
+		return nil, Pos{}, Pos{}, ErrKingOrRookMoved
 	}
 
 	switch p2.X {
 	case 2: // Queen-side.
-		// Move the queen-side rook to d1 or d8.
-		piece, found := b.posToPiece[Pos{0, p2.Y}]
-		if !found {
-			return ErrNoRookToCastleWith
+		// The queen-side rook moves to d1 or d8.
+		piece, found := b.pieceAt(Pos{0, p2.Y})
+		if !found || piece.Name != Rook || piece.Color != king.Color {
+			return nil, Pos{}, Pos{}, ErrNoRookToCastleWith
 		}
 		if i, found := b.hasMoved[piece]; found && i > 0 {
-			return ErrKingOrRookMoved
+			return nil, Pos{}, Pos{}, ErrKingOrRookMoved
 		}
 
 		// Make sure there's no pieces in between the king and the rook.
 		for x := 1; x < 4; x++ {
-			if _, found := b.posToPiece[Pos{x, p2.Y}]; found {
-				return ErrCastleWithPieceBetween
+			if _, found := b.pieceAt(Pos{x, p2.Y}); found {
+				return nil, Pos{}, Pos{}, ErrCastleWithPieceBetween
 			}
 		}
 
 		for x := 2; x < 4; x++ {
 			if b.positionAttacked(Pos{x, p2.Y}, piece.Color^1) {
-				return ErrCastleMoveThroughCheck
+				return nil, Pos{}, Pos{}, ErrCastleMoveThroughCheck
 			}
 		}
 
-		// Add the rook to it's new position.
-		b.posToPiece[Pos{3, p2.Y}] = piece
-
-		// Remove the rook from the old position.
-		delete(b.posToPiece, Pos{0, p2.Y})
+		return piece, Pos{0, p2.Y}, Pos{3, p2.Y}, nil
 	case 6: // King-side.
-		// Move the king-side rook to f1 or f8.
-		piece, found := b.posToPiece[Pos{7, p2.Y}]
-		if !found {
-			return ErrNoRookToCastleWith
+		// The king-side rook moves to f1 or f8.
+		piece, found := b.pieceAt(Pos{7, p2.Y})
+		if !found || piece.Name != Rook || piece.Color != king.Color {
+			return nil, Pos{}, Pos{}, ErrNoRookToCastleWith
 		}
 		if i, found := b.hasMoved[piece]; found && i > 0 {
-			return ErrKingOrRookMoved
+			return nil, Pos{}, Pos{}, ErrKingOrRookMoved
 		}
 
 		// Make sure there's no pieces in between the king and the rook.
 		for x := 5; x < 7; x++ {
-			if _, found := b.posToPiece[Pos{x, p2.Y}]; found {
-				return ErrCastleWithPieceBetween
+			if _, found := b.pieceAt(Pos{x, p2.Y}); found {
+				return nil, Pos{}, Pos{}, ErrCastleWithPieceBetween
 			}
 			if b.positionAttacked(Pos{x, p2.Y}, piece.Color^1) {
-				return ErrCastleMoveThroughCheck
+				return nil, Pos{}, Pos{}, ErrCastleMoveThroughCheck
 			}
 		}
 
-		// Add the rook to it's new position.
-		b.posToPiece[Pos{5, p2.Y}] = piece
-
-		// Remove the rook from the old position.
-		delete(b.posToPiece, Pos{7, p2.Y})
-	default:
-		// Shouldn't happen if called correctly.
-		return fmt.Errorf("can't castle king to position %s", p2)
+		return piece, Pos{7, p2.Y}, Pos{5, p2.Y}, nil
 	}
 
-	// Move the king to it's new position.
-	//
-	// The history will be able to tell that it was a castling
-	// by which positions the king moved from and where to.
-	b.makeMove(b.newMove(king, p1, p2, false))
-
-	return nil
+	// Shouldn't happen if called correctly.
+	return nil, Pos{}, Pos{}, fmt.Errorf("can't castle king to position %s", p2)
 }
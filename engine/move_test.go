@@ -1,6 +1,9 @@
 package engine
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func TestMoveByLocation(t *testing.T) {
 	b := NewBoard()
@@ -48,7 +51,7 @@ func TestMove(t *testing.T) {
 		if err := b.Move(tc.p1, tc.p2); err != nil {
 			t.Error(err)
 		}
-		piece, found := b.posToPiece[tc.p2]
+		piece, found := b.pieceAt(tc.p2)
 		if !found {
 			t.Errorf("expected to find piece %v at pos %v", tc.name, tc.p2)
 		}
@@ -58,6 +61,12 @@ func TestMove(t *testing.T) {
 	}
 }
 
+// piecePos contains a *Piece and it's position on the board.
+type piecePos struct {
+	*Piece
+	Pos
+}
+
 func TestMoveBlocked(t *testing.T) {
 	b := NewBoard()
 
@@ -150,10 +159,10 @@ func TestMoveBlocked(t *testing.T) {
 		b.clear() // clear the board
 
 		for _, move := range tc.setupMoves {
-			b.posToPiece[move.Pos] = move.Piece
+			b.setPiece(move.Pos, move.Piece)
 		}
 		for _, positions := range tc.blockTests {
-			piece, found := b.posToPiece[positions[0]]
+			piece, found := b.pieceAt(positions[0])
 			if !found {
 				t.Errorf("no piece found at pos %v", positions[0])
 			}
@@ -499,8 +508,8 @@ func TestCastlingQueenSide(t *testing.T) {
 	wR := &Piece{Rook, White}
 
 	// Add king and rook on the queen-side for white.
-	b.posToPiece[Pos{4, 0}] = wK
-	b.posToPiece[Pos{0, 0}] = wR
+	b.setPiece(Pos{4, 0}, wK)
+	b.setPiece(Pos{0, 0}, wR)
 
 	// Castle white by moving the king over 2 squares queen-side.
 	if err := b.MoveByLocation("e1", "c1"); err != nil {
@@ -537,8 +546,8 @@ func TestCastlingKingSide(t *testing.T) {
 	bR := &Piece{Rook, Black}
 
 	// Add king and rook on the king-side for black.
-	b.posToPiece[Pos{4, 7}] = bK
-	b.posToPiece[Pos{7, 7}] = bR
+	b.setPiece(Pos{4, 7}, bK)
+	b.setPiece(Pos{7, 7}, bR)
 
 	// Castle black by moving the king over 2 squares king-side.
 	if err := b.MoveByLocation("e8", "g8"); err != nil {
@@ -576,12 +585,12 @@ func TestCantCastleIfKingMoved(t *testing.T) {
 	bR := &Piece{Rook, Black}
 
 	// Add king and rook on the quuen-side for white.
-	b.posToPiece[Pos{4, 0}] = wK
-	b.posToPiece[Pos{0, 0}] = wR
+	b.setPiece(Pos{4, 0}, wK)
+	b.setPiece(Pos{0, 0}, wR)
 
 	// Add king and rook on the queen-side for black.
-	b.posToPiece[Pos{4, 7}] = bK
-	b.posToPiece[Pos{0, 7}] = bR
+	b.setPiece(Pos{4, 7}, bK)
+	b.setPiece(Pos{0, 7}, bR)
 
 	// Move the white king.
 	if err := b.MoveByLocation("e1", "d1"); err != nil {
@@ -630,12 +639,12 @@ func TestCantCastleIfRookMoved(t *testing.T) {
 	bR := &Piece{Rook, Black}
 
 	// Add king and rook on the quuen-side for white.
-	b.posToPiece[Pos{4, 0}] = wK
-	b.posToPiece[Pos{0, 0}] = wR
+	b.setPiece(Pos{4, 0}, wK)
+	b.setPiece(Pos{0, 0}, wR)
 
 	// Add king and rook on the queen-side for black.
-	b.posToPiece[Pos{4, 7}] = bK
-	b.posToPiece[Pos{0, 7}] = bR
+	b.setPiece(Pos{4, 7}, bK)
+	b.setPiece(Pos{0, 7}, bR)
 
 	// Move the white rook.
 	if err := b.MoveByLocation("a1", "b1"); err != nil {
@@ -676,13 +685,13 @@ func TestCantCastleWhiteIfKingInCheck(t *testing.T) {
 	b.clear()
 
 	// Add king and rook on the quuen-side for white.
-	b.posToPiece[Pos{4, 0}] = &Piece{King, White}
-	b.posToPiece[Pos{0, 0}] = &Piece{Rook, White}
+	b.setPiece(Pos{4, 0}, &Piece{King, White})
+	b.setPiece(Pos{0, 0}, &Piece{Rook, White})
 
 	b.turn ^= 1
 
 	// Put black rook on board and move it to put white king in check.
-	b.posToPiece[Pos{4, 4}] = &Piece{Rook, Black}
+	b.setPiece(Pos{4, 4}, &Piece{Rook, Black})
 	if err := b.Move(Pos{4, 4}, Pos{4, 3}); err != nil {
 		t.Error(err)
 	}
@@ -698,11 +707,11 @@ func TestCantCastleBlackIfKingInCheck(t *testing.T) {
 	b.clear()
 
 	// Add king and rook on the queen-side for black.
-	b.posToPiece[Pos{4, 7}] = &Piece{King, Black}
-	b.posToPiece[Pos{0, 7}] = &Piece{Rook, Black}
+	b.setPiece(Pos{4, 7}, &Piece{King, Black})
+	b.setPiece(Pos{0, 7}, &Piece{Rook, Black})
 
 	// Put white rook on board and move it to put black king in check.
-	b.posToPiece[Pos{4, 4}] = &Piece{Rook, White}
+	b.setPiece(Pos{4, 4}, &Piece{Rook, White})
 	if err := b.Move(Pos{4, 4}, Pos{4, 3}); err != nil {
 		t.Error(err)
 	}
@@ -718,13 +727,13 @@ func TestCantCastleIfPieceBetweenKingAndRook(t *testing.T) {
 	b.clear()
 
 	// Add king and rook on the queen-side for white.
-	b.posToPiece[Pos{4, 0}] = &Piece{King, White}
-	b.posToPiece[Pos{0, 0}] = &Piece{Rook, White}
-	b.posToPiece[Pos{7, 0}] = &Piece{Rook, White}
+	b.setPiece(Pos{4, 0}, &Piece{King, White})
+	b.setPiece(Pos{0, 0}, &Piece{Rook, White})
+	b.setPiece(Pos{7, 0}, &Piece{Rook, White})
 
 	// Put a bishop between the king and the rook on both sides.
-	b.posToPiece[Pos{2, 0}] = &Piece{Bishop, White}
-	b.posToPiece[Pos{6, 0}] = &Piece{Bishop, White}
+	b.setPiece(Pos{2, 0}, &Piece{Bishop, White})
+	b.setPiece(Pos{6, 0}, &Piece{Bishop, White})
 
 	// Try to castle white queen-side.
 	if err := b.MoveByLocation("e1", "c1"); err != ErrCastleWithPieceBetween {
@@ -739,13 +748,13 @@ func TestCantCastleIfPieceBetweenKingAndRook(t *testing.T) {
 	b.turn ^= 1
 
 	// Add king and rook on the queen-side for black.
-	b.posToPiece[Pos{4, 7}] = &Piece{King, Black}
-	b.posToPiece[Pos{0, 7}] = &Piece{Rook, Black}
-	b.posToPiece[Pos{7, 7}] = &Piece{Rook, Black}
+	b.setPiece(Pos{4, 7}, &Piece{King, Black})
+	b.setPiece(Pos{0, 7}, &Piece{Rook, Black})
+	b.setPiece(Pos{7, 7}, &Piece{Rook, Black})
 
 	// Put a bishop between the king and the rook on both sides.
-	b.posToPiece[Pos{2, 7}] = &Piece{Bishop, Black}
-	b.posToPiece[Pos{6, 7}] = &Piece{Bishop, Black}
+	b.setPiece(Pos{2, 7}, &Piece{Bishop, Black})
+	b.setPiece(Pos{6, 7}, &Piece{Bishop, Black})
 
 	// Try to castle black queen-side.
 	if err := b.MoveByLocation("e8", "c8"); err != ErrCastleWithPieceBetween {
@@ -763,12 +772,12 @@ func TestCantCastleIfKingMovesThroughCheck(t *testing.T) {
 	b.clear()
 
 	// Add king and rook on the queen-side for white.
-	b.posToPiece[Pos{4, 0}] = &Piece{King, White}
-	b.posToPiece[Pos{0, 0}] = &Piece{Rook, White}
-	b.posToPiece[Pos{7, 0}] = &Piece{Rook, White}
+	b.setPiece(Pos{4, 0}, &Piece{King, White})
+	b.setPiece(Pos{0, 0}, &Piece{Rook, White})
+	b.setPiece(Pos{7, 0}, &Piece{Rook, White})
 
 	// Put a bishop that will make both sides castle through check.
-	b.posToPiece[Pos{4, 2}] = &Piece{Bishop, Black}
+	b.setPiece(Pos{4, 2}, &Piece{Bishop, Black})
 
 	// Try to castle white queen-side.
 	if err := b.MoveByLocation("e1", "c1"); err != ErrCastleMoveThroughCheck {
@@ -783,12 +792,12 @@ func TestCantCastleIfKingMovesThroughCheck(t *testing.T) {
 	b.turn ^= 1
 
 	// Add king and rook on the queen-side for black.
-	b.posToPiece[Pos{4, 7}] = &Piece{King, Black}
-	b.posToPiece[Pos{0, 7}] = &Piece{Rook, Black}
-	b.posToPiece[Pos{7, 7}] = &Piece{Rook, Black}
+	b.setPiece(Pos{4, 7}, &Piece{King, Black})
+	b.setPiece(Pos{0, 7}, &Piece{Rook, Black})
+	b.setPiece(Pos{7, 7}, &Piece{Rook, Black})
 
 	// Put a bishop that will make both sides castle through check.
-	b.posToPiece[Pos{4, 5}] = &Piece{Bishop, White}
+	b.setPiece(Pos{4, 5}, &Piece{Bishop, White})
 
 	// Try to castle black queen-side.
 	if err := b.MoveByLocation("e8", "c8"); err != ErrCastleMoveThroughCheck {
@@ -916,10 +925,8 @@ func TestCantMoveKingWithin1CoordOfKing(t *testing.T) {
 	b.clear()
 
 	// Add both kings to the board.
-	b.posToPiece[Pos{5, 6}] = &Piece{King, White}
-	b.posToPiece[Pos{7, 7}] = &Piece{King, Black}
-	b.kings[White] = Pos{5, 6}
-	b.kings[Black] = Pos{7, 7}
+	b.setPiece(Pos{5, 6}, &Piece{King, White})
+	b.setPiece(Pos{7, 7}, &Piece{King, Black})
 
 	b.turn ^= 1
 
@@ -932,12 +939,9 @@ func TestHasStalemate(t *testing.T) {
 	b := NewBoard()
 	b.clear()
 
-	b.posToPiece[Pos{5, 6}] = &Piece{King, White}
-	b.posToPiece[Pos{6, 5}] = &Piece{Queen, White}
-	b.posToPiece[Pos{7, 7}] = &Piece{King, Black}
-
-	b.kings[White] = Pos{5, 6}
-	b.kings[Black] = Pos{7, 7}
+	b.setPiece(Pos{5, 6}, &Piece{King, White})
+	b.setPiece(Pos{6, 5}, &Piece{Queen, White})
+	b.setPiece(Pos{7, 7}, &Piece{King, Black})
 
 	b.turn ^= 1
 
@@ -950,13 +954,10 @@ func TestNoStalemateIfCanMove(t *testing.T) {
 	b := NewBoard()
 	b.clear()
 
-	b.posToPiece[Pos{5, 6}] = &Piece{King, White}
-	b.posToPiece[Pos{6, 5}] = &Piece{Queen, White}
-	b.posToPiece[Pos{7, 7}] = &Piece{King, Black}
-	b.posToPiece[Pos{7, 3}] = &Piece{Rook, Black}
-
-	b.kings[White] = Pos{5, 6}
-	b.kings[Black] = Pos{7, 7}
+	b.setPiece(Pos{5, 6}, &Piece{King, White})
+	b.setPiece(Pos{6, 5}, &Piece{Queen, White})
+	b.setPiece(Pos{7, 7}, &Piece{King, Black})
+	b.setPiece(Pos{7, 3}, &Piece{Rook, Black})
 
 	b.turn ^= 1
 
@@ -964,3 +965,162 @@ func TestNoStalemateIfCanMove(t *testing.T) {
 		t.Error("expected there not to be a stalemate")
 	}
 }
+
+func TestMoveWithPromotion(t *testing.T) {
+	b, err := ParseFEN("1n2k3/P7/8/8/8/8/8/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An invalid promotion piece leaves the board untouched.
+	for _, name := range []PieceName{Pawn, King} {
+		if err := b.MoveWithPromotion(Pos{0, 6}, Pos{1, 7}, name); err != ErrInvalidPromotion {
+			t.Errorf("expected ErrInvalidPromotion error, got %v", err)
+		}
+	}
+	if b.turn != White || len(b.history) != 0 {
+		t.Error("expected no move to be made")
+	}
+
+	// A piece that isn't promoting can't be given a promotion piece.
+	if err := b.MoveWithPromotion(Pos{4, 0}, Pos{4, 1}, Queen); err != ErrInvalidPromotion {
+		t.Errorf("expected ErrInvalidPromotion error, got %v", err)
+	}
+
+	if err := b.MoveWithPromotion(Pos{0, 6}, Pos{1, 7}, Knight); err != nil {
+		t.Fatal(err)
+	}
+	if mustPromote, _ := b.MustPromote(); mustPromote {
+		t.Error("expected no pawn to need promoting")
+	}
+	if b.turn != Black {
+		t.Error("expected turn to be black")
+	}
+	piece, err := b.GetPieceAt("b8")
+	if err != nil || piece.Name != Knight || piece.Color != White {
+		t.Error("expected there to be a white knight at location b8")
+	}
+	if len(b.history) != 1 {
+		t.Errorf("expected 1 move in history, got %d", len(b.history))
+	}
+	if m, _ := b.prevMove(); m.SAN != "axb8=N" {
+		t.Errorf("expected SAN to be axb8=N, got %s", m.SAN)
+	}
+
+	// Moves that aren't promotions work like Move.
+	if err := b.MoveWithPromotion(Pos{4, 7}, Pos{4, 6}, Pawn); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMoveByLocationPromotion(t *testing.T) {
+	b, err := ParseFEN("4k3/P7/8/8/8/8/8/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Pawns can't be promoted to pawns or kings, and moves that aren't
+	// promotions can't have a promotion piece.
+	for _, tc := range []struct{ loc1, loc2 string }{
+		{"a7", "a8x"},
+		{"a7", "a8p"},
+		{"a7", "a8k"},
+		{"e1", "e2q"},
+		{"e1", "e2p"},
+		{"e1", "e2k"},
+	} {
+		if err := b.MoveByLocation(tc.loc1, tc.loc2); err != ErrInvalidPromotion {
+			t.Errorf("%s%s: expected ErrInvalidPromotion error, got %v", tc.loc1, tc.loc2, err)
+		}
+	}
+	if fen := b.FEN(); fen != "4k3/P7/8/8/8/8/8/4K3 w - - 0 1" {
+		t.Errorf("expected the board not to change, got %s", fen)
+	}
+	if err := b.MoveByLocation("a7", "a8Q"); err != nil {
+		t.Fatal(err)
+	}
+	piece, err := b.GetPieceAt("a8")
+	if err != nil || piece.Name != Queen {
+		t.Error("expected there to be a queen at location a8")
+	}
+	if hasCheck, color := b.HasCheck(); !hasCheck || color != Black {
+		t.Error("expected black to be in check")
+	}
+}
+
+func TestCantMoveWhilePromotionPending(t *testing.T) {
+	b, err := ParseFEN("4k3/P7/8/8/8/8/8/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.MoveByLocation("a7", "a8"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.MoveByLocation("e8", "d7"); err != ErrPromotionPending {
+		t.Errorf("expected ErrPromotionPending error, got %v", err)
+	}
+	if moves := b.LegalMoves(); len(moves) != 0 {
+		t.Errorf("expected no legal moves, got %v", moves)
+	}
+	for _, to := range []PieceName{Pawn, King} {
+		if err := b.PromotePawn(to); !errors.Is(err, ErrInvalidPromotion) {
+			t.Errorf("expected ErrInvalidPromotion error promoting to %s, got %v", to, err)
+		}
+	}
+	if err := b.PromotePawn(Rook); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.MoveByLocation("e8", "d7"); err != nil {
+		t.Error(err)
+	}
+}
+
+func BenchmarkPositionAttacked(b *testing.B) {
+	board, err := ParseFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		board.positionAttacked(Pos{4, 3}, Black)
+	}
+}
+
+func BenchmarkInCheckmate(b *testing.B) {
+	// White is in check, but isn't in checkmate.
+	board, err := ParseFEN("rnbqk1nr/pppp1ppp/8/4p3/1b1P4/5N2/PPP1PPPP/RNBQKB1R w KQkq - 2 3")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		board.InCheckmate(White)
+	}
+}
+
+func BenchmarkHasStalemate(b *testing.B) {
+	board, err := ParseFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		board.HasStalemate(White)
+	}
+}
+
+func BenchmarkMoveAndUndo(b *testing.B) {
+	board, err := ParseFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := board.Move(Pos{4, 4}, Pos{5, 6}); err != nil {
+			b.Fatal(err)
+		}
+		if err := board.UndoMove(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package engine
+
+import "fmt"
+
+// Perft walks the tree of legal moves from the board's current position
+// to the given depth and returns the number of leaf positions found.
+//
+// Comparing the result against published node counts is used to check
+// that move generation is correct. The board is left as it was found,
+// including any moves that could still be redone, and no events are sent
+// for the moves that are walked.
+func Perft(b *Board, depth int) uint64 {
+	defer b.restoreHistory()()
+	defer b.muteEvents()()
+	return b.perft(depth)
+}
+
+// PerftDivide is like Perft, but returns the number of leaf positions
+// found after each legal move at the root, which makes it easier to
+// find which move a wrong count comes from.
+func PerftDivide(b *Board, depth int) map[Move]uint64 {
+	defer b.restoreHistory()()
+	defer b.muteEvents()()
+	nodes := make(map[Move]uint64)
+	if depth < 1 {
+		return nodes
+	}
+	for _, m := range b.LegalMoves() {
+		b.perftMove(m)
+		nodes[m] = b.perft(depth - 1)
+		b.perftUndo(m)
+	}
+	return nodes
+}
+
+func (b *Board) perft(depth int) uint64 {
+	if depth < 1 {
+		return 1
+	}
+	moves := b.LegalMoves()
+	if depth == 1 {
+		return uint64(len(moves))
+	}
+	var nodes uint64
+	for _, m := range moves {
+		b.perftMove(m)
+		nodes += b.perft(depth - 1)
+		b.perftUndo(m)
+	}
+	return nodes
+}
+
+// perftMove makes move m, which must be a legal move, on the board.
+func (b *Board) perftMove(m Move) {
+	if err := b.MoveWithPromotion(m.From, m.To, m.Promotion); err != nil {
+		panic(fmt.Sprintf("perft: legal move %s failed: %v", m, err))
+	}
+}
+
+// perftUndo undoes move m after it was made by perftMove.
+func (b *Board) perftUndo(m Move) {
+	if err := b.UndoMove(); err != nil {
+		panic(fmt.Sprintf("perft: undo of move %s failed: %v", m, err))
+	}
+}
+
+// restoreHistory saves a copy of the board's history and returns a
+// function that puts it back, since making new moves deletes any moves
+// after the current move that could have been redone.
+func (b *Board) restoreHistory() func() {
+	history := append([]*MoveInfo(nil), b.history...)
+	return func() {
+		b.history = history
+	}
+}
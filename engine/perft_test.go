@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+)
+
+// perftPositions holds the standard perft test positions along with their
+// published node counts, starting at depth 1.
+var perftPositions = []struct {
+	name  string
+	fen   string
+	nodes []uint64
+}{
+	{
+		"initial position",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		[]uint64{20, 400, 8902, 197281},
+	},
+	{
+		"kiwipete",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		[]uint64{48, 2039, 97862},
+	},
+	{
+		"position 3",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		[]uint64{14, 191, 2812, 43238, 674624},
+	},
+	{
+		"position 4",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		[]uint64{6, 264, 9467, 422333},
+	},
+	{
+		"position 4 mirrored",
+		"r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R b KQ - 0 1",
+		[]uint64{6, 264, 9467, 422333},
+	},
+	{
+		"position 5",
+		"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+		[]uint64{44, 1486, 62379},
+	},
+	{
+		"position 6",
+		"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+		[]uint64{46, 2079, 89890},
+	},
+}
+
+func TestPerft(t *testing.T) {
+	for _, tc := range perftPositions {
+		b, err := ParseFEN(tc.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		for i, expected := range tc.nodes {
+			depth := i + 1
+
+			// The deepest counts take the longest, so skip them
+			// when running the short tests.
+			if testing.Short() && expected > 100000 {
+				break
+			}
+
+			if nodes := Perft(b, depth); nodes != expected {
+				t.Errorf("%s: depth %d: expected %d nodes, got %d",
+					tc.name, depth, expected, nodes)
+			}
+		}
+
+		// Make sure perft left the board as it was.
+		if fen := b.FEN(); fen != tc.fen {
+			t.Errorf("%s: expected board to be left at %q, got %q",
+				tc.name, tc.fen, fen)
+		}
+	}
+}
+
+func TestPerftDivide(t *testing.T) {
+	b, err := ParseFEN(perftPositions[1].fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	divide := PerftDivide(b, 2)
+	if len(divide) != len(b.LegalMoves()) {
+		t.Errorf("expected %d moves, got %d", len(b.LegalMoves()), len(divide))
+	}
+
+	var total uint64
+	for _, nodes := range divide {
+		total += nodes
+	}
+	if total != 2039 {
+		t.Errorf("expected divide to total 2039 nodes, got %d", total)
+	}
+
+	// Castling king side leaves black with 43 moves.
+	castle := Move{From: Pos{4, 0}, To: Pos{6, 0}}
+	if divide[castle] != 43 {
+		t.Errorf("expected %s to have 43 nodes, got %d", castle, divide[castle])
+	}
+}
+
+func TestPerftKeepsRedoHistory(t *testing.T) {
+	b := NewBoard()
+	for _, move := range []string{"e4", "e5", "Nf3"} {
+		if err := b.MoveSAN(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.UndoMove(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The count should match the same position without any history.
+	fresh, err := ParseFEN(b.FEN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nodes, expected := Perft(b, 2), Perft(fresh, 2); nodes != expected {
+		t.Errorf("expected %d nodes, got %d", expected, nodes)
+	}
+
+	// The undone move should still be able to be redone.
+	if err := b.RedoMove(); err != nil {
+		t.Fatal(err)
+	}
+	if history := b.HistorySAN(); history != "1. e4 e5 2. Nf3" {
+		t.Errorf("expected history to be 1. e4 e5 2. Nf3, got %q", history)
+	}
+}
+
+func BenchmarkPerft(b *testing.B) {
+	board, err := ParseFEN(perftPositions[1].fen)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < b.N; i++ {
+		Perft(board, 2)
+	}
+}
+
+func ExamplePerft() {
+	fmt.Println(Perft(NewBoard(), 3))
+	// Output: 8902
+}
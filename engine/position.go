@@ -6,7 +6,12 @@ type Pos struct {
 
 // String returns a Pos as a location string. Example: Pos{0, 0} -> A1.
 func (p Pos) String() string {
-	return string(p.X+'A') + string(p.Y+'1')
+	return string(rune(p.X+'A')) + string(rune(p.Y+'1'))
+}
+
+// loc returns a Pos as a lower case location string. Example: Pos{0, 0} -> a1.
+func (p Pos) loc() string {
+	return string(rune(p.X+'a')) + string(rune(p.Y+'1'))
 }
 
 // locToPos turns a location string into a Pos object.
@@ -24,177 +29,62 @@ func locToPos(loc string) (Pos, error) {
 	return Pos{-1, -1}, ErrInvalidLocation
 }
 
-// diagPositions returns a map of diagonal move positions starting
+// diagPositions returns a bitboard of diagonal move positions starting
 // from the specified current position.
-func diagPositions(cur Pos) map[Pos]struct{} {
-	pos := make(map[Pos]struct{})
-	for x, y := cur.X+1, cur.Y+1; x < 8 && y < 8; x, y = x+1, y+1 {
-		pos[Pos{x, y}] = struct{}{}
-	}
-	for x, y := cur.X-1, cur.Y+1; x >= 0 && y < 8; x, y = x-1, y+1 {
-		pos[Pos{x, y}] = struct{}{}
-	}
-	for x, y := cur.X+1, cur.Y-1; x < 8 && y >= 0; x, y = x+1, y-1 {
-		pos[Pos{x, y}] = struct{}{}
-	}
-	for x, y := cur.X-1, cur.Y-1; x >= 0 && y >= 0; x, y = x-1, y-1 {
-		pos[Pos{x, y}] = struct{}{}
-	}
-	return pos
+func diagPositions(cur Pos) bitboard {
+	return bishopAttacks(cur.square(), 0)
 }
 
-// linePositions returns a map of straight line move positions starting
-// from the specified current position.
-func linePositions(cur Pos) map[Pos]struct{} {
-	pos := make(map[Pos]struct{})
-	for x := cur.X + 1; x < 8; x++ {
-		pos[Pos{x, cur.Y}] = struct{}{}
-	}
-	for x := cur.X - 1; x >= 0; x-- {
-		pos[Pos{x, cur.Y}] = struct{}{}
-	}
-	for y := cur.Y + 1; y < 8; y++ {
-		pos[Pos{cur.X, y}] = struct{}{}
-	}
-	for y := cur.Y - 1; y >= 0; y-- {
-		pos[Pos{cur.X, y}] = struct{}{}
-	}
-	return pos
+// linePositions returns a bitboard of straight line move positions
+// starting from the specified current position.
+func linePositions(cur Pos) bitboard {
+	return rookAttacks(cur.square(), 0)
 }
 
-// getMovePositions returns a map of all possible positions that the
+// getMovePositions returns a bitboard of all possible positions that the
 // specified piece could move to with no restrictions in place.
-func getMovePositions(piece *Piece, cur Pos) map[Pos]struct{} {
-	pos := make(map[Pos]struct{})
-
+func getMovePositions(piece *Piece, cur Pos) bitboard {
 	switch piece.Name {
 	case Pawn:
-		switch piece.Color {
-		case Black:
-			if cur.Y == 6 {
-				pos[Pos{cur.X, cur.Y - 2}] = struct{}{}
-			}
-			if cur.Y != 0 {
-				pos[Pos{cur.X, cur.Y - 1}] = struct{}{}
-				if cur.X != 0 {
-					pos[Pos{cur.X - 1, cur.Y - 1}] = struct{}{}
-				}
-				if cur.X != 7 {
-					pos[Pos{cur.X + 1, cur.Y - 1}] = struct{}{}
-				}
-			}
-		case White:
-			if cur.Y == 1 {
-				pos[Pos{cur.X, cur.Y + 2}] = struct{}{}
-			}
-			if cur.Y != 7 {
-				pos[Pos{cur.X, cur.Y + 1}] = struct{}{}
-				if cur.X != 0 {
-					pos[Pos{cur.X - 1, cur.Y + 1}] = struct{}{}
-				}
-				if cur.X != 7 {
-					pos[Pos{cur.X + 1, cur.Y + 1}] = struct{}{}
-				}
-			}
+		// Pawns can capture diagonally, move forward one position, or
+		// two positions from their starting rank.
+		d, startY := 1, 1
+		if piece.Color == Black {
+			d, startY = -1, 6
+		}
+		pos := pawnAttacks[piece.Color][cur.square()]
+		if next := (Pos{cur.X, cur.Y + d}); !offBoard(next) {
+			pos |= posBit(next)
 		}
+		if cur.Y == startY {
+			pos |= posBit(Pos{cur.X, cur.Y + 2*d})
+		}
+		return pos
 	case Knight:
-		pos[Pos{cur.X + 2, cur.Y + 1}] = struct{}{}
-		pos[Pos{cur.X - 2, cur.Y + 1}] = struct{}{}
-		pos[Pos{cur.X + 2, cur.Y - 1}] = struct{}{}
-		pos[Pos{cur.X - 2, cur.Y - 1}] = struct{}{}
-		pos[Pos{cur.X + 1, cur.Y + 2}] = struct{}{}
-		pos[Pos{cur.X - 1, cur.Y + 2}] = struct{}{}
-		pos[Pos{cur.X + 1, cur.Y - 2}] = struct{}{}
-		pos[Pos{cur.X - 1, cur.Y - 2}] = struct{}{}
+		return knightAttacks[cur.square()]
 	case Bishop:
-		pos = diagPositions(cur)
+		return diagPositions(cur)
 	case Rook:
-		pos = linePositions(cur)
+		return linePositions(cur)
 	case Queen:
-		pos = diagPositions(cur)
-		for k, v := range linePositions(cur) {
-			pos[k] = v
-		}
+		return diagPositions(cur) | linePositions(cur)
 	case King:
-		pos[Pos{cur.X + 1, cur.Y}] = struct{}{}
-		pos[Pos{cur.X - 1, cur.Y}] = struct{}{}
-		pos[Pos{cur.X, cur.Y + 1}] = struct{}{}
-		pos[Pos{cur.X, cur.Y - 1}] = struct{}{}
-		pos[Pos{cur.X + 1, cur.Y + 1}] = struct{}{}
-		pos[Pos{cur.X + 1, cur.Y - 1}] = struct{}{}
-		pos[Pos{cur.X - 1, cur.Y + 1}] = struct{}{}
-		pos[Pos{cur.X - 1, cur.Y - 1}] = struct{}{}
+		return kingAttacks[cur.square()]
 	}
-	return pos
+	return 0
 }
 
-func (b *Board) availBetween(piece *Piece, p1, p2 Pos) map[Pos]struct{} {
-	yd, xd := 1, 1
-	if p1.Y > p2.Y {
-		yd = -1
-	}
-	if p1.X > p2.X {
-		xd = -1
-	}
+// availBetween returns the empty positions between piece at position p1
+// and position p2, when piece can move along a line or diagonal from
+// p1 to p2.
+func (b *Board) availBetween(piece *Piece, p1, p2 Pos) bitboard {
 	switch piece.Name {
-	case Pawn:
-		d := 1
-		if piece.Color == Black {
-			d = -1
-		}
-		if p1.Y == p2.Y+(2*d) {
-			if _, blocked := b.posToPiece[Pos{p1.X, p1.Y + d}]; blocked {
-				return map[Pos]struct{}{Pos{p1.X, p1.Y + d}: struct{}{}}
-			}
-		}
-	case Rook:
-		return b.lineBetween(p1, p2, xd, yd)
-	case Bishop:
-		return b.diagBetween(p1, p2, xd, yd)
-	case Queen:
-		if p1.Y == p2.Y || p1.X == p2.X {
-			return b.lineBetween(p1, p2, xd, yd)
-		}
-		return b.diagBetween(p1, p2, xd, yd)
-	}
-	return nil
-}
-
-func (b *Board) lineBetween(p1, p2 Pos, xd, yd int) map[Pos]struct{} {
-	m := map[Pos]struct{}{}
-	switch {
-	case p1.Y != p2.Y:
-		for y := p1.Y + yd; y != p2.Y; y = y + yd {
-			_, blocked := b.posToPiece[Pos{p2.X, y}]
-			if !blocked {
-				m[Pos{p2.X, y}] = struct{}{}
-			}
-		}
-	case p1.X != p2.X:
-		for x := p1.X + xd; x != p2.X; x = x + xd {
-			_, blocked := b.posToPiece[Pos{x, p2.Y}]
-			if !blocked {
-				m[Pos{x, p2.Y}] = struct{}{}
-			}
-		}
+	case Rook, Bishop, Queen:
+		return between[p1.square()][p2.square()] &^ b.occupied()
 	}
-	return m
-}
-
-func (b *Board) diagBetween(p1, p2 Pos, xd, yd int) map[Pos]struct{} {
-	m := map[Pos]struct{}{}
-	for x, y := p1.X+xd, p1.Y+yd; x != p2.X && y != p2.Y; x, y = x+xd, y+yd {
-		_, blocked := b.posToPiece[Pos{x, y}]
-		if !blocked {
-			m[Pos{x, y}] = struct{}{}
-		}
-	}
-	return m
+	return 0
 }
 
 func (b *Board) positionOffBoard(pos Pos) bool {
-	if pos.X < 0 || pos.X > 7 || pos.Y < 0 || pos.Y > 7 {
-		return true
-	}
-	return false
+	return offBoard(pos)
 }
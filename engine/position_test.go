@@ -64,12 +64,12 @@ func TestDiagPositions(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		positions := diagPositions(tc.cur)
-		if len(positions) != len(tc.expected) {
-			t.Errorf("expected len(positions) to be %d, got %d",
-				len(tc.expected), len(positions))
+		if positions.count() != len(tc.expected) {
+			t.Errorf("expected %d positions, got %d",
+				len(tc.expected), positions.count())
 		}
 		for _, pos := range tc.expected {
-			if _, found := positions[pos]; !found {
+			if !positions.has(pos) {
 				t.Errorf("expected to find pos %v", pos)
 			}
 		}
@@ -114,12 +114,12 @@ func TestLinePositions(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		positions := linePositions(tc.cur)
-		if len(positions) != len(tc.expected) {
-			t.Errorf("expected len(positions) to be %d, got %d",
-				len(tc.expected), len(positions))
+		if positions.count() != len(tc.expected) {
+			t.Errorf("expected %d positions, got %d",
+				len(tc.expected), positions.count())
 		}
 		for _, pos := range tc.expected {
-			if _, found := positions[pos]; !found {
+			if !positions.has(pos) {
 				t.Errorf("expected to find pos %v", pos)
 			}
 		}
@@ -225,12 +225,12 @@ func TestGetMovePositions(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		positions := getMovePositions(tc.piece, tc.cur)
-		if len(positions) != len(tc.expected) {
-			t.Errorf("expected len(positions) to be %d, got %d",
-				len(tc.expected), len(positions))
+		if positions.count() != len(tc.expected) {
+			t.Errorf("expected %d positions, got %d",
+				len(tc.expected), positions.count())
 		}
 		for _, pos := range tc.expected {
-			if _, found := positions[pos]; !found {
+			if !positions.has(pos) {
 				t.Errorf("expected to find pos %v", pos)
 			}
 		}
@@ -256,12 +256,12 @@ func TestPieceContainsPosition(t *testing.T) {
 		{Pos{1, 7}, Pos{2, 5}, true},
 	}
 	for _, tc := range testCases {
-		piece, found := b.posToPiece[tc.p1]
+		piece, found := b.pieceAt(tc.p1)
 		if !found {
 			t.Errorf("no piece found at pos %v", tc.p1)
 		}
 		positions := getMovePositions(piece, tc.p1)
-		_, contains := positions[tc.p2]
+		contains := positions.has(tc.p2)
 		if tc.contains && !contains {
 			t.Errorf("expected pos %s to be available for %s at pos %s",
 				tc.p2, piece, tc.p1)
@@ -283,11 +283,11 @@ func TestAvailBetween(t *testing.T) {
 	qPos := Pos{7, 3}
 	kPos := Pos{4, 0}
 
-	b.posToPiece[qPos] = bQ
-	b.posToPiece[kPos] = wK
+	b.setPiece(qPos, bQ)
+	b.setPiece(kPos, wK)
 
 	between := b.availBetween(bQ, qPos, kPos)
-	if len(between) > 2 {
+	if between.count() > 2 {
 		t.Error("expected between to only have 2 positions")
 	}
 }
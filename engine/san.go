@@ -0,0 +1,217 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// sanPieces holds the SAN letters for each piece name, indexed by
+// their PieceName values. Pawns don't have a letter in SAN.
+const sanPieces = " NBRQK"
+
+// sanRegexp matches a non castling SAN move, capturing the piece
+// letter, the file and rank of the position moved from, whether
+// it's a capture, the position moved to and the promotion piece.
+var sanRegexp = regexp.MustCompile(
+	`^([NBRQK])?([a-h])?([1-8])?(x)?([a-h][1-8])(?:=?([NBRQ]))?$`)
+
+// sanPrefix returns the SAN for piece moving from p1 to p2 without
+// any promotion or check suffix.
+//
+// sanPrefix must be called before the move is made on the board.
+func (b *Board) sanPrefix(piece *Piece, p1, p2 Pos, enPassant bool) string {
+	// Castling.
+	if piece.Name == King && p1.X == 4 && p2.X == 2 {
+		return "O-O-O"
+	}
+	if piece.Name == King && p1.X == 4 && p2.X == 6 {
+		return "O-O"
+	}
+
+	_, capture := b.pieceAt(p2)
+	capture = capture || enPassant
+
+	if piece.Name == Pawn {
+		if capture {
+			return string(rune(p1.X+'a')) + "x" + p2.loc()
+		}
+		return p2.loc()
+	}
+
+	san := string(sanPieces[piece.Name])
+
+	// If another piece of the same type can also legally move to p2,
+	// add the file, rank or both of p1 to tell the pieces apart.
+	ambiguous, sameFile, sameRank := false, false, false
+	for others := b.pieces[piece.Color][piece.Name] &^ posBit(p1); others != 0; {
+		pos := squarePos(others.pop())
+		pc := b.squares[pos.square()]
+		if !getMovePositions(pc, pos).has(p2) {
+			continue
+		}
+		if b.moveLegal(pc, pos, p2) != nil {
+			continue
+		}
+		ambiguous = true
+		sameFile = sameFile || pos.X == p1.X
+		sameRank = sameRank || pos.Y == p1.Y
+	}
+	if ambiguous {
+		switch {
+		case !sameFile:
+			san += string(rune(p1.X + 'a'))
+		case !sameRank:
+			san += string(rune(p1.Y + '1'))
+		default:
+			san += p1.loc()
+		}
+	}
+
+	if capture {
+		san += "x"
+	}
+	return san + p2.loc()
+}
+
+// sanSuffix returns a SAN check or checkmate suffix for the side
+// to move.
+func (b *Board) sanSuffix() string {
+	if !b.check[b.turn] {
+		return ""
+	}
+	if b.InCheckmate(b.turn) {
+		return "#"
+	}
+	return "+"
+}
+
+// MoveSAN makes a move on the board from a move string in Standard
+// Algebraic Notation. For example, Nf3, exd5, O-O-O or e8=Q+.
+//
+// Any check, checkmate or annotation symbols at the end of the move
+// string are ignored.
+func (b *Board) MoveSAN(san string) error {
+	s := strings.TrimRight(strings.TrimSpace(san), "+#!?")
+
+	// Castling.
+	switch s {
+	case "O-O", "0-0":
+		return b.castleSAN(6)
+	case "O-O-O", "0-0-0":
+		return b.castleSAN(2)
+	}
+
+	match := sanRegexp.FindStringSubmatch(s)
+	if match == nil {
+		return fmt.Errorf("%w: %q", ErrInvalidSAN, san)
+	}
+
+	name := Pawn
+	if match[1] != "" {
+		name = PieceName(strings.Index(sanPieces, match[1]))
+	}
+	to, err := locToPos(match[5])
+	if err != nil {
+		return err
+	}
+	promotion := Pawn
+	if match[6] != "" {
+		promotion = PieceName(strings.Index(sanPieces, match[6]))
+	}
+	if name != Pawn && promotion != Pawn {
+		return fmt.Errorf("%w: only pawns can promote in %q", ErrInvalidSAN, san)
+	}
+
+	// The capture marker must match whether the move captures. A pawn
+	// captures by moving diagonally, which can be en passant onto an
+	// empty position, so a pawn's capture must give it's file instead.
+	capture := match[4] != ""
+	if name == Pawn {
+		if capture && match[2] == "" {
+			return fmt.Errorf("%w: pawn capture without a file in %q", ErrInvalidSAN, san)
+		}
+	} else if _, occupied := b.pieceAt(to); capture && !occupied {
+		return fmt.Errorf("%w: %q isn't a capture", ErrInvalidSAN, san)
+	} else if !capture && occupied {
+		return fmt.Errorf("%w: capture without x in %q", ErrInvalidSAN, san)
+	}
+
+	// Find all of the pieces that could be moving to position to.
+	var candidates, legal []Pos
+	for pieces := b.pieces[b.turn][name]; pieces != 0; {
+		pos := squarePos(pieces.pop())
+		pc := b.squares[pos.square()]
+		if match[2] != "" && pos.X != int(match[2][0]-'a') {
+			continue
+		}
+		if match[3] != "" && pos.Y != int(match[3][0]-'1') {
+			continue
+		}
+		// A pawn only moves straight forward without capturing.
+		if name == Pawn && capture == (pos.X == to.X) {
+			continue
+		}
+		if !getMovePositions(pc, pos).has(to) {
+			continue
+		}
+		candidates = append(candidates, pos)
+		if b.moveLegal(pc, pos, to) == nil {
+			legal = append(legal, pos)
+		}
+	}
+
+	var from Pos
+	switch {
+	case len(legal) == 1:
+		from = legal[0]
+	case len(legal) > 1:
+		return fmt.Errorf("%w: %q", ErrAmbiguousSAN, san)
+	case len(candidates) == 1:
+		// Let b.Move explain why the only candidate's move is illegal.
+		return b.Move(candidates[0], to)
+	default:
+		return ErrInvalidPieceMove
+	}
+
+	if name == Pawn && (to.Y == 7 || to.Y == 0) && promotion == Pawn {
+		return fmt.Errorf("%w: missing promotion piece in %q", ErrInvalidSAN, san)
+	}
+	if promotion != Pawn && to.Y != 7 && to.Y != 0 {
+		return fmt.Errorf("%w: pawn can't promote on %s", ErrInvalidSAN, to)
+	}
+
+	return b.MoveWithPromotion(from, to, promotion)
+}
+
+// castleSAN castles the king for the side to move to the x position
+// on the king's starting rank.
+func (b *Board) castleSAN(x int) error {
+	y := homeRank(b.turn)
+	king, found := b.pieceAt(Pos{4, y})
+	if !found || king.Name != King || king.Color != b.turn {
+		return ErrKingOrRookMoved
+	}
+	return b.Move(Pos{4, y}, Pos{x, y})
+}
+
+// HistorySAN returns all of the moves currently stored in the board's
+// history in Standard Algebraic Notation with move numbers. For
+// example, 1. e4 e5 2. Nf3 Nc6.
+func (b *Board) HistorySAN() string {
+	var sb strings.Builder
+	num := b.startFullMove
+	for i, m := range b.history[:b.moveNum+1] {
+		switch {
+		case m.Piece.Color == White:
+			fmt.Fprintf(&sb, "%d. ", num)
+		case i == 0:
+			fmt.Fprintf(&sb, "%d... ", num)
+		}
+		if m.Piece.Color == Black {
+			num++
+		}
+		sb.WriteString(m.SAN + " ")
+	}
+	return strings.TrimRight(sb.String(), " ")
+}
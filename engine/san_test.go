@@ -0,0 +1,181 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMoveSAN(t *testing.T) {
+	b := NewBoard()
+
+	// The Opera Game, Morphy vs Duke Karl / Count Isouard, 1858.
+	moves := []string{
+		"e4", "e5", "Nf3", "d6", "d4", "Bg4", "dxe5", "Bxf3", "Qxf3", "dxe5",
+		"Bc4", "Nf6", "Qb3", "Qe7", "Nc3", "c6", "Bg5", "b5", "Nxb5", "cxb5",
+		"Bxb5+", "Nbd7", "O-O-O", "Rd8", "Rxd7", "Rxd7", "Rd1", "Qe6",
+		"Bxd7+", "Nxd7", "Qb8+", "Nxb8", "Rd8#",
+	}
+	for _, san := range moves {
+		if err := b.MoveSAN(san); err != nil {
+			t.Fatalf("moving %s failed: %s", san, err)
+		}
+	}
+	for i, m := range b.history {
+		if m.SAN != moves[i] {
+			t.Errorf("expected move %d's SAN to be %s, got %s", i, moves[i], m.SAN)
+		}
+	}
+	if !b.InCheckmate(Black) {
+		t.Error("expected black to be in checkmate")
+	}
+
+	expected := "1. e4 e5 2. Nf3 d6 3. d4 Bg4 4. dxe5 Bxf3 5. Qxf3 dxe5 " +
+		"6. Bc4 Nf6 7. Qb3 Qe7 8. Nc3 c6 9. Bg5 b5 10. Nxb5 cxb5 " +
+		"11. Bxb5+ Nbd7 12. O-O-O Rd8 13. Rxd7 Rxd7 14. Rd1 Qe6 " +
+		"15. Bxd7+ Nxd7 16. Qb8+ Nxb8 17. Rd8#"
+	if history := b.HistorySAN(); history != expected {
+		t.Errorf("expected history to be %q, got %q", expected, history)
+	}
+}
+
+func TestSANDisambiguation(t *testing.T) {
+	b, err := ParseFEN("1k1r3r/8/8/R7/4Q2Q/8/2K5/R6Q w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		in, out string
+	}{
+		// Two rooks on the same file.
+		{"R1a3", "R1a3"},
+		// Two rooks on the same rank.
+		{"Rdf8", "Rdf8"},
+		// Three queens that can all reach e1, so both the file
+		// and rank are needed.
+		{"Qh4e1", "Qh4e1"},
+	}
+	for _, tc := range testCases {
+		if err := b.MoveSAN(tc.in); err != nil {
+			t.Fatalf("moving %s failed: %s", tc.in, err)
+		}
+		m, _ := b.prevMove()
+		if m.SAN != tc.out {
+			t.Errorf("expected SAN to be %s, got %s", tc.out, m.SAN)
+		}
+	}
+}
+
+func TestSANEnPassantAndPromotion(t *testing.T) {
+	b, err := ParseFEN("4k3/1P6/8/3pP3/8/8/8/4K3 w - d6 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.MoveSAN("exd6"); err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := b.prevMove(); m.SAN != "exd6" || !m.EnPassant {
+		t.Errorf("expected en passant exd6, got %s", m.SAN)
+	}
+	if err := b.MoveSAN("Kd7"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A pawn can't move to the last rank without promoting.
+	if err := b.MoveSAN("b8"); !errors.Is(err, ErrInvalidSAN) {
+		t.Errorf("expected ErrInvalidSAN error, got %v", err)
+	}
+	if err := b.MoveSAN("b8=Q"); err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := b.prevMove(); m.SAN != "b8=Q" {
+		t.Errorf("expected SAN to be b8=Q, got %s", m.SAN)
+	}
+
+	// Promoting with a check adds a check suffix after the piece.
+	b, err = ParseFEN("8/1P6/8/8/8/8/k7/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.MoveSAN("b8=R"); err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := b.prevMove(); m.SAN != "b8=R" {
+		t.Errorf("expected SAN to be b8=R, got %s", m.SAN)
+	}
+	if err := b.MoveSAN("Ka3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.MoveSAN("Rb3+"); err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := b.prevMove(); m.SAN != "Rb3+" {
+		t.Errorf("expected SAN to be Rb3+, got %s", m.SAN)
+	}
+}
+
+func TestMoveSANErrors(t *testing.T) {
+	b, err := ParseFEN("4k3/8/8/1b6/8/3N4/4K3/R6R w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		san string
+		err error
+	}{
+		{"", ErrInvalidSAN},
+		{"Zf3", ErrInvalidSAN},
+		{"e9", ErrInvalidSAN},
+		{"Nf3=Q", ErrInvalidSAN},
+		// Both rooks can move to f1.
+		{"Rf1", ErrAmbiguousSAN},
+		// The knight on d3 is pinned.
+		{"Nf4", ErrMovingIntoCheck},
+		// No bishop can move to f3.
+		{"Bf3", ErrInvalidPieceMove},
+		// White's king isn't on it's starting position.
+		{"O-O", ErrKingOrRookMoved},
+	}
+	for _, tc := range testCases {
+		if err := b.MoveSAN(tc.san); !errors.Is(err, tc.err) {
+			t.Errorf("expected moving %q to fail with %v, got %v",
+				tc.san, tc.err, err)
+		}
+	}
+}
+
+func TestMoveSANCaptures(t *testing.T) {
+	testCases := []struct {
+		fen string
+		san string
+		err error
+	}{
+		// Only the pawn on d3 can move to e4, which is a capture.
+		{"4k3/8/8/8/4p3/3P4/8/4K3 w - - 0 1", "e4", ErrInvalidPieceMove},
+		{"4k3/8/8/8/4p3/3P4/8/4K3 w - - 0 1", "xe4", ErrInvalidSAN},
+		{"4k3/8/8/8/4p3/3P4/8/4K3 w - - 0 1", "exe4", ErrInvalidPieceMove},
+		{"4k3/8/8/8/4p3/3P4/8/4K3 w - - 0 1", "dxe4", nil},
+		// Nothing is captured on f3 or e4.
+		{StartFEN, "Nxf3", ErrInvalidSAN},
+		{StartFEN, "exe4", ErrInvalidPieceMove},
+		{StartFEN, "dxe3", ErrInvalidPieceMove},
+		// The knight on g1 captures on f3.
+		{"4k3/8/8/8/8/5p2/8/4K1N1 w - - 0 1", "Nf3", ErrInvalidSAN},
+		{"4k3/8/8/8/8/5p2/8/4K1N1 w - - 0 1", "Nxf3", nil},
+		// An en passant capture moves onto an empty position.
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "exd6", nil},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "d6", ErrInvalidPieceMove},
+	}
+	for _, tc := range testCases {
+		b, err := ParseFEN(tc.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := b.MoveSAN(tc.san); !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected moving %q to fail with %v, got %v",
+				tc.fen, tc.san, tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Position is an immutable snapshot of a board's position at the time
+// that it was taken, which doesn't change when moves are made on the
+// board afterwards.
+//
+// Positions are small values that are safe to copy, compare and share
+// between goroutines.
+type Position struct {
+	pieces    [2][6]bitboard
+	turn      Color
+	castling  uint8 // A bit for each castlingIndex.
+	enPassant Pos
+	halfMoves int
+	fullMove  int
+	hash      uint64
+}
+
+// Position returns a snapshot of the board's current position.
+func (b *Board) Position() Position {
+	p := Position{
+		pieces:    b.pieces,
+		turn:      b.turn,
+		enPassant: b.enPassantTarget(),
+		halfMoves: b.HalfMoveClock(),
+		fullMove:  b.FullMoveNumber(),
+		hash:      b.hash,
+	}
+	for _, color := range []Color{White, Black} {
+		for _, rookX := range []int{0, 7} {
+			if b.canStillCastle(color, rookX) {
+				p.castling |= 1 << uint(castlingIndex(color, rookX))
+			}
+		}
+	}
+	return p
+}
+
+// PieceAt returns the piece at position pos and true, or false if
+// there's no piece at pos.
+func (p Position) PieceAt(pos Pos) (Piece, bool) {
+	for color := range p.pieces {
+		for name, bb := range p.pieces[color] {
+			if bb.has(pos) {
+				return Piece{PieceName(name), Color(color)}, true
+			}
+		}
+	}
+	return Piece{}, false
+}
+
+// Turn returns the color of the side to move.
+func (p Position) Turn() Color {
+	return p.turn
+}
+
+// CanCastle reports whether color still has the right to castle king
+// side, or queen side if kingSide is false.
+func (p Position) CanCastle(color Color, kingSide bool) bool {
+	rookX := 0
+	if kingSide {
+		rookX = 7
+	}
+	return p.castling&(1<<uint(castlingIndex(color, rookX))) != 0
+}
+
+// EnPassant returns the position that a pawn passed over by moving two
+// positions forward on the previous move and true, or false if the
+// previous move wasn't a pawn moving two positions.
+func (p Position) EnPassant() (Pos, bool) {
+	return p.enPassant, !offBoard(p.enPassant)
+}
+
+// HalfMoveClock returns the number of half moves made since the last
+// capture or pawn move.
+func (p Position) HalfMoveClock() int {
+	return p.halfMoves
+}
+
+// FullMoveNumber returns the move number, which starts at 1 and is
+// incremented after each of black's moves.
+func (p Position) FullMoveNumber() int {
+	return p.fullMove
+}
+
+// Hash returns the position's Zobrist hash, which is the same as the
+// board's Hash at the time the snapshot was taken.
+func (p Position) Hash() uint64 {
+	return p.hash
+}
+
+// FEN returns the position as a FEN string.
+func (p Position) FEN() string {
+	var sb strings.Builder
+
+	// Piece placement.
+	for y := 7; y >= 0; y-- {
+		empty := 0
+		for x := 0; x < 8; x++ {
+			piece, found := p.PieceAt(Pos{x, y})
+			if !found {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteByte(byte('0' + empty))
+				empty = 0
+			}
+			sb.WriteByte(fenLetter(&piece))
+		}
+		if empty > 0 {
+			sb.WriteByte(byte('0' + empty))
+		}
+		if y > 0 {
+			sb.WriteByte('/')
+		}
+	}
+
+	// Active color.
+	if p.turn == White {
+		sb.WriteString(" w ")
+	} else {
+		sb.WriteString(" b ")
+	}
+
+	// Castling rights.
+	castling := ""
+	for _, cr := range []struct {
+		letter   string
+		color    Color
+		kingSide bool
+	}{
+		{"K", White, true}, {"Q", White, false}, {"k", Black, true}, {"q", Black, false},
+	} {
+		if p.CanCastle(cr.color, cr.kingSide) {
+			castling += cr.letter
+		}
+	}
+	if castling == "" {
+		castling = "-"
+	}
+	sb.WriteString(castling)
+
+	// En passant target.
+	if ep, ok := p.EnPassant(); ok {
+		sb.WriteString(" " + ep.loc())
+	} else {
+		sb.WriteString(" -")
+	}
+
+	fmt.Fprintf(&sb, " %d %d", p.halfMoves, p.fullMove)
+
+	return sb.String()
+}
+
+// Board returns a new board set up at the position, without any
+// history, which moves can be made on.
+//
+// An error is returned if the board can't be set up at the position,
+// such as when the snapshot was taken while a pawn was waiting to be
+// promoted.
+func (p Position) Board() (*Board, error) {
+	return ParseFEN(p.FEN())
+}
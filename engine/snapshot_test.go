@@ -0,0 +1,78 @@
+package engine
+
+import "testing"
+
+func TestPosition(t *testing.T) {
+	b := NewBoard()
+	for _, move := range []string{"e4", "c5", "e5", "d5", "Ke2"} {
+		if err := b.MoveSAN(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := b.Position()
+	fen := "rnbqkbnr/pp2pppp/8/2ppP3/8/8/PPPPKPPP/RNBQ1BNR b kq - 1 3"
+	if p.FEN() != fen {
+		t.Errorf("expected FEN %q, got %q", fen, p.FEN())
+	}
+
+	// Making moves on the board shouldn't change the snapshot.
+	if err := b.MoveSAN("Nc6"); err != nil {
+		t.Fatal(err)
+	}
+	if p.FEN() != fen {
+		t.Errorf("expected snapshot to still be %q, got %q", fen, p.FEN())
+	}
+	if p.Turn() != Black {
+		t.Error("expected it to be black's turn in the snapshot")
+	}
+	if p.CanCastle(White, true) || p.CanCastle(White, false) {
+		t.Error("expected white to have lost it's castling rights")
+	}
+	if !p.CanCastle(Black, true) || !p.CanCastle(Black, false) {
+		t.Error("expected black to still be able to castle")
+	}
+	if _, ok := p.EnPassant(); ok {
+		t.Error("expected no en passant target after Ke2")
+	}
+	if p.HalfMoveClock() != 1 || p.FullMoveNumber() != 3 {
+		t.Errorf("expected move counters 1 and 3, got %d and %d",
+			p.HalfMoveClock(), p.FullMoveNumber())
+	}
+
+	piece, found := p.PieceAt(Pos{4, 1})
+	if !found || piece != (Piece{King, White}) {
+		t.Errorf("expected white king at e2, got %v, %t", piece, found)
+	}
+	if _, found := p.PieceAt(Pos{4, 0}); found {
+		t.Error("expected no piece at e1")
+	}
+
+	// Undoing back to the snapshot's position gives the same snapshot.
+	if err := b.UndoMove(); err != nil {
+		t.Fatal(err)
+	}
+	if b.Position() != p {
+		t.Error("expected the same snapshot after undoing back to it's position")
+	}
+
+	// A new board can be set up from the snapshot.
+	nb, err := p.Board()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nb.FEN() != fen || nb.Hash() != p.Hash() {
+		t.Errorf("expected new board at %q with the snapshot's hash, got %q",
+			fen, nb.FEN())
+	}
+}
+
+func TestPositionEnPassant(t *testing.T) {
+	b := NewBoard()
+	if err := b.MoveSAN("d4"); err != nil {
+		t.Fatal(err)
+	}
+	ep, ok := b.Position().EnPassant()
+	if !ok || ep != (Pos{3, 2}) {
+		t.Errorf("expected en passant target d3, got %v, %t", ep, ok)
+	}
+}
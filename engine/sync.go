@@ -0,0 +1,258 @@
+package engine
+
+import "sync"
+
+// A SyncGame is a handle to a Game that's safe for concurrent use by
+// multiple goroutines, so that a server or UI can read a game's state
+// from one goroutine while moves are made from another.
+//
+// Any number of goroutines can query the game at once, since queries
+// don't modify the board, while moves and other changes to the game wait
+// for exclusive access. Values returned by a SyncGame, such as a Position
+// or a MoveInfo, are copies that don't change with the game afterwards.
+type SyncGame struct {
+	mu sync.RWMutex
+	g  *Game
+}
+
+// NewSyncGame creates a SyncGame for g. g must not be used directly
+// afterwards, other than through the SyncGame's Do and View methods.
+func NewSyncGame(g *Game) *SyncGame {
+	return &SyncGame{g: g}
+}
+
+// Do calls f with exclusive access to the game, so that a sequence of
+// changes and queries can be made without any other goroutine making
+// changes in between. f must not keep g after it returns.
+func (s *SyncGame) Do(f func(g *Game)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(s.g)
+}
+
+// View calls f with read access to the game, so that several queries
+// can be made on the same position. f must not change the game or keep
+// g after it returns.
+func (s *SyncGame) View(f func(g *Game)) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f(s.g)
+}
+
+// Subscribe calls f with each event that happens on the game's board,
+// until the returned unsubscribe function is called. f is called while
+// the game is locked for the change that sent the event, so it must not
+// call the SyncGame's methods.
+func (s *SyncGame) Subscribe(f func(Event)) (unsubscribe func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unsubscribeBoard := s.g.Subscribe(f)
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		unsubscribeBoard()
+	}
+}
+
+// update calls f with exclusive access to the game and returns it's
+// error.
+func (s *SyncGame) update(f func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return f()
+}
+
+// Move moves the piece at p1 to p2.
+func (s *SyncGame) Move(p1, p2 Pos) error {
+	return s.update(func() error { return s.g.Move(p1, p2) })
+}
+
+// MoveWithPromotion moves the piece at p1 to p2, promoting a pawn to
+// promo.
+func (s *SyncGame) MoveWithPromotion(p1, p2 Pos, promo PieceName) error {
+	return s.update(func() error { return s.g.MoveWithPromotion(p1, p2, promo) })
+}
+
+// MoveByLocation makes a move from loc1 to loc2, such as e2 to e4.
+func (s *SyncGame) MoveByLocation(loc1, loc2 string) error {
+	return s.update(func() error { return s.g.MoveByLocation(loc1, loc2) })
+}
+
+// MoveSAN makes a move in SAN, such as Nf3.
+func (s *SyncGame) MoveSAN(san string) error {
+	return s.update(func() error { return s.g.MoveSAN(san) })
+}
+
+// PromotePawn promotes the pawn that's waiting to be promoted to to.
+func (s *SyncGame) PromotePawn(to PieceName) error {
+	return s.update(func() error { return s.g.PromotePawn(to) })
+}
+
+// UndoMove takes back the last move.
+func (s *SyncGame) UndoMove() error {
+	return s.update(s.g.UndoMove)
+}
+
+// RedoMove makes the last move that was taken back again.
+func (s *SyncGame) RedoMove() error {
+	return s.update(s.g.RedoMove)
+}
+
+// GoToMove undoes or redoes moves until n moves from the start of the
+// history have been made.
+func (s *SyncGame) GoToMove(n int) error {
+	return s.update(func() error { return s.g.GoToMove(n) })
+}
+
+// Resign ends the game with color resigning.
+func (s *SyncGame) Resign(color Color) error {
+	return s.update(func() error { return s.g.Resign(color) })
+}
+
+// Timeout ends the game with color running out of time.
+func (s *SyncGame) Timeout(color Color) error {
+	return s.update(func() error { return s.g.Timeout(color) })
+}
+
+// OfferDraw offers a draw from color to it's opponent.
+func (s *SyncGame) OfferDraw(color Color) error {
+	return s.update(func() error { return s.g.OfferDraw(color) })
+}
+
+// AcceptDraw accepts an open draw offer for color.
+func (s *SyncGame) AcceptDraw(color Color) error {
+	return s.update(func() error { return s.g.AcceptDraw(color) })
+}
+
+// DeclineDraw declines an open draw offer for color.
+func (s *SyncGame) DeclineDraw(color Color) error {
+	return s.update(func() error { return s.g.DeclineDraw(color) })
+}
+
+// ClaimDraw ends the game in a draw if the side to move can claim one.
+func (s *SyncGame) ClaimDraw() error {
+	return s.update(s.g.ClaimDraw)
+}
+
+// Turn returns the color of the side to move.
+func (s *SyncGame) Turn() Color {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.g.Turn()
+}
+
+// Position returns a snapshot of the game's current position.
+func (s *SyncGame) Position() Position {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.g.Position()
+}
+
+// FEN returns the FEN string of the game's current position.
+func (s *SyncGame) FEN() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.g.FEN()
+}
+
+// LegalMoves returns every legal move for the side to move.
+func (s *SyncGame) LegalMoves() []Move {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.g.LegalMoves()
+}
+
+// LegalMovesFrom returns every legal move for the piece at pos.
+func (s *SyncGame) LegalMovesFrom(pos Pos) []Move {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.g.LegalMovesFrom(pos)
+}
+
+// HasCheck reports whether there's a king in check, and it's color.
+func (s *SyncGame) HasCheck() (bool, Color) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.g.HasCheck()
+}
+
+// MustPromote reports whether a pawn is waiting to be promoted, and it's
+// color.
+func (s *SyncGame) MustPromote() (bool, Color) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.g.MustPromote()
+}
+
+// Status returns the game's status and the color of the winner.
+func (s *SyncGame) Status() (Status, Color) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.g.Status()
+}
+
+// Over reports whether the game has ended.
+func (s *SyncGame) Over() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.g.Over()
+}
+
+// Message returns a message describing the game's status.
+func (s *SyncGame) Message() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.g.Message()
+}
+
+// DrawReason returns the reason that the game is drawn, or NoDraw.
+func (s *SyncGame) DrawReason() DrawReason {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.g.DrawReason()
+}
+
+// DrawOffer reports whether there's an open draw offer, and the color
+// that offered it.
+func (s *SyncGame) DrawOffer() (bool, Color) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.g.DrawOffer()
+}
+
+// Moves returns the moves that have been made from the starting
+// position.
+func (s *SyncGame) Moves() []Move {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.g.Moves()
+}
+
+// LastMove returns a copy of the last move that was made.
+func (s *SyncGame) LastMove() (*MoveInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.g.LastMove()
+}
+
+// HistorySAN returns the moves that have been made in SAN.
+func (s *SyncGame) HistorySAN() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.g.HistorySAN()
+}
+
+// InitialFEN returns the FEN string of the game's starting position.
+func (s *SyncGame) InitialFEN() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.g.InitialFEN()
+}
+
+// Clone returns a deep copy of the game's board, which can be used
+// freely, such as for a search, without holding up the game.
+func (s *SyncGame) Clone() *Board {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.g.Clone()
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSyncGame(t *testing.T) {
+	s := NewSyncGame(NewGame(NewBoard()))
+	var moves int
+	unsubscribe := s.Subscribe(func(e Event) {
+		if e.Type == EventMove {
+			moves++
+		}
+	})
+
+	// Query the game from several goroutines while moves are made and
+	// taken back from another, which the race detector checks.
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				s.LegalMoves()
+				s.Status()
+				s.Message()
+				s.FEN()
+				s.Position()
+				s.HistorySAN()
+				s.LastMove()
+				s.DrawReason()
+				s.Clone()
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		for _, san := range []string{"e4", "e5", "Nf3", "Nc6", "Bb5", "a6", "Bxc6", "dxc6", "O-O"} {
+			if err := s.MoveSAN(san); err != nil {
+				t.Fatalf("%s: %v", san, err)
+			}
+		}
+		if err := s.GoToMove(0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	unsubscribe()
+	if moves != 20*9 {
+		t.Errorf("expected %d move events, got %d", 20*9, moves)
+	}
+	if fen := s.FEN(); fen != StartFEN {
+		t.Errorf("expected the starting position, got %s", fen)
+	}
+	if err := s.MoveByLocation("e7", "e5"); err != ErrOpponentsPiece {
+		t.Errorf("expected error %v, got %v", ErrOpponentsPiece, err)
+	}
+
+	// Do makes several changes without any other changes in between.
+	s.Do(func(g *Game) {
+		g.MoveSAN("f3")
+		g.MoveSAN("e5")
+		g.MoveSAN("g4")
+		g.MoveSAN("Qh4")
+	})
+	if status, winner := s.Status(); status != StatusCheckmate || winner != Black {
+		t.Errorf("expected black to checkmate, got %s and %d", status, winner)
+	}
+	var turn Color
+	var legal int
+	s.View(func(g *Game) {
+		turn, legal = g.Turn(), len(g.LegalMoves())
+	})
+	if turn != White || legal != 0 {
+		t.Errorf("expected white to have no legal moves, got %s to move with %d", turn, legal)
+	}
+	if err := s.Resign(White); err != ErrGameOver {
+		t.Errorf("expected error %v, got %v", ErrGameOver, err)
+	}
+}
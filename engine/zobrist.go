@@ -0,0 +1,124 @@
+package engine
+
+// Zobrist keys are random numbers for each part of a position, which
+// are xored together to make the position's hash.
+//
+// The keys are generated from a fixed seed, so the same position always
+// has the same hash, even across different runs of a program.
+var (
+	// zobristPieces holds a key for each color's pieces of each piece
+	// name on each square.
+	zobristPieces [2][6][64]uint64
+
+	// zobristBlack is included in the hash when it's black's turn.
+	zobristBlack uint64
+
+	// zobristCastling holds a key for each color's king side and
+	// queen side castling rights, indexed by castlingIndex.
+	zobristCastling [4]uint64
+
+	// zobristEnPassant holds a key for each file that a pawn can
+	// capture en passant on.
+	zobristEnPassant [8]uint64
+)
+
+func init() {
+	// A splitmix64 generator, which is simple and good enough for
+	// making keys that don't collide.
+	seed := uint64(0x9e3779b97f4a7c15)
+	next := func() uint64 {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		return z ^ (z >> 31)
+	}
+
+	for color := range zobristPieces {
+		for name := range zobristPieces[color] {
+			for sq := range zobristPieces[color][name] {
+				zobristPieces[color][name][sq] = next()
+			}
+		}
+	}
+	zobristBlack = next()
+	for i := range zobristCastling {
+		zobristCastling[i] = next()
+	}
+	for i := range zobristEnPassant {
+		zobristEnPassant[i] = next()
+	}
+}
+
+// castlingIndex returns the index in zobristCastling for color castling
+// with the rook on file rookX.
+func castlingIndex(color Color, rookX int) int {
+	i := int(color) * 2
+	if rookX == 0 {
+		i++
+	}
+	return i
+}
+
+// Hash returns a 64 bit Zobrist hash of the board's current position.
+//
+// Positions have the same hash when they have the same pieces on the
+// same positions, the same side to move, the same castling rights and
+// the same en passant captures available, no matter which moves were
+// made to reach them.
+func (b *Board) Hash() uint64 {
+	return b.hash
+}
+
+// stateHash returns the part of the board's hash that isn't from the
+// pieces on the board, which is the side to move, the castling rights
+// and the en passant file.
+func (b *Board) stateHash() uint64 {
+	var hash uint64
+	if b.turn == Black {
+		hash ^= zobristBlack
+	}
+	for _, color := range []Color{White, Black} {
+		for _, rookX := range []int{0, 7} {
+			if b.canStillCastle(color, rookX) {
+				hash ^= zobristCastling[castlingIndex(color, rookX)]
+			}
+		}
+	}
+	if ep := b.enPassantCapture(); !b.positionOffBoard(ep) {
+		hash ^= zobristEnPassant[ep.X]
+	}
+	return hash
+}
+
+// updateHash replaces the side to move, castling rights and en passant
+// file in the board's hash with the board's current ones. The pieces
+// are kept up to date in the hash by setPiece and removePiece.
+func (b *Board) updateHash() {
+	b.hash ^= b.hashState
+	b.hashState = b.stateHash()
+	b.hash ^= b.hashState
+}
+
+// enPassantCapture returns the board's en passant target position if a
+// pawn can legally capture en passant, or an invalid position if not.
+func (b *Board) enPassantCapture() Pos {
+	ep := b.enPassantTarget()
+	if b.positionOffBoard(ep) {
+		return ep
+	}
+	y := ep.Y - 1
+	if b.turn == Black {
+		y = ep.Y + 1
+	}
+	for _, x := range []int{ep.X - 1, ep.X + 1} {
+		pawn, found := b.pieceAt(Pos{x, y})
+		if !found || pawn.Name != Pawn || pawn.Color != b.turn {
+			continue
+		}
+		if b.moveLegal(pawn, Pos{x, y}, ep) == nil {
+			return ep
+		}
+	}
+	return Pos{-1, -1}
+}
@@ -0,0 +1,143 @@
+package engine
+
+import "testing"
+
+// playSAN makes each of the moves in SAN on board b.
+func playSAN(t *testing.T, b *Board, moves ...string) {
+	t.Helper()
+	for _, move := range moves {
+		if err := b.MoveSAN(move); err != nil {
+			t.Fatalf("%s: %v", move, err)
+		}
+	}
+}
+
+func TestHashMatchesFEN(t *testing.T) {
+	// checkHashes compares the board's hash with the hash of a new
+	// board set up from it's FEN, for every position up to depth
+	// moves ahead.
+	var checkHashes func(b *Board, depth int)
+	checkHashes = func(b *Board, depth int) {
+		fen := b.FEN()
+		fresh, err := ParseFEN(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.Hash() != fresh.Hash() {
+			t.Fatalf("%s: expected hash %x, got %x", fen, fresh.Hash(), b.Hash())
+		}
+		if depth == 0 {
+			return
+		}
+		for _, m := range b.LegalMoves() {
+			if err := b.MoveWithPromotion(m.From, m.To, m.Promotion); err != nil {
+				t.Fatal(err)
+			}
+			checkHashes(b, depth-1)
+			if err := b.UndoMove(); err != nil {
+				t.Fatal(err)
+			}
+			if b.Hash() != fresh.Hash() {
+				t.Fatalf("%s: expected hash %x after undoing %s, got %x",
+					fen, fresh.Hash(), m, b.Hash())
+			}
+		}
+	}
+
+	// Positions with castling, en passant and promotions.
+	for _, fen := range []string{
+		StartFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+	} {
+		b, err := ParseFEN(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkHashes(b, 2)
+	}
+}
+
+func TestHashRedo(t *testing.T) {
+	b := NewBoard()
+	playSAN(t, b, "e4", "d5", "exd5", "c6", "dxc6", "Nf6", "cxb7", "e6", "bxa8=Q")
+	hash := b.Hash()
+	if err := b.GoToMove(0); err != nil {
+		t.Fatal(err)
+	}
+	if b.Hash() != NewBoard().Hash() {
+		t.Error("expected hash to match the starting position")
+	}
+	if err := b.GoToMove(9); err != nil {
+		t.Fatal(err)
+	}
+	if b.Hash() != hash {
+		t.Errorf("expected hash %x after redoing moves, got %x", hash, b.Hash())
+	}
+}
+
+func TestHashTransposition(t *testing.T) {
+	b1, b2 := NewBoard(), NewBoard()
+	playSAN(t, b1, "Nf3", "Nf6", "Nc3", "Nc6")
+	playSAN(t, b2, "Nc3", "Nc6", "Nf3", "Nf6")
+	if b1.Hash() != b2.Hash() {
+		t.Error("expected the same position reached by different moves to have the same hash")
+	}
+
+	// Moving the knights back and forth reaches the starting position.
+	playSAN(t, b1, "Ng1", "Ng8", "Nb1", "Nb8")
+	if b1.Hash() != NewBoard().Hash() {
+		t.Error("expected hash to match the starting position")
+	}
+}
+
+func TestHashDifferences(t *testing.T) {
+	start := NewBoard()
+	playSAN(t, start, "e4", "e5")
+
+	testCases := []struct {
+		name  string
+		moves []string
+	}{
+		// The same pieces with different castling rights.
+		{"castling rights", []string{"e4", "e5", "Ke2", "Ke7", "Ke1", "Ke8"}},
+		// The same pieces with black to move.
+		{"side to move", []string{"e4", "e5", "Nf3", "Nc6", "Ng1", "Nb8", "Qh5", "Qh4", "Qd1"}},
+	}
+	for _, tc := range testCases {
+		b := NewBoard()
+		playSAN(t, b, tc.moves...)
+		if b.Hash() == start.Hash() {
+			t.Errorf("%s: expected hashes to be different", tc.name)
+		}
+	}
+}
+
+func TestHashEnPassant(t *testing.T) {
+	// No black pawn can capture e3 en passant, so the en passant
+	// target doesn't change the hash.
+	b := NewBoard()
+	playSAN(t, b, "e4")
+	noEnPassant, err := ParseFEN("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Hash() != noEnPassant.Hash() {
+		t.Error("expected en passant target without a capture to not change the hash")
+	}
+
+	// After d5, white's pawn on e5 can capture d6 en passant.
+	b, err = ParseFEN("rnbqkbnr/pppppppp/8/4P3/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	playSAN(t, b, "d5")
+	noEnPassant, err = ParseFEN("rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR w KQkq - 0 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Hash() == noEnPassant.Hash() {
+		t.Error("expected en passant capture to change the hash")
+	}
+}
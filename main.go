@@ -2,47 +2,240 @@ package main
 
 import (
 	"bufio"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/radovskyb/chess/ai"
+	"github.com/radovskyb/chess/api"
+	"github.com/radovskyb/chess/cecp"
+	"github.com/radovskyb/chess/clock"
 	"github.com/radovskyb/chess/engine"
+	"github.com/radovskyb/chess/server"
+	"github.com/radovskyb/chess/uci"
+	"github.com/radovskyb/chess/web"
 )
 
 func main() {
+	vsComputer := flag.String("vs-computer", "", "play against the computer as white or black")
+	depth := flag.Int("depth", 0, "the computer's maximum search depth in half moves, or 0 for no limit")
+	moveTime := flag.Duration("movetime", 2*time.Second, "the computer's maximum thinking time for each move")
+	enginePath := flag.String("engine", "", "the path of a UCI engine to play as the computer and to analyse with")
+	weightsPath := flag.String("weights", "", "the path of a JSON file of evaluation weights for the computer")
+	timeControl := flag.String("time", "", "the time control to play and serve games with, such as 5+3 or 40/90+30:30+30, or none for untimed games")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [uci | xboard | serve [addr] | connect addr | web [addr] | api [addr]]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var control clock.Control
+	if *timeControl != "" {
+		var err error
+		if control, err = clock.Parse(*timeControl); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	// Run as a UCI or xboard engine for chess GUIs instead of playing
+	// in the terminal.
+	switch flag.Arg(0) {
+	case "uci":
+		if err := uci.Run(os.Stdin, os.Stdout); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	case "xboard":
+		if err := cecp.Run(os.Stdin, os.Stdout); err != nil {
+			log.Fatalln(err)
+		}
+		return
+
+	// Host games between players on the network, or connect to a
+	// server to play one. The web server hosts games for browsers, and
+	// the API lets other programs drive games over HTTP.
+	case "serve":
+		addr := server.DefaultAddr
+		if flag.NArg() > 1 {
+			addr = flag.Arg(1)
+		}
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("listening on %s\n", l.Addr())
+		log.Fatalln(server.Serve(l, control))
+	case "web":
+		addr := web.DefaultAddr
+		if flag.NArg() > 1 {
+			addr = flag.Arg(1)
+		}
+		fmt.Printf("serving the browser client on %s\n", addr)
+		log.Fatalln(http.ListenAndServe(addr, web.NewHandler(control)))
+	case "api":
+		addr := api.DefaultAddr
+		if flag.NArg() > 1 {
+			addr = flag.Arg(1)
+		}
+		fmt.Printf("serving the API on %s\n", addr)
+		log.Fatalln(http.ListenAndServe(addr, api.NewServer()))
+	case "connect":
+		if flag.NArg() < 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		if err := playOnline(flag.Arg(1)); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+
+	// computer holds the color that the computer plays, or 2 if
+	// there's no computer playing.
+	var computer engine.Color = 2
+	switch *vsComputer {
+	case "":
+	case "white":
+		computer = engine.Black
+	case "black":
+		computer = engine.White
+	default:
+		log.Fatalf("invalid color %q for -vs-computer, must be white or black", *vsComputer)
+	}
+	limits := ai.Limits{Depth: *depth, Time: *moveTime}
+	if *weightsPath != "" {
+		weights, err := loadWeights(*weightsPath)
+		if err != nil {
+			log.Fatalf("loading weights %s: %v", *weightsPath, err)
+		}
+		limits.Weights = &weights
+	}
+
+	// search finds the computer's moves, which are found by an external
+	// engine instead of the ai package when one is given.
+	search := ai.Search
+	var external *uci.Client
+	if *enginePath != "" {
+		var err error
+		if external, err = uci.Start(*enginePath); err != nil {
+			log.Fatalf("starting engine %s: %v", *enginePath, err)
+		}
+		defer external.Close()
+		fmt.Printf("using engine %s\n", external.Name)
+		search = external.Analyse
+	}
+
 	b := engine.NewBoard()
-	b.Print()
+	g := engine.NewGame(b)
+
+	// Moves are made through a clock.Game in a timed game, so that the
+	// clock is pressed after each one.
+	var moves mover = g
+	var timed *clock.Game
+	if len(control) > 0 {
+		timed = clock.NewGame(g, clock.New(control))
+		moves = timed
+	}
+
+	printGame(g, timed)
+	if playComputer(g, timed, computer, search, limits) {
+		return
+	}
 
 	scanner := bufio.NewScanner(os.Stdin)
 outer:
 	for scanner.Scan() {
+		// A player who ran out of time while thinking loses as soon as
+		// they enter anything.
+		if timed != nil && timed.CheckTime() {
+			printGame(g, timed)
+			break
+		}
 		text := strings.TrimSpace(scanner.Text())
 		switch text {
-		case "u":
-			if err := b.UndoMove(); err != nil {
+		case "u", "r":
+			step := moves.UndoMove
+			if text == "r" {
+				step = moves.RedoMove
+			}
+			if err := step(); err != nil {
 				fmt.Println(err)
 				continue
 			}
-			b.Print()
-			if hasCheck, color := b.HasCheck(); hasCheck {
-				if b.InCheckmate(color) {
-					fmt.Printf("%s is in checkmate\n", color)
-					break
-				}
-				fmt.Printf("%s is in check\n", color)
+			// When playing against the computer, step over the
+			// computer's moves too. If there's no move to step
+			// over, the computer makes a new move instead.
+			if b.Turn() == computer {
+				step()
 			}
-			if b.HasStalemate(b.Turn()) {
-				fmt.Println("stalemate")
-				break
+			printGame(g, timed)
+			if playComputer(g, timed, computer, search, limits) {
+				break outer
 			}
 			continue
 		case "p":
-			history := b.History()
+			history := b.HistorySAN()
 			if history != "" {
 				fmt.Println(history)
 			}
 			continue
+		case "eval":
+			score := ai.Evaluate(b)
+			if limits.Weights != nil {
+				score = limits.Weights.Evaluate(b)
+			}
+			fmt.Printf("evaluation: %+.2f\n", float64(score)/100)
+			continue
+		case "analyse":
+			printAnalysis("computer", ai.Search, b, limits)
+			if external != nil {
+				printAnalysis(external.Name, external.Analyse, b, limits)
+			}
+			continue
+		case "resign":
+			if err := g.Resign(b.Turn()); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			printGame(g, timed)
+			break outer
+		case "draw":
+			if err := g.OfferDraw(b.Turn()); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			if b.Turn()^1 == computer {
+				g.DeclineDraw(computer)
+				fmt.Println("the computer declines the draw offer")
+				continue
+			}
+			fmt.Printf("%s offers a draw (accept or decline)\n", b.Turn())
+			continue
+		case "accept", "claim":
+			accept := func() error { return answerDraw(g, true) }
+			if text == "claim" {
+				accept = g.ClaimDraw
+			}
+			if err := accept(); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			printGame(g, timed)
+			break outer
+		case "decline":
+			if err := answerDraw(g, false); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Println("draw offer declined")
+			continue
 		case "q":
 		inner:
 			for {
@@ -61,34 +254,46 @@ outer:
 			b.Print()
 			continue
 		}
+		if strings.HasPrefix(text, "perft ") {
+			depth, err := strconv.Atoi(strings.TrimSpace(text[len("perft "):]))
+			if err != nil || depth < 1 {
+				fmt.Println("usage: perft <depth> (e.g. perft 3)")
+				continue
+			}
+			printPerft(b, depth)
+			continue
+		}
 		var loc1, loc2 string
 		switch len(text) {
 		case 4:
 			loc1, loc2 = text[0:2], text[2:4]
-		case 5:
-			locations := strings.Split(strings.TrimSpace(scanner.Text()), " ")
+		case 5, 6:
+			locations := strings.Split(text, " ")
 			if len(locations) == 2 {
 				loc1, loc2 = locations[0], locations[1]
+			} else if len(text) == 5 {
+				// A pawn promotion, such as e7e8q.
+				loc1, loc2 = text[0:2], text[2:5]
 			}
 		}
-		if err := b.MoveByLocation(loc1, loc2); err != nil {
-			if err == engine.ErrInvalidLocation {
-				fmt.Println("allowed formats: l1 l2 or l1l2 (e.g. a2 a4 or a2a4)")
+		err := moves.MoveByLocation(loc1, loc2)
+		if err == engine.ErrInvalidLocation {
+			// If the text isn't a pair of locations, try it as SAN.
+			err = moves.MoveSAN(text)
+		}
+		if g.Over() {
+			printGame(g, timed)
+			break
+		}
+		if err != nil {
+			if errors.Is(err, engine.ErrInvalidSAN) {
+				fmt.Println("allowed formats: l1 l2, l1l2, l1l2p or SAN (e.g. a2 a4, a2a4, e7e8q or Nf3)")
 			} else {
 				fmt.Println(err)
 			}
 			continue
 		}
-		b.Print()
-		if hasCheck, color := b.HasCheck(); hasCheck {
-			if b.InCheckmate(color) {
-				fmt.Printf("%s is in checkmate\n", color)
-				break
-			}
-			fmt.Printf("%s is in check\n", color)
-		}
-		if b.HasStalemate(b.Turn()) {
-			fmt.Println("stalemate")
+		if printGame(g, timed) {
 			break
 		}
 		if mustPromote, _ := b.MustPromote(); mustPromote {
@@ -98,13 +303,13 @@ outer:
 				var err error
 				switch text {
 				case "k":
-					err = b.PromotePawn(engine.Knight)
+					err = moves.PromotePawn(engine.Knight)
 				case "r":
-					err = b.PromotePawn(engine.Rook)
+					err = moves.PromotePawn(engine.Rook)
 				case "b":
-					err = b.PromotePawn(engine.Bishop)
+					err = moves.PromotePawn(engine.Bishop)
 				case "q":
-					err = b.PromotePawn(engine.Queen)
+					err = moves.PromotePawn(engine.Queen)
 				default:
 					fmt.Println("invalid piece, please choose between: k, r, b, q")
 					continue
@@ -113,23 +318,162 @@ outer:
 					fmt.Println(err)
 					continue
 				}
-				b.Print()
-				if hasCheck, color := b.HasCheck(); hasCheck {
-					if b.InCheckmate(color) {
-						fmt.Printf("%s is in checkmate\n", color)
-						break outer
-					}
-					fmt.Printf("%s is in check\n", color)
-				}
-				if b.HasStalemate(b.Turn()) {
-					fmt.Println("stalemate")
+				if printGame(g, timed) {
 					break outer
 				}
 				break
 			}
 		}
+		if playComputer(g, timed, computer, search, limits) {
+			break
+		}
 	}
 	if err := scanner.Err(); err != nil {
 		log.Fatalln(err)
 	}
 }
+
+// A mover makes moves in a game, which is either an engine.Game or a
+// clock.Game for a timed game.
+type mover interface {
+	MoveByLocation(loc1, loc2 string) error
+	MoveSAN(san string) error
+	MoveWithPromotion(p1, p2 engine.Pos, promo engine.PieceName) error
+	PromotePawn(to engine.PieceName) error
+	UndoMove() error
+	RedoMove() error
+}
+
+// playComputer makes the computer's move if it's the computer's turn
+// and reports whether the game is over. In a timed game, timed isn't nil
+// and the computer doesn't think for longer than it's share of the time
+// left on it's clock.
+func playComputer(g *engine.Game, timed *clock.Game, computer engine.Color, search searchFunc, limits ai.Limits) bool {
+	if g.Over() || g.Turn() != computer {
+		return g.Over()
+	}
+	if mustPromote, _ := g.MustPromote(); mustPromote {
+		return false
+	}
+	var moves mover = g
+	if timed != nil {
+		moves = timed
+		if budget := timed.Clock.Budget(computer); limits.Time == 0 || budget < limits.Time {
+			limits.Time = budget
+		}
+	}
+	fmt.Println("the computer is thinking...")
+	result, err := search(g.Board, limits)
+	if err != nil {
+		fmt.Println(err)
+		return true
+	}
+	m := result.Move
+	if err := moves.MoveWithPromotion(m.From, m.To, m.Promotion); err != nil {
+		// The computer's flag can fall while it's thinking.
+		if g.Over() {
+			return printGame(g, timed)
+		}
+		fmt.Println(err)
+		return true
+	}
+	over := printGame(g, timed)
+	fmt.Printf("the computer played %s\n", m)
+	return over
+}
+
+// A searchFunc searches a board's position within limits for it's best
+// move, such as ai.Search or the Analyse method of a uci.Client.
+type searchFunc func(b *engine.Board, limits ai.Limits) (ai.Result, error)
+
+// printAnalysis prints the best move, score and principal variation
+// that search finds for board b's position, labelled with name.
+func printAnalysis(name string, search searchFunc, b *engine.Board, limits ai.Limits) {
+	result, err := search(b, limits)
+	if err != nil {
+		fmt.Printf("%s: %v\n", name, err)
+		return
+	}
+	score := fmt.Sprintf("%+.2f", float64(result.Score)/100)
+	if n, mate := result.MateIn(); mate {
+		score = fmt.Sprintf("mate %d", n)
+	}
+	pv := make([]string, len(result.PV))
+	for i, m := range result.PV {
+		pv[i] = m.String()
+	}
+	fmt.Printf("%s: %s (%s, depth %d) %s\n", name, result.Move, score,
+		result.Depth, strings.Join(pv, " "))
+}
+
+// answerDraw accepts or declines the open draw offer for the player that
+// it was offered to, who can answer it either before or after the
+// offering player's move, since both players share the terminal.
+func answerDraw(g *engine.Game, accept bool) error {
+	offered, color := g.DrawOffer()
+	if !offered {
+		return engine.ErrNoDrawOffer
+	}
+	if accept {
+		return g.AcceptDraw(color ^ 1)
+	}
+	return g.DeclineDraw(color ^ 1)
+}
+
+// printGame prints the game's board and the clocks of a timed game,
+// followed by any message about the game's status, and reports whether
+// the game is over.
+func printGame(g *engine.Game, timed *clock.Game) bool {
+	g.Print()
+	if timed != nil {
+		c := timed.Clock
+		fmt.Printf("white %s  black %s\n", formatClock(c.Remaining(engine.White)),
+			formatClock(c.Remaining(engine.Black)))
+	}
+	if msg := g.Message(); msg != "" {
+		fmt.Println(msg)
+	}
+	if g.Over() {
+		return true
+	}
+	if offered, color := g.DrawOffer(); offered {
+		fmt.Printf("%s offers a draw (accept or decline)\n", color)
+	}
+	if reason := g.Board.DrawReason(); reason.Claimable() {
+		fmt.Printf("%s can claim a draw by %s (claim)\n", g.Turn(), reason)
+	}
+	return false
+}
+
+// formatClock formats the time left on a clock as minutes and seconds,
+// with tenths of a second when there's less than 10 seconds left.
+func formatClock(d time.Duration) string {
+	if d < 10*time.Second {
+		return fmt.Sprintf("0:%04.1f", d.Seconds())
+	}
+	d = d.Truncate(time.Second)
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// printPerft prints the number of positions found after each legal move
+// up to depth moves ahead, followed by the total number of positions.
+func printPerft(b *engine.Board, depth int) {
+	divide := engine.PerftDivide(b, depth)
+	var total uint64
+	for _, m := range b.LegalMoves() {
+		fmt.Printf("%s: %d\n", m, divide[m])
+		total += divide[m]
+	}
+	fmt.Printf("\nnodes: %d\n", total)
+}
+
+// loadWeights loads the computer's evaluation weights from the JSON file
+// at path.
+func loadWeights(path string) (ai.Weights, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ai.Weights{}, err
+	}
+	defer f.Close()
+	return ai.LoadWeights(f)
+}
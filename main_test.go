@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/radovskyb/chess/engine"
+)
+
+func TestAnswerDraw(t *testing.T) {
+	testCases := []struct {
+		accept bool
+		// move is made by the offering player before the offer is
+		// answered, if it isn't empty.
+		move   string
+		status engine.Status
+	}{
+		{true, "", engine.StatusAgreement},
+		{true, "e4", engine.StatusAgreement},
+		{false, "", engine.StatusOngoing},
+		{false, "e4", engine.StatusOngoing},
+	}
+	for _, tc := range testCases {
+		g := engine.NewGame(engine.NewBoard())
+		if err := answerDraw(g, tc.accept); err != engine.ErrNoDrawOffer {
+			t.Errorf("expected error %v, got %v", engine.ErrNoDrawOffer, err)
+		}
+		if err := g.OfferDraw(g.Turn()); err != nil {
+			t.Fatal(err)
+		}
+		if tc.move != "" {
+			if err := g.MoveSAN(tc.move); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := answerDraw(g, tc.accept); err != nil {
+			t.Errorf("accept %t after %q: %v", tc.accept, tc.move, err)
+		}
+		if status, _ := g.Status(); status != tc.status {
+			t.Errorf("accept %t after %q: expected status %s, got %s",
+				tc.accept, tc.move, tc.status, status)
+		}
+		if offered, _ := g.DrawOffer(); offered {
+			t.Errorf("accept %t after %q: expected the offer to be answered", tc.accept, tc.move)
+		}
+	}
+}
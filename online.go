@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/radovskyb/chess/engine"
+	"github.com/radovskyb/chess/server"
+)
+
+// playOnline plays a game against another player through the server at
+// addr, reading the player's moves and commands from stdin.
+func playOnline(addr string) error {
+	fmt.Printf("connecting to %s and waiting for an opponent...\n", addr)
+	c, err := server.Dial(addr)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	fmt.Printf("you're playing %s\n", c.Color)
+	c.Board.Print()
+	printClocks(c)
+	printTurn(c)
+
+	// Read the player's input in the background.
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- strings.TrimSpace(scanner.Text())
+		}
+		close(lines)
+	}()
+
+	// Read the server's messages in the background too. They're only
+	// applied to the client's board below, so that the board is only
+	// used by this goroutine.
+	msgs := make(chan server.Message)
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := c.Read()
+			if err != nil {
+				errs <- err
+				return
+			}
+			msgs <- msg
+		}
+	}()
+
+	for {
+		select {
+		case text, ok := <-lines:
+			if !ok {
+				return nil
+			}
+			var err error
+			switch text {
+			case "":
+			case "p":
+				if history := c.Board.HistorySAN(); history != "" {
+					fmt.Println(history)
+				}
+			case "resign":
+				err = c.Resign()
+			default:
+				if c.Board.Turn() != c.Color {
+					fmt.Println("it's not your turn")
+					continue
+				}
+				// Moves are sent as e2e4 or SAN, so allow the
+				// same e2 e4 format as a local game too.
+				err = c.Move(strings.Replace(text, " ", "", -1))
+			}
+			if err != nil {
+				return err
+			}
+		case msg := <-msgs:
+			if err := c.Apply(msg); err != nil {
+				return err
+			}
+			switch msg.Type {
+			case server.TypeMove:
+				c.Board.Print()
+				printClocks(c)
+				if hasCheck, color := c.Board.HasCheck(); hasCheck {
+					fmt.Printf("%s is in check\n", color)
+				}
+				printTurn(c)
+			case server.TypeError:
+				fmt.Println(msg.Text)
+			case server.TypeEnd:
+				printClocks(c)
+				fmt.Printf("game over: %s\n", msg.Text)
+				return nil
+			}
+		case err := <-errs:
+			return err
+		}
+	}
+}
+
+// printClocks prints the time that each player had left when the server
+// sent it's last message, if the game is timed.
+func printClocks(c *server.Client) {
+	white, black := c.Time[engine.White], c.Time[engine.Black]
+	if white == 0 && black == 0 {
+		return
+	}
+	fmt.Printf("white %s  black %s\n", formatClock(white), formatClock(black))
+}
+
+// printTurn prints whose turn it is in an online game.
+func printTurn(c *server.Client) {
+	if c.Board.Turn() == c.Color {
+		fmt.Println("your move")
+		return
+	}
+	fmt.Printf("waiting for %s to move...\n", c.Color^1)
+}
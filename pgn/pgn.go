@@ -0,0 +1,369 @@
+// Package pgn reads and writes chess games in Portable Game Notation.
+package pgn
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/radovskyb/chess/engine"
+)
+
+var (
+	ErrUnterminatedTag     = errors.New("pgn: tag pair is not terminated")
+	ErrUnterminatedComment = errors.New("pgn: comment is not terminated")
+	ErrUnexpectedToken     = errors.New("pgn: unexpected token")
+)
+
+// Results that can end a game's movetext.
+const (
+	WhiteWins = "1-0"
+	BlackWins = "0-1"
+	Draw      = "1/2-1/2"
+	Unknown   = "*"
+)
+
+// A Tag is a PGN tag pair, such as [Event "Casual Game"].
+type Tag struct {
+	Name, Value string
+}
+
+// A Move is a single move from a game's movetext.
+type Move struct {
+	// SAN holds the move in Standard Algebraic Notation.
+	SAN string
+
+	// NAGs holds any Numeric Annotation Glyphs for the move.
+	NAGs []int
+
+	// Comment holds any comments that follow the move.
+	Comment string
+}
+
+// A Game is a chess game read from PGN.
+type Game struct {
+	// Tags holds the game's tag pairs in the order that they were read.
+	Tags []Tag
+
+	// Comment holds any comments that come before the first move.
+	Comment string
+
+	// Moves holds all of the moves from the game's movetext.
+	Moves []Move
+
+	// Result holds the game termination marker from the movetext.
+	Result string
+
+	// Board holds a board that all of the game's moves have been
+	// replayed on.
+	Board *engine.Board
+}
+
+// Tag returns the value of the tag pair with the specified name, or an
+// empty string if the game doesn't have a tag pair with that name.
+func (g *Game) Tag(name string) string {
+	for _, tag := range g.Tags {
+		if tag.Name == name {
+			return tag.Value
+		}
+	}
+	return ""
+}
+
+// tokenKind describes the kind of a token in PGN.
+type tokenKind uint8
+
+const (
+	tokenTag tokenKind = iota
+	tokenComment
+	tokenNAG
+	tokenSymbol
+	tokenResult
+)
+
+type token struct {
+	kind tokenKind
+	// name is only used by tag tokens.
+	name, value string
+}
+
+// A Reader reads games from PGN input.
+type Reader struct {
+	r *bufio.Reader
+
+	// pending holds a token that was read but belongs to the next game.
+	pending *token
+
+	// lineStart is set when the next rune starts a line, where a %
+	// escapes the rest of the line.
+	lineStart bool
+}
+
+// NewReader returns a new Reader that reads from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r), lineStart: true}
+}
+
+// ReadAll reads all of the games from r.
+func ReadAll(r io.Reader) ([]*Game, error) {
+	var games []*Game
+	pr := NewReader(r)
+	for {
+		g, err := pr.Next()
+		if err == io.EOF {
+			return games, nil
+		}
+		if err != nil {
+			return games, err
+		}
+		games = append(games, g)
+	}
+}
+
+// Next reads the next game and replays all of it's moves on a new
+// board. Next returns io.EOF when there are no more games to read.
+func (pr *Reader) Next() (*Game, error) {
+	g := &Game{Result: Unknown}
+	started, inMoves := false, false
+	for {
+		tok, err := pr.token()
+		if err == io.EOF {
+			if !started {
+				return nil, io.EOF
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		started = true
+
+		if tok.kind == tokenResult {
+			g.Result = tok.value
+			break
+		}
+
+		// A tag pair after the movetext has started belongs to
+		// the next game.
+		if tok.kind == tokenTag && inMoves {
+			pr.pending = &tok
+			break
+		}
+
+		switch tok.kind {
+		case tokenTag:
+			g.Tags = append(g.Tags, Tag{tok.name, tok.value})
+		case tokenComment:
+			comment := &g.Comment
+			if len(g.Moves) > 0 {
+				comment = &g.Moves[len(g.Moves)-1].Comment
+			}
+			if *comment != "" {
+				*comment += " "
+			}
+			*comment += tok.value
+		case tokenNAG:
+			if len(g.Moves) == 0 {
+				return nil, fmt.Errorf("%w: NAG $%s before any moves",
+					ErrUnexpectedToken, tok.value)
+			}
+			nag, err := strconv.Atoi(tok.value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid NAG $%s",
+					ErrUnexpectedToken, tok.value)
+			}
+			m := &g.Moves[len(g.Moves)-1]
+			m.NAGs = append(m.NAGs, nag)
+		case tokenSymbol:
+			inMoves = true
+			// Skip move numbers.
+			if strings.TrimRight(tok.value, "0123456789") == "" {
+				continue
+			}
+			san, nag := splitSuffixAnnotation(tok.value)
+			m := Move{SAN: san}
+			if nag != 0 {
+				m.NAGs = append(m.NAGs, nag)
+			}
+			g.Moves = append(g.Moves, m)
+		}
+	}
+
+	if err := g.replay(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// replay creates a new board for the game and makes all of the
+// game's moves on it.
+func (g *Game) replay() error {
+	b := engine.NewBoard()
+	if fen := g.Tag("FEN"); fen != "" {
+		var err error
+		if b, err = engine.ParseFEN(fen); err != nil {
+			return err
+		}
+	}
+	for i, m := range g.Moves {
+		if err := b.MoveSAN(m.SAN); err != nil {
+			return fmt.Errorf("pgn: move %d (%s): %w", i+1, m.SAN, err)
+		}
+	}
+	g.Board = b
+	return nil
+}
+
+// suffixAnnotations maps move suffix annotations to their NAGs.
+var suffixAnnotations = map[string]int{
+	"!": 1, "?": 2, "!!": 3, "??": 4, "!?": 5, "?!": 6,
+}
+
+// splitSuffixAnnotation splits a move into it's SAN and the NAG for
+// any suffix annotation at the end of it, such as !? for $5.
+func splitSuffixAnnotation(move string) (string, int) {
+	san := strings.TrimRight(move, "!?")
+	return san, suffixAnnotations[move[len(san):]]
+}
+
+// token reads the next token, skipping over any whitespace, move
+// number periods, escaped lines and recursive annotation variations.
+func (pr *Reader) token() (token, error) {
+	if pr.pending != nil {
+		tok := *pr.pending
+		pr.pending = nil
+		return tok, nil
+	}
+
+	for {
+		c, _, err := pr.r.ReadRune()
+		if err != nil {
+			return token{}, err
+		}
+		lineStart := pr.lineStart
+		pr.lineStart = c == '\n'
+
+		switch {
+		case unicode.IsSpace(c) || c == '.':
+			continue
+		case c == '%' && lineStart:
+			// Escaped lines are ignored.
+			if _, err := pr.r.ReadString('\n'); err != nil && err != io.EOF {
+				return token{}, err
+			}
+			pr.lineStart = true
+			continue
+		case c == '[':
+			return pr.tag()
+		case c == '{':
+			comment, err := pr.r.ReadString('}')
+			if err != nil {
+				return token{}, ErrUnterminatedComment
+			}
+			comment = strings.Join(strings.Fields(strings.TrimSuffix(comment, "}")), " ")
+			return token{kind: tokenComment, value: comment}, nil
+		case c == ';':
+			comment, err := pr.r.ReadString('\n')
+			if err != nil && err != io.EOF {
+				return token{}, err
+			}
+			pr.lineStart = true
+			return token{kind: tokenComment, value: strings.TrimSpace(comment)}, nil
+		case c == '(':
+			if err := pr.skipVariation(); err != nil {
+				return token{}, err
+			}
+			continue
+		case c == '$':
+			return token{kind: tokenNAG, value: pr.readWhile(unicode.IsDigit)}, nil
+		case isSymbolRune(c):
+			symbol := string(c) + pr.readWhile(isSymbolRune)
+			switch symbol {
+			case WhiteWins, BlackWins, Draw, Unknown:
+				return token{kind: tokenResult, value: symbol}, nil
+			}
+			return token{kind: tokenSymbol, value: symbol}, nil
+		default:
+			return token{}, fmt.Errorf("%w: %q", ErrUnexpectedToken, c)
+		}
+	}
+}
+
+// tag reads the rest of a tag pair after it's opening bracket.
+func (pr *Reader) tag() (token, error) {
+	line, err := pr.r.ReadString(']')
+	if err != nil {
+		return token{}, ErrUnterminatedTag
+	}
+	line = strings.TrimSpace(strings.TrimSuffix(line, "]"))
+
+	// A tag's value can contain a closing bracket, so keep reading
+	// until the value's closing quote is found.
+	for strings.Count(line, `"`)-strings.Count(line, `\"`) == 1 {
+		rest, err := pr.r.ReadString(']')
+		if err != nil {
+			return token{}, ErrUnterminatedTag
+		}
+		line += "]" + strings.TrimSuffix(rest, "]")
+	}
+
+	i := strings.IndexFunc(line, unicode.IsSpace)
+	if i < 0 {
+		return token{}, fmt.Errorf("%w: tag pair [%s]", ErrUnexpectedToken, line)
+	}
+	name, value := line[:i], strings.TrimSpace(line[i:])
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return token{}, fmt.Errorf("%w: tag pair [%s]", ErrUnexpectedToken, line)
+	}
+	value = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(value[1 : len(value)-1])
+	return token{kind: tokenTag, name: name, value: value}, nil
+}
+
+// skipVariation skips over a recursive annotation variation, including
+// any nested variations and comments inside of it.
+func (pr *Reader) skipVariation() error {
+	depth := 1
+	for depth > 0 {
+		c, _, err := pr.r.ReadRune()
+		if err != nil {
+			return fmt.Errorf("%w: variation is not terminated", ErrUnexpectedToken)
+		}
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '{':
+			if _, err := pr.r.ReadString('}'); err != nil {
+				return ErrUnterminatedComment
+			}
+		}
+	}
+	return nil
+}
+
+// readWhile reads runes for as long as f returns true for them.
+func (pr *Reader) readWhile(f func(rune) bool) string {
+	var sb strings.Builder
+	for {
+		c, _, err := pr.r.ReadRune()
+		if err != nil {
+			return sb.String()
+		}
+		if !f(c) {
+			pr.r.UnreadRune()
+			return sb.String()
+		}
+		sb.WriteRune(c)
+	}
+}
+
+// isSymbolRune reports whether c can be part of a PGN symbol token.
+func isSymbolRune(c rune) bool {
+	return c < unicode.MaxASCII && (unicode.IsLetter(c) || unicode.IsDigit(c) ||
+		strings.ContainsRune("_+#=:-/*!?", c))
+}
@@ -0,0 +1,248 @@
+package pgn
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/radovskyb/chess/engine"
+)
+
+const operaGame = `[Event "Paris"]
+[Site "Paris FRA"]
+[Date "1858.??.??"]
+[Round "?"]
+[White "Paul Morphy"]
+[Black "Duke Karl / Count Isouard"]
+[Result "1-0"]
+[ECO "C41"]
+
+1. e4 e5 2. Nf3 d6 3. d4 Bg4 {This is a weak move already.} 4. dxe5 Bxf3
+5. Qxf3 dxe5 6. Bc4 Nf6 7. Qb3 Qe7 8. Nc3 c6 9. Bg5 b5? (9... Qb4 10. Qxb4
+Bxb4) 10. Nxb5! cxb5 11. Bxb5+ Nbd7 12. O-O-O Rd8 13. Rxd7 Rxd7 14. Rd1 Qe6
+15. Bxd7+ Nxd7 16. Qb8+ $1 Nxb8 17. Rd8# 1-0
+`
+
+const setUpGame = `[Event "Endgame"]
+[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/4P3/4K3 b - - 0 40"]
+
+; Black moves first.
+40... Kd7 41. e4 Ke6 42. Ke2 *
+
+[Event "Second"]
+1. f3 e5 2. g4 Qh4# 0-1
+`
+
+func TestReadAll(t *testing.T) {
+	games, err := ReadAll(strings.NewReader(operaGame + "\n" + setUpGame))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 3 {
+		t.Fatalf("expected 3 games, got %d", len(games))
+	}
+
+	g := games[0]
+	if white := g.Tag("White"); white != "Paul Morphy" {
+		t.Errorf("expected white to be Paul Morphy, got %q", white)
+	}
+	if eco := g.Tag("ECO"); eco != "C41" {
+		t.Errorf("expected ECO to be C41, got %q", eco)
+	}
+	if len(g.Moves) != 33 {
+		t.Errorf("expected 33 moves, got %d", len(g.Moves))
+	}
+	if g.Result != WhiteWins {
+		t.Errorf("expected result to be %s, got %s", WhiteWins, g.Result)
+	}
+	if comment := g.Moves[5].Comment; comment != "This is a weak move already." {
+		t.Errorf("expected comment after Bg4, got %q", comment)
+	}
+	if nags := g.Moves[17].NAGs; len(nags) != 1 || nags[0] != 2 {
+		t.Errorf("expected b5? to have NAG $2, got %v", nags)
+	}
+	if nags := g.Moves[18].NAGs; g.Moves[18].SAN != "Nxb5" || len(nags) != 1 || nags[0] != 1 {
+		t.Errorf("expected Nxb5! to have NAG $1, got %s %v", g.Moves[18].SAN, nags)
+	}
+	if nags := g.Moves[30].NAGs; len(nags) != 1 || nags[0] != 1 {
+		t.Errorf("expected Qb8+ to have NAG $1, got %v", nags)
+	}
+	if !g.Board.InCheckmate(engine.Black) {
+		t.Error("expected black to be in checkmate")
+	}
+
+	g = games[1]
+	if g.Comment != "Black moves first." {
+		t.Errorf("expected game comment, got %q", g.Comment)
+	}
+	if g.Result != Unknown {
+		t.Errorf("expected result to be %s, got %s", Unknown, g.Result)
+	}
+	expected := "8/8/4k3/8/4P3/8/4K3/8 b - - 2 42"
+	if fen := g.Board.FEN(); fen != expected {
+		t.Errorf("expected FEN to be %q, got %q", expected, fen)
+	}
+
+	g = games[2]
+	if event := g.Tag("Event"); event != "Second" {
+		t.Errorf("expected event to be Second, got %q", event)
+	}
+	if g.Result != BlackWins {
+		t.Errorf("expected result to be %s, got %s", BlackWins, g.Result)
+	}
+}
+
+func TestReaderNext(t *testing.T) {
+	r := NewReader(strings.NewReader(setUpGame))
+	for i := 0; i < 2; i++ {
+		if _, err := r.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF error, got %v", err)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	testCases := []struct {
+		pgn string
+		err error
+	}{
+		{`[Event "Unterminated`, ErrUnterminatedTag},
+		{`1. e4 {unterminated comment`, ErrUnterminatedComment},
+		{`1. e4 e5 2. Ke3 *`, engine.ErrInvalidPieceMove},
+		{`1. e4 e5 2. Nf9 *`, engine.ErrInvalidSAN},
+		{"[FEN \"8/8/8 w - - 0 1\"]\n\n*", engine.ErrInvalidFEN},
+		{`1. e4 & *`, ErrUnexpectedToken},
+		// A % only escapes a line at the start of it.
+		{"1. e4%e5 *", ErrUnexpectedToken},
+	}
+	for _, tc := range testCases {
+		if _, err := ReadAll(strings.NewReader(tc.pgn)); !errors.Is(err, tc.err) {
+			t.Errorf("expected reading %q to fail with %v, got %v",
+				tc.pgn, tc.err, err)
+		}
+	}
+}
+
+func TestReadEscapedLines(t *testing.T) {
+	testCases := []string{
+		"% escaped\n1. e4 e5 *",
+		"1. e4\n% escaped e4\ne5 *",
+		"1. e4 ; comment\n% escaped\ne5 *",
+	}
+	for _, pgn := range testCases {
+		games, err := ReadAll(strings.NewReader(pgn))
+		if err != nil {
+			t.Errorf("%q: %v", pgn, err)
+			continue
+		}
+		if len(games) != 1 || len(games[0].Moves) != 2 {
+			t.Errorf("%q: expected 1 game with 2 moves, got %+v", pgn, games)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	games, err := ReadAll(strings.NewReader(operaGame))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	tags := []Tag{{"ECO", "C41"}, {"White", "Paul Morphy"}, {"Event", "Paris"}}
+	if err := Write(&buf, games[0].Board, tags...); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `[Event "Paris"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "Paul Morphy"]
+[Black "?"]
+[Result "1-0"]
+[ECO "C41"]
+
+1. e4 e5 2. Nf3 d6 3. d4 Bg4 4. dxe5 Bxf3 5. Qxf3 dxe5 6. Bc4 Nf6 7. Qb3 Qe7
+8. Nc3 c6 9. Bg5 b5 10. Nxb5 cxb5 11. Bxb5+ Nbd7 12. O-O-O Rd8 13. Rxd7 Rxd7
+14. Rd1 Qe6 15. Bxd7+ Nxd7 16. Qb8+ Nxb8 17. Rd8# 1-0
+
+`
+	if buf.String() != expected {
+		t.Errorf("expected PGN to be:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestWriteGameResult(t *testing.T) {
+	testCases := []struct {
+		end    func(g *engine.Game) error
+		result string
+	}{
+		{func(g *engine.Game) error { return nil }, Unknown},
+		{func(g *engine.Game) error { return g.Resign(engine.White) }, BlackWins},
+		{func(g *engine.Game) error { return g.Timeout(engine.Black) }, WhiteWins},
+		{func(g *engine.Game) error {
+			if err := g.OfferDraw(engine.White); err != nil {
+				return err
+			}
+			return g.AcceptDraw(engine.Black)
+		}, Draw},
+	}
+	for _, tc := range testCases {
+		g := engine.NewGame(engine.NewBoard())
+		if err := g.MoveSAN("e4"); err != nil {
+			t.Fatal(err)
+		}
+		if err := tc.end(g); err != nil {
+			t.Fatal(err)
+		}
+		if result := GameResult(g); result != tc.result {
+			t.Errorf("expected result %s, got %s", tc.result, result)
+		}
+
+		var buf bytes.Buffer
+		if err := Write(&buf, g.Board, Tag{"Result", GameResult(g)}); err != nil {
+			t.Fatal(err)
+		}
+		if pgn := buf.String(); !strings.Contains(pgn, fmt.Sprintf("[Result %q]", tc.result)) ||
+			!strings.HasSuffix(pgn, "1. e4 "+tc.result+"\n\n") {
+			t.Errorf("expected the PGN to end with %s, got %q", tc.result, pgn)
+		}
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	games, err := ReadAll(strings.NewReader(setUpGame))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, g := range games {
+		var buf bytes.Buffer
+		if err := Write(&buf, g.Board, g.Tags...); err != nil {
+			t.Fatal(err)
+		}
+		read, err := ReadAll(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(read) != 1 {
+			t.Fatalf("expected 1 game, got %d", len(read))
+		}
+		if fen, readFEN := g.Board.FEN(), read[0].Board.FEN(); fen != readFEN {
+			t.Errorf("expected FEN to be %q, got %q", fen, readFEN)
+		}
+		if read[0].Result != g.Result {
+			t.Errorf("expected result to be %s, got %s", g.Result, read[0].Result)
+		}
+		if event := read[0].Tag("Event"); event != g.Tag("Event") {
+			t.Errorf("expected event to be %q, got %q", g.Tag("Event"), event)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package pgn
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/radovskyb/chess/engine"
+)
+
+// maxLineLen is the maximum length of a line of movetext.
+const maxLineLen = 79
+
+// sevenTagRoster holds the tags that every PGN game must have, in
+// order, along with their default values.
+var sevenTagRoster = []Tag{
+	{"Event", "?"},
+	{"Site", "?"},
+	{"Date", "????.??.??"},
+	{"Round", "?"},
+	{"White", "?"},
+	{"Black", "?"},
+	{"Result", ""},
+}
+
+// Write writes all of the moves made on b to w as a PGN game.
+//
+// Any of the Seven Tag Roster's tags that aren't found in tags are
+// written with unknown values, except for the Result tag which is
+// worked out from the board if it's not specified. A game that ended
+// off the board, such as by a resignation, needs it's Result tag from
+// GameResult.
+func Write(w io.Writer, b *engine.Board, tags ...Tag) error {
+	values := make(map[string]string)
+	for _, tag := range tags {
+		values[tag.Name] = tag.Value
+	}
+	if values["Result"] == "" {
+		values["Result"] = GameResult(engine.NewGame(b))
+	}
+
+	var sb strings.Builder
+
+	// The Seven Tag Roster comes first.
+	for _, tag := range sevenTagRoster {
+		value, found := values[tag.Name]
+		if !found {
+			value = tag.Value
+		}
+		writeTag(&sb, tag.Name, value)
+	}
+
+	// Games that don't start from the standard starting position
+	// need their starting position.
+	if fen := b.InitialFEN(); fen != engine.StartFEN {
+		writeTag(&sb, "SetUp", "1")
+		writeTag(&sb, "FEN", fen)
+	}
+
+	// Then any other tags in the order that they were specified.
+	for _, tag := range tags {
+		if isRosterTag(tag.Name) || tag.Name == "SetUp" || tag.Name == "FEN" {
+			continue
+		}
+		writeTag(&sb, tag.Name, tag.Value)
+	}
+	sb.WriteByte('\n')
+
+	// Movetext, wrapped so no lines are longer than maxLineLen, while
+	// keeping move numbers on the same line as their moves.
+	var toks []string
+	for _, tok := range strings.Fields(b.HistorySAN()) {
+		if n := len(toks); n > 0 && strings.HasSuffix(toks[n-1], ".") {
+			toks[n-1] += " " + tok
+			continue
+		}
+		toks = append(toks, tok)
+	}
+	lineLen := 0
+	for _, tok := range append(toks, values["Result"]) {
+		if lineLen > 0 && lineLen+1+len(tok) > maxLineLen {
+			sb.WriteByte('\n')
+			lineLen = 0
+		}
+		if lineLen > 0 {
+			sb.WriteByte(' ')
+			lineLen++
+		}
+		sb.WriteString(tok)
+		lineLen += len(tok)
+	}
+	sb.WriteString("\n\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// GameResult returns the result of g, including a result from the game
+// ending off the board such as by a timeout, or Unknown if the game
+// hasn't ended.
+func GameResult(g *engine.Game) string {
+	switch status, winner := g.Status(); {
+	case status == engine.StatusOngoing:
+		return Unknown
+	case winner == engine.White:
+		return WhiteWins
+	case winner == engine.Black:
+		return BlackWins
+	}
+	return Draw
+}
+
+// writeTag writes a tag pair, escaping any quotes or backslashes in
+// the tag's value.
+func writeTag(sb *strings.Builder, name, value string) {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+	fmt.Fprintf(sb, "[%s \"%s\"]\n", name, value)
+}
+
+// isRosterTag reports whether name is one of the Seven Tag Roster's tags.
+func isRosterTag(name string) bool {
+	for _, tag := range sevenTagRoster {
+		if tag.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/radovskyb/chess/engine"
+)
+
+// ErrOutOfSync is returned when a move from the server leaves the
+// client's board in a different position to the server's board.
+var ErrOutOfSync = errors.New("server: board is out of sync with the server")
+
+// A Client is a player's connection to a game on a server.
+type Client struct {
+	// Color holds the color that the client plays.
+	Color engine.Color
+
+	// Board holds the client's copy of the game's board, which is kept
+	// up to date with the moves from the server.
+	Board *engine.Board
+
+	// Time holds the time that each player had left on their clock in a
+	// timed game when the server sent it's last start, move or end
+	// message.
+	Time [2]time.Duration
+
+	conn    io.ReadWriteCloser
+	scanner *bufio.Scanner
+	enc     *json.Encoder
+}
+
+// Dial connects to the server at addr and waits for a game to start.
+func Dial(addr string) (*Client, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn)
+}
+
+// NewClient creates a client that's connected to a server by conn and
+// waits for a game to start.
+func NewClient(conn io.ReadWriteCloser) (*Client, error) {
+	c := &Client{
+		conn:    conn,
+		scanner: bufio.NewScanner(conn),
+		enc:     json.NewEncoder(conn),
+	}
+	msg, err := c.Read()
+	if err == nil && msg.Type != TypeStart {
+		err = fmt.Errorf("server: expected a start message, got %q", msg.Type)
+	}
+	if err == nil {
+		switch msg.Color {
+		case "white":
+			c.Color = engine.White
+		case "black":
+			c.Color = engine.Black
+		default:
+			err = fmt.Errorf("server: invalid color %q", msg.Color)
+		}
+	}
+	if err == nil {
+		c.Board, err = engine.ParseFEN(msg.FEN)
+		c.setTime(msg)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Read reads the next message from the server without applying it, so
+// that it can be read on one goroutine and applied with Apply on the
+// goroutine that uses the client's board.
+func (c *Client) Read() (Message, error) {
+	if !c.scanner.Scan() {
+		if err := c.scanner.Err(); err != nil {
+			return Message{}, err
+		}
+		return Message{}, io.EOF
+	}
+	var msg Message
+	err := json.Unmarshal(c.scanner.Bytes(), &msg)
+	return msg, err
+}
+
+// Next reads the next message from the server. If it's a move, the move
+// is made on the client's board before the message is returned, and the
+// clocks are updated from move and end messages.
+func (c *Client) Next() (Message, error) {
+	msg, err := c.Read()
+	if err != nil {
+		return msg, err
+	}
+	return msg, c.Apply(msg)
+}
+
+// Apply applies a message that was read with Read to the client. A move
+// is made on the client's board, and the clocks are updated from move
+// and end messages. Other messages are ignored.
+func (c *Client) Apply(msg Message) error {
+	if msg.Type != TypeMove && msg.Type != TypeEnd {
+		return nil
+	}
+	c.setTime(msg)
+	if msg.Type == TypeEnd {
+		return nil
+	}
+
+	var m engine.MoveInfo
+	if err := m.Decode(msg.MoveInfo); err != nil {
+		return err
+	}
+	promo := engine.Pawn
+	if m.Promotion != nil {
+		promo = m.Promotion.Name
+	}
+	if err := c.Board.MoveWithPromotion(m.From, m.To, promo); err != nil {
+		return fmt.Errorf("%w: %v", ErrOutOfSync, err)
+	}
+	if c.Board.FEN() != msg.FEN {
+		return ErrOutOfSync
+	}
+	return nil
+}
+
+// setTime sets the time left on each player's clock from msg.
+func (c *Client) setTime(msg Message) {
+	c.Time[engine.White] = time.Duration(msg.WhiteTime) * time.Millisecond
+	c.Time[engine.Black] = time.Duration(msg.BlackTime) * time.Millisecond
+}
+
+// Move sends a move in coordinate notation or SAN to the server. The
+// move isn't made on the client's board until the server sends it back.
+func (c *Client) Move(move string) error {
+	return c.enc.Encode(Message{Type: TypeMove, Move: move})
+}
+
+// Resign resigns the game.
+func (c *Client) Resign() error {
+	return c.enc.Encode(Message{Type: TypeResign})
+}
+
+// Close closes the connection to the server.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/radovskyb/chess/engine"
+)
+
+// dialPair connects a white and a black client to the server at addr.
+func dialPair(t *testing.T, addr string) (white, black *Client) {
+	t.Helper()
+
+	// A client isn't created until the game starts, so white has to
+	// wait for black in the background after connecting first.
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error)
+	go func() {
+		var err error
+		white, err = NewClient(conn)
+		errs <- err
+	}()
+	black, err = Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		white.Close()
+		black.Close()
+	})
+	return white, black
+}
+
+func TestClient(t *testing.T) {
+	white, black := dialPair(t, startServer(t, nil))
+	if white.Color != engine.White || black.Color != engine.Black {
+		t.Fatalf("expected white and black, got %s and %s", white.Color, black.Color)
+	}
+
+	// Fool's mate.
+	for i, move := range []string{"f2f3", "e5", "g4", "d8h4"} {
+		c := white
+		if i%2 == 1 {
+			c = black
+		}
+		if err := c.Move(move); err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range []*Client{white, black} {
+			msg, err := c.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Type != TypeMove || c.Board.FEN() != msg.FEN {
+				t.Fatalf("expected board to be %s, got %s", msg.FEN, c.Board.FEN())
+			}
+			if i < 3 && len(msg.Legal) != len(c.Board.LegalMoves()) {
+				t.Errorf("expected %d legal moves, got %q", len(c.Board.LegalMoves()), msg.Legal)
+			}
+			if i == 3 && (msg.Status != "checkmate" || msg.Legal != nil ||
+				msg.Text != "white is in checkmate") {
+				t.Errorf("expected checkmate after the last move, got %+v", msg)
+			}
+		}
+	}
+
+	for _, c := range []*Client{white, black} {
+		msg, err := c.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Type != TypeEnd || msg.Status != "checkmate" || msg.Color != "black" {
+			t.Errorf("expected black to checkmate, got %+v", msg)
+		}
+		if !c.Board.InCheckmate(engine.White) {
+			t.Errorf("expected client's board to be checkmate")
+		}
+	}
+}
+
+func TestClientPromotion(t *testing.T) {
+	white, black := dialPair(t, startServer(t, nil))
+
+	moves := []string{"h4", "g5", "hxg5", "Nf6", "gxf6", "Rg8", "fxe7", "Rh8", "exd8=N"}
+	for i, move := range moves {
+		c := white
+		if i%2 == 1 {
+			c = black
+		}
+		if err := c.Move(move); err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range []*Client{white, black} {
+			if _, err := c.Next(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for _, c := range []*Client{white, black} {
+		p, err := c.Board.GetPieceAt("d8")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Name != engine.Knight || p.Color != engine.White {
+			t.Errorf("expected a white knight on d8, got %s", p)
+		}
+	}
+}
+
+func TestClientReadApply(t *testing.T) {
+	white, black := dialPair(t, startServer(t, nil))
+	if err := white.Move("e4"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading a move doesn't make it until it's applied.
+	msg, err := black.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fen := black.Board.FEN(); fen != engine.StartFEN {
+		t.Errorf("expected the starting position before applying the move, got %s", fen)
+	}
+	if err := black.Apply(msg); err != nil {
+		t.Fatal(err)
+	}
+	if fen := black.Board.FEN(); fen != msg.FEN {
+		t.Errorf("expected board to be %s, got %s", msg.FEN, fen)
+	}
+
+	// Applying the same move again leaves the board out of sync.
+	if err := black.Apply(msg); !errors.Is(err, ErrOutOfSync) {
+		t.Errorf("expected error %v, got %v", ErrOutOfSync, err)
+	}
+}
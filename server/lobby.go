@@ -0,0 +1,145 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/radovskyb/chess/clock"
+)
+
+// maxBuffered is the most that's read from a player's connection before
+// the game reads it, so that a player waiting for an opponent can't use
+// up the server's memory.
+const maxBuffered = 1 << 16
+
+// errBufferFull stops the reads from a player who sent more than
+// maxBuffered before the game read it.
+var errBufferFull = errors.New("server: too much sent before the game started")
+
+// A Lobby pairs up players' connections into games with a time control,
+// where the first player of each pair plays white. A player that
+// disconnects while waiting for an opponent is dropped, so that the next
+// player isn't paired with them.
+type Lobby struct {
+	control clock.Control
+
+	mu      sync.Mutex // Guards waiting.
+	waiting *waiter    // The player waiting for an opponent, or nil.
+}
+
+// NewLobby creates a Lobby that plays games with the time control, or
+// untimed games if the control doesn't have any stages.
+func NewLobby(control clock.Control) *Lobby {
+	return &Lobby{control: control}
+}
+
+// Serve accepts connections from ln and joins them to the lobby. Serve
+// returns when ln stops accepting connections.
+func (l *Lobby) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		l.Join(conn)
+	}
+}
+
+// Join starts a game between the player connected to conn and the player
+// that's waiting for an opponent, or leaves conn waiting for one.
+func (l *Lobby) Join(conn io.ReadWriteCloser) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// The waiting player may have disconnected without being dropped
+	// yet.
+	if l.waiting != nil && l.waiting.disconnected() {
+		l.waiting.Close()
+		l.waiting = nil
+	}
+	if l.waiting == nil {
+		w := newWaiter(conn)
+		l.waiting = w
+		go l.watch(w)
+		return
+	}
+	// Only the waiting player needs watching, since the game notices
+	// either player disconnecting once it starts.
+	go Play(l.waiting, conn, l.control)
+	l.waiting = nil
+}
+
+// watch reads from the player's connection in the background for as
+// long as it's open, so that a player who disconnects while waiting for
+// an opponent is noticed and dropped, even after they've sent something.
+func (l *Lobby) watch(w *waiter) {
+	buf := make([]byte, 512)
+	for {
+		n, err := w.ReadWriteCloser.Read(buf)
+		w.mu.Lock()
+		w.buf = append(w.buf, buf[:n]...)
+		if err == nil && len(w.buf) > maxBuffered {
+			err = errBufferFull
+		}
+		w.err = err
+		w.cond.Broadcast()
+		w.mu.Unlock()
+		if err != nil {
+			break
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.waiting == w {
+		l.waiting = nil
+		w.Close()
+	}
+}
+
+// A waiter is the connection of a player who joined a Lobby. It's read
+// from in the background by the lobby, and what's been read is returned
+// by it's reads once the game starts.
+type waiter struct {
+	io.ReadWriteCloser
+
+	mu   sync.Mutex
+	cond *sync.Cond // Signalled when buf or err change.
+
+	// buf holds what's been read but not returned by Read yet, and err
+	// holds the error that stopped the background reads.
+	buf []byte
+	err error
+}
+
+// newWaiter creates a waiter for the player connected to conn.
+func newWaiter(conn io.ReadWriteCloser) *waiter {
+	w := &waiter{ReadWriteCloser: conn}
+	w.cond = sync.NewCond(&w.mu)
+	return w
+}
+
+// Read reads what's been read from the connection in the background,
+// waiting for more if there isn't anything yet.
+func (w *waiter) Read(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for len(w.buf) == 0 && w.err == nil {
+		w.cond.Wait()
+	}
+	if len(w.buf) > 0 {
+		n := copy(p, w.buf)
+		w.buf = w.buf[n:]
+		return n, nil
+	}
+	return 0, w.err
+}
+
+// disconnected reports whether the background reads have found that the
+// player disconnected.
+func (w *waiter) disconnected() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err != nil
+}
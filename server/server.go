@@ -0,0 +1,289 @@
+// Package server lets two players play a game of chess against each
+// other over a network. The server owns the game's board and checks every
+// move, and the players' clients mirror the board from the moves that the
+// server sends them.
+//
+// The server and it's clients send each other Messages encoded as JSON,
+// one per line.
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/radovskyb/chess/clock"
+	"github.com/radovskyb/chess/engine"
+)
+
+// DefaultAddr is the address that the server listens on when no address
+// is given.
+const DefaultAddr = ":7000"
+
+// The types of Messages.
+const (
+	// TypeStart is sent by the server to each client when the game
+	// starts, with the Color that the client plays, the FEN of the
+	// starting position and the Legal moves.
+	TypeStart = "start"
+
+	// TypeMove is sent by a client with the Move that it wants to make,
+	// and by the server to both clients with the MoveInfo, FEN, Legal
+	// moves and Status after each move that's made.
+	TypeMove = "move"
+
+	// TypeResign is sent by a client to resign the game.
+	TypeResign = "resign"
+
+	// TypeEnd is sent by the server to both clients when the game ends,
+	// with the game's Status, the Color of the winner, if there is one,
+	// and a Text describing how the game ended.
+	TypeEnd = "end"
+
+	// TypeError is sent by the server to a client when it can't do what
+	// the client asked, such as making an illegal move, with the error
+	// in Text.
+	TypeError = "error"
+)
+
+// A Message is sent between the server and it's clients.
+type Message struct {
+	Type string `json:"type"`
+
+	// Move holds a move sent by a client in coordinate notation, such
+	// as e2e4, or in SAN, such as Nf3.
+	Move string `json:"move,omitempty"`
+
+	// MoveInfo holds a move that the server made on the board, encoded
+	// with engine.MoveInfo's Encode method.
+	MoveInfo json.RawMessage `json:"move_info,omitempty"`
+
+	// FEN holds the position on the server's board.
+	FEN string `json:"fen,omitempty"`
+
+	// Legal holds the legal moves for the side to move in coordinate
+	// notation.
+	Legal []string `json:"legal,omitempty"`
+
+	// Color holds the color that the client plays in a start message,
+	// or the color of the winner in an end message.
+	Color string `json:"color,omitempty"`
+
+	// Status holds the game's status, such as ongoing or checkmate.
+	Status string `json:"status,omitempty"`
+
+	// Text holds a message about the game, such as white is in check,
+	// or an error.
+	Text string `json:"text,omitempty"`
+
+	// WhiteTime and BlackTime hold the time in milliseconds that each
+	// player has left in a timed game, in start, move and end messages.
+	WhiteTime int64 `json:"white_time,omitempty"`
+	BlackTime int64 `json:"black_time,omitempty"`
+}
+
+// ErrNotYourTurn is sent to a client that sends a move when it isn't
+// it's turn.
+var ErrNotYourTurn = errors.New("server: it's not your turn")
+
+// Serve accepts connections from l and pairs them up into games with the
+// time control in a Lobby, where the first player of each pair plays
+// white. Serve returns when l stops accepting connections.
+func Serve(l net.Listener, control clock.Control) error {
+	return NewLobby(control).Serve(l)
+}
+
+// A clientMessage is a message read from the client playing color, or
+// the error that stopped the message from being read.
+type clientMessage struct {
+	color engine.Color
+	msg   Message
+	err   error
+}
+
+// A mover makes moves on a game's board, which is either an engine.Game
+// or a clock.Game in a timed game.
+type mover interface {
+	MoveWithPromotion(p1, p2 engine.Pos, promo engine.PieceName) error
+	MoveSAN(san string) error
+}
+
+// Play plays a game between the clients connected to white and black
+// until it ends, and then closes both connections and returns the game.
+// A client that disconnects during the game resigns.
+//
+// The game is played with the time control, or untimed if the control
+// doesn't have any stages. A client that runs out of time loses as soon
+// as their flag falls, without having to try to move.
+func Play(white, black io.ReadWriteCloser, control clock.Control) *engine.Game {
+	g := engine.NewGame(engine.NewBoard())
+	var timed *clock.Game
+	var moves mover = g
+	if len(control) > 0 {
+		timed = clock.NewGame(g, clock.New(control))
+		moves = timed
+	}
+	conns := [2]io.ReadWriteCloser{engine.White: white, engine.Black: black}
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	// Read the messages from both clients in the background.
+	msgs := make(chan clientMessage)
+	done := make(chan struct{})
+	defer close(done)
+	for color, conn := range conns {
+		go readMessages(engine.Color(color), conn, msgs, done)
+	}
+
+	var encs [2]*json.Encoder
+	for color, conn := range conns {
+		encs[color] = json.NewEncoder(conn)
+	}
+	// Errors writing to a client are ignored, since the client's
+	// disconnect is noticed when reading from it.
+	broadcast := func(msg Message) {
+		for _, enc := range encs {
+			enc.Encode(msg)
+		}
+	}
+
+	for color, enc := range encs {
+		msg := position(g, timed)
+		msg.Type, msg.Color = TypeStart, engine.Color(color).String()
+		enc.Encode(msg)
+	}
+
+	// flag fires when the side to move runs out of time in a timed game.
+	var flag <-chan time.Time
+	var timer *time.Timer
+	if timed != nil {
+		timer = time.NewTimer(0)
+		defer timer.Stop()
+		flag = timer.C
+	}
+
+	for !g.Over() {
+		if timer != nil {
+			timer.Reset(timed.Clock.Remaining(g.Turn()))
+		}
+		var cm clientMessage
+		select {
+		case cm = <-msgs:
+		case <-flag:
+			timed.CheckTime()
+			continue
+		}
+		if cm.err != nil {
+			g.Resign(cm.color)
+			break
+		}
+
+		var err error
+		switch cm.msg.Type {
+		case TypeMove:
+			var msg Message
+			if msg, err = move(g, moves, timed, cm.color, cm.msg.Move); err == nil {
+				broadcast(msg)
+			}
+		case TypeResign:
+			err = g.Resign(cm.color)
+		default:
+			err = fmt.Errorf("server: invalid message type %q", cm.msg.Type)
+		}
+		if err != nil {
+			encs[cm.color].Encode(Message{Type: TypeError, Text: err.Error()})
+		}
+	}
+
+	status, winner := g.Status()
+	end := Message{Type: TypeEnd, Status: status.String(), Text: g.Message()}
+	if timed != nil {
+		end.WhiteTime = timed.Clock.Remaining(engine.White).Milliseconds()
+		end.BlackTime = timed.Clock.Remaining(engine.Black).Milliseconds()
+	}
+	if winner == engine.White || winner == engine.Black {
+		end.Color = winner.String()
+	}
+	broadcast(end)
+	return g
+}
+
+// readMessages reads messages from the client playing color and sends
+// them to msgs until it can't read any more, or done is closed.
+func readMessages(color engine.Color, r io.Reader, msgs chan<- clientMessage, done <-chan struct{}) {
+	scanner := bufio.NewScanner(r)
+	for {
+		cm := clientMessage{color: color}
+		if scanner.Scan() {
+			// A message that isn't valid JSON is left without a
+			// type, so that it's rejected like any other invalid
+			// message.
+			json.Unmarshal(scanner.Bytes(), &cm.msg)
+		} else if cm.err = scanner.Err(); cm.err == nil {
+			cm.err = io.EOF
+		}
+		select {
+		case msgs <- cm:
+		case <-done:
+			return
+		}
+		if cm.err != nil {
+			return
+		}
+	}
+}
+
+// move makes the move s in coordinate notation or SAN for color on the
+// game's board with moves, and returns the message to send to both
+// clients about it.
+func move(g *engine.Game, moves mover, timed *clock.Game, color engine.Color, s string) (Message, error) {
+	if g.Turn() != color {
+		return Message{}, ErrNotYourTurn
+	}
+	var err error
+	if m, perr := engine.ParseMove(s); perr == nil {
+		err = moves.MoveWithPromotion(m.From, m.To, m.Promotion)
+	} else {
+		err = moves.MoveSAN(s)
+	}
+	if err != nil {
+		return Message{}, err
+	}
+
+	m, err := g.LastMove()
+	if err != nil {
+		return Message{}, err
+	}
+	data, err := m.Encode()
+	if err != nil {
+		return Message{}, err
+	}
+	msg := position(g, timed)
+	msg.Type, msg.MoveInfo = TypeMove, data
+	return msg, nil
+}
+
+// position returns a message with the FEN, legal moves, status and
+// status message of the game's current position, and the time left on
+// each player's clock if timed isn't nil.
+func position(g *engine.Game, timed *clock.Game) Message {
+	status, _ := g.Status()
+	msg := Message{FEN: g.FEN(), Status: status.String(), Text: g.Message()}
+	if status == engine.StatusOngoing {
+		for _, m := range g.LegalMoves() {
+			msg.Legal = append(msg.Legal, m.String())
+		}
+	}
+	if timed != nil {
+		msg.WhiteTime = timed.Clock.Remaining(engine.White).Milliseconds()
+		msg.BlackTime = timed.Clock.Remaining(engine.Black).Milliseconds()
+	}
+	return msg
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/radovskyb/chess/clock"
+	"github.com/radovskyb/chess/engine"
+)
+
+// startServer starts a server for games with the time control on a free
+// local port and returns it's address. The server is stopped when the
+// test finishes.
+func startServer(t *testing.T, control clock.Control) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go Serve(l, control)
+	return l.Addr().String()
+}
+
+// A testConn speaks the server's protocol directly over a connection.
+type testConn struct {
+	t       *testing.T
+	conn    net.Conn
+	scanner *bufio.Scanner
+}
+
+func dialTest(t *testing.T, addr string) *testConn {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testConn{t: t, conn: conn, scanner: bufio.NewScanner(conn)}
+}
+
+func (c *testConn) send(msg Message) {
+	c.t.Helper()
+	if err := json.NewEncoder(c.conn).Encode(msg); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// expect reads the next message and checks that it's of type typ.
+func (c *testConn) expect(typ string) Message {
+	c.t.Helper()
+	if !c.scanner.Scan() {
+		c.t.Fatalf("expected a %s message, got %v", typ, c.scanner.Err())
+	}
+	var msg Message
+	if err := json.Unmarshal(c.scanner.Bytes(), &msg); err != nil {
+		c.t.Fatal(err)
+	}
+	if msg.Type != typ {
+		c.t.Fatalf("expected a %s message, got %+v", typ, msg)
+	}
+	return msg
+}
+
+func TestPlay(t *testing.T) {
+	addr := startServer(t, nil)
+	white, black := dialTest(t, addr), dialTest(t, addr)
+
+	for color, c := range map[string]*testConn{"white": white, "black": black} {
+		msg := c.expect(TypeStart)
+		if msg.Color != color || msg.FEN != engine.NewBoard().FEN() ||
+			msg.Status != "ongoing" || len(msg.Legal) != 20 {
+			t.Errorf("expected %s to start from the initial position, got %+v", color, msg)
+		}
+	}
+
+	// Black can't move first, and white can't make an illegal move.
+	black.send(Message{Type: TypeMove, Move: "e7e5"})
+	if msg := black.expect(TypeError); msg.Text != ErrNotYourTurn.Error() {
+		t.Errorf("expected error %q, got %q", ErrNotYourTurn, msg.Text)
+	}
+	white.send(Message{Type: TypeMove, Move: "e2e5"})
+	white.expect(TypeError)
+	white.send(Message{Type: "draw"})
+	white.expect(TypeError)
+
+	// Both sides are sent each move, in coordinate notation or SAN.
+	for i, move := range []string{"e2e4", "e5"} {
+		c := white
+		if i%2 == 1 {
+			c = black
+		}
+		c.send(Message{Type: TypeMove, Move: move})
+		for _, c := range []*testConn{white, black} {
+			msg := c.expect(TypeMove)
+			var m engine.MoveInfo
+			if err := m.Decode(msg.MoveInfo); err != nil {
+				t.Fatal(err)
+			}
+			if m.SAN != []string{"e4", "e5"}[i] {
+				t.Errorf("expected move %s, got %+v", move, m)
+			}
+		}
+	}
+
+	black.send(Message{Type: TypeResign})
+	for _, c := range []*testConn{white, black} {
+		msg := c.expect(TypeEnd)
+		if msg.Status != "resignation" || msg.Color != "white" || msg.Text != "black resigned" {
+			t.Errorf("expected black to resign, got %+v", msg)
+		}
+	}
+}
+
+func TestPlayDisconnect(t *testing.T) {
+	addr := startServer(t, nil)
+	white, black := dialTest(t, addr), dialTest(t, addr)
+	white.expect(TypeStart)
+	black.expect(TypeStart)
+
+	white.conn.Close()
+	msg := black.expect(TypeEnd)
+	if msg.Status != "resignation" || msg.Color != "black" {
+		t.Errorf("expected white to resign by disconnecting, got %+v", msg)
+	}
+}
+
+func TestPlayTimeout(t *testing.T) {
+	addr := startServer(t, clock.Control{{Time: time.Second}})
+	white, black := dialTest(t, addr), dialTest(t, addr)
+	if msg := white.expect(TypeStart); msg.WhiteTime <= 900 || msg.BlackTime != 1000 {
+		t.Errorf("expected both players to start with about 1000ms, got %d and %d", msg.WhiteTime, msg.BlackTime)
+	}
+	black.expect(TypeStart)
+
+	white.send(Message{Type: TypeMove, Move: "e2e4"})
+	white.expect(TypeMove)
+	if msg := black.expect(TypeMove); msg.WhiteTime <= 0 || msg.WhiteTime >= 1000 || msg.BlackTime <= 900 {
+		t.Errorf("expected white's time to have run, got %d and %d", msg.WhiteTime, msg.BlackTime)
+	}
+
+	// Black loses as soon as their flag falls, without having to move.
+	for _, c := range []*testConn{white, black} {
+		msg := c.expect(TypeEnd)
+		if msg.Status != "timeout" || msg.Color != "white" || msg.Text != "black ran out of time" ||
+			msg.BlackTime != 0 {
+			t.Errorf("expected black to run out of time, got %+v", msg)
+		}
+	}
+}
+
+func TestLobbyDropsDisconnectedPlayer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	lobby := NewLobby(nil)
+	go lobby.Serve(l)
+
+	// waitFor waits for the lobby to have a player waiting or not.
+	waitFor := func(waiting bool) {
+		t.Helper()
+		for i := 0; i < 100; i++ {
+			lobby.mu.Lock()
+			found := lobby.waiting != nil
+			lobby.mu.Unlock()
+			if found == waiting {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("expected a player waiting to be %t", waiting)
+	}
+
+	// A player that leaves before an opponent joins is dropped.
+	gone := dialTest(t, l.Addr().String())
+	waitFor(true)
+	gone.conn.Close()
+	waitFor(false)
+
+	// Including a player that sends something before leaving.
+	gone = dialTest(t, l.Addr().String())
+	waitFor(true)
+	gone.send(Message{Type: TypeMove, Move: "e4"})
+	gone.conn.Close()
+	waitFor(false)
+
+	white := dialTest(t, l.Addr().String())
+	waitFor(true)
+	// Anything that white sends while waiting is still read once the
+	// game starts.
+	white.send(Message{Type: TypeMove, Move: "e4"})
+	black := dialTest(t, l.Addr().String())
+	if msg := white.expect(TypeStart); msg.Color != "white" {
+		t.Errorf("expected white to start, got %+v", msg)
+	}
+	if msg := black.expect(TypeStart); msg.Color != "black" {
+		t.Errorf("expected black to start, got %+v", msg)
+	}
+	if msg := black.expect(TypeMove); msg.FEN != "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1" {
+		t.Errorf("expected e4 to be played, got %+v", msg)
+	}
+}
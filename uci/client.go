@@ -0,0 +1,239 @@
+package uci
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/radovskyb/chess/ai"
+	"github.com/radovskyb/chess/engine"
+)
+
+var (
+	ErrEngineExited = errors.New("uci: engine exited unexpectedly")
+	ErrInfinite     = errors.New("uci: infinite searches aren't supported")
+)
+
+// A Client runs an external UCI engine as a subprocess, so that it can
+// be played against or used to analyse positions.
+type Client struct {
+	// Name holds the name that the engine identified itself with.
+	Name string
+
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	out *bufio.Scanner
+}
+
+// Start starts the UCI engine at path with the arguments args, and waits
+// for it to be ready.
+func Start(path string, args ...string) (*Client, error) {
+	cmd := exec.Command(path, args...)
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	c := &Client{cmd: cmd, in: in, out: bufio.NewScanner(out)}
+	err = c.send("uci")
+	for err == nil {
+		var line string
+		if line, err = c.readLine(); err != nil {
+			break
+		}
+		if strings.HasPrefix(line, "id name ") {
+			c.Name = strings.TrimPrefix(line, "id name ")
+		}
+		if line == "uciok" {
+			break
+		}
+	}
+	if err == nil {
+		err = c.ready()
+	}
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// send sends a command to the engine.
+func (c *Client) send(cmd string) error {
+	_, err := io.WriteString(c.in, cmd+"\n")
+	return err
+}
+
+// readLine reads the next line from the engine.
+func (c *Client) readLine() (string, error) {
+	if !c.out.Scan() {
+		if err := c.out.Err(); err != nil {
+			return "", err
+		}
+		return "", ErrEngineExited
+	}
+	return strings.TrimSpace(c.out.Text()), nil
+}
+
+// ready waits for the engine to be ready for the next command.
+func (c *Client) ready() error {
+	if err := c.send("isready"); err != nil {
+		return err
+	}
+	for {
+		line, err := c.readLine()
+		if err != nil || line == "readyok" {
+			return err
+		}
+	}
+}
+
+// Analyse has the engine search board b's current position until it
+// reaches one of limits and returns it's result. If neither limit is
+// set, the engine searches to ai.DefaultDepth.
+//
+// The result's PV holds the moves that the engine sent with it's last
+// info line that had a principal variation, and it's Score is converted
+// so that a checkmate is the same score that ai.Search would use.
+func (c *Client) Analyse(b *engine.Board, limits ai.Limits) (ai.Result, error) {
+	if limits.Infinite {
+		return ai.Result{}, ErrInfinite
+	}
+
+	// Send the starting position and every move since, so that the
+	// engine knows about repetitions.
+	pos := "position fen " + b.InitialFEN()
+	if moves := b.Moves(); len(moves) > 0 {
+		pos += " moves"
+		for _, m := range moves {
+			pos += " " + m.String()
+		}
+	}
+
+	var goCmd string
+	switch {
+	case limits.Depth > 0 && limits.Time > 0:
+		goCmd = fmt.Sprintf("go depth %d movetime %d", limits.Depth, limits.Time.Milliseconds())
+	case limits.Time > 0:
+		goCmd = fmt.Sprintf("go movetime %d", limits.Time.Milliseconds())
+	case limits.Depth > 0:
+		goCmd = fmt.Sprintf("go depth %d", limits.Depth)
+	default:
+		goCmd = fmt.Sprintf("go depth %d", ai.DefaultDepth)
+	}
+
+	for _, cmd := range []string{pos, goCmd} {
+		if err := c.send(cmd); err != nil {
+			return ai.Result{}, err
+		}
+	}
+
+	var result ai.Result
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return ai.Result{}, err
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "info":
+			parseInfo(&result, fields[1:])
+		case "bestmove":
+			if len(fields) < 2 {
+				return ai.Result{}, fmt.Errorf("uci: invalid bestmove %q", line)
+			}
+			if fields[1] == "0000" || fields[1] == "(none)" {
+				return ai.Result{}, ai.ErrNoMoves
+			}
+			m, err := engine.ParseMove(fields[1])
+			if err != nil {
+				return ai.Result{}, fmt.Errorf("uci: invalid bestmove %q: %v", line, err)
+			}
+			result.Move = m
+			return result, nil
+		}
+	}
+}
+
+// parseInfo updates result with the depth, score, nodes and principal
+// variation from the fields of an info line.
+func parseInfo(result *ai.Result, fields []string) {
+	// The rest of an info string line is just text.
+	if len(fields) > 0 && fields[0] == "string" {
+		return
+	}
+	for i := 0; i+1 < len(fields); i++ {
+		switch fields[i] {
+		case "depth":
+			result.Depth, _ = strconv.Atoi(fields[i+1])
+		case "nodes":
+			result.Nodes, _ = strconv.ParseUint(fields[i+1], 10, 64)
+		case "score":
+			if i+2 >= len(fields) {
+				continue
+			}
+			n, err := strconv.Atoi(fields[i+2])
+			if err != nil {
+				continue
+			}
+			switch fields[i+1] {
+			case "cp":
+				result.Score = n
+			case "mate":
+				// Mating in n moves takes 2n-1 half moves, and
+				// being mated in n moves takes 2n half moves.
+				if n > 0 {
+					result.Score = ai.MateScore - (2*n - 1)
+				} else {
+					result.Score = -ai.MateScore - 2*n
+				}
+			}
+		case "pv":
+			var pv []engine.Move
+			for _, s := range fields[i+1:] {
+				m, err := engine.ParseMove(s)
+				if err != nil {
+					break
+				}
+				pv = append(pv, m)
+			}
+			result.PV = pv
+			return
+		}
+	}
+}
+
+// Move has the engine find the best move for board b's current position
+// within limits, and makes the move on b.
+func (c *Client) Move(b *engine.Board, limits ai.Limits) (engine.Move, error) {
+	result, err := c.Analyse(b, limits)
+	if err != nil {
+		return engine.Move{}, err
+	}
+	s := result.Move.String()
+	if err := b.MoveByLocation(s[:2], s[2:]); err != nil {
+		return engine.Move{}, fmt.Errorf("uci: engine's move %s: %w", s, err)
+	}
+	return result.Move, nil
+}
+
+// Close tells the engine to quit and waits for it to exit.
+func (c *Client) Close() error {
+	c.send("quit")
+	c.in.Close()
+	return c.cmd.Wait()
+}
@@ -0,0 +1,118 @@
+package uci
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/radovskyb/chess/ai"
+	"github.com/radovskyb/chess/engine"
+)
+
+// startFakeEngine starts the fake engine script in testdata with args.
+func startFakeEngine(t *testing.T, args ...string) *Client {
+	t.Helper()
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is needed to run the fake engine")
+	}
+	c, err := Start(sh, append([]string{"testdata/fake_engine.sh"}, args...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestClient(t *testing.T) {
+	c := startFakeEngine(t)
+	if c.Name != "Fake Engine" {
+		t.Errorf("expected engine name Fake Engine, got %q", c.Name)
+	}
+
+	b := engine.NewBoard()
+	m, err := c.Move(b, ai.Limits{Depth: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.String() != "e2e4" || b.HistorySAN() != "1. e4" {
+		t.Errorf("expected engine to play e2e4, got %s with history %q", m, b.HistorySAN())
+	}
+
+	result, err := c.Analyse(b, ai.Limits{Time: 250 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Move.String() != "e7e5" || result.Depth != 2 || result.Nodes != 400 {
+		t.Errorf("expected e7e5 at depth 2 with 400 nodes, got %+v", result)
+	}
+	if n, mate := result.MateIn(); !mate || n != -3 {
+		t.Errorf("expected to be mated in 3, got score %d", result.Score)
+	}
+	if len(result.PV) != 2 || result.PV[1].String() != "g1f3" {
+		t.Errorf("expected pv e7e5 g1f3, got %v", result.PV)
+	}
+
+	if _, err := c.Analyse(b, ai.Limits{Infinite: true}); err != ErrInfinite {
+		t.Errorf("expected error %v, got %v", ErrInfinite, err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClientSendsHistory(t *testing.T) {
+	c := startFakeEngine(t)
+	defer c.Close()
+
+	b, err := engine.ParseFEN("4k3/4P3/8/8/8/8/8/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.MoveSAN("Kd2"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The fake engine only plays Kd7 if it's sent the board's starting
+	// position with the moves since, and both of the limits.
+	m, err := c.Move(b, ai.Limits{Depth: 2, Time: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.String() != "e8d7" {
+		t.Errorf("expected engine to play e8d7, got %s", m)
+	}
+
+	// The info strings that the fake engine sends back shouldn't be
+	// read as search info.
+	if err := b.UndoMove(); err != nil {
+		t.Fatal(err)
+	}
+	result, err := c.Analyse(b, ai.Limits{Depth: 2, Time: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Depth != 0 || result.PV != nil {
+		t.Errorf("expected no search info, got %+v", result)
+	}
+
+	if _, err := c.Analyse(b, ai.Limits{}); err != ai.ErrNoMoves {
+		t.Errorf("expected error %v, got %v", ai.ErrNoMoves, err)
+	}
+}
+
+func TestClientEngineExits(t *testing.T) {
+	c := startFakeEngine(t, "crash")
+	if _, err := c.Move(engine.NewBoard(), ai.Limits{Depth: 1}); err != ErrEngineExited {
+		t.Errorf("expected error %v, got %v", ErrEngineExited, err)
+	}
+	if err := c.Close(); err == nil {
+		t.Error("expected an error from the engine exiting with status 1")
+	}
+}
+
+func TestStartMissingEngine(t *testing.T) {
+	if _, err := Start("testdata/no_such_engine"); err == nil {
+		t.Error("expected an error starting an engine that doesn't exist")
+	}
+}
@@ -0,0 +1,50 @@
+#!/bin/sh
+# A fake UCI engine for testing the client. It plays e2e4 as white and
+# e7e5 as black after 1. e4, and sends back the position and go command
+# that it was sent as info strings.
+#
+# If it's run with the argument crash, it exits when told to search.
+while read -r cmd args; do
+	case "$cmd" in
+	uci)
+		echo "id name Fake Engine"
+		echo "id author Nobody"
+		echo "option name Hash type spin default 16 min 1 max 1024"
+		echo "uciok"
+		;;
+	isready)
+		echo "readyok"
+		;;
+	position)
+		position="$args"
+		;;
+	go)
+		if [ "$1" = "crash" ]; then
+			exit 1
+		fi
+		echo "info string $position"
+		echo "info string go $args"
+		case "$position" in
+		"fen 4k3/4P3/8/8/8/8/8/4K3 w - - 0 1 moves e1d2")
+			if [ "$args" = "depth 2 movetime 1000" ]; then
+				echo "bestmove e8d7"
+			else
+				echo "bestmove 0000"
+			fi
+			;;
+		*"moves e2e4")
+			echo "info depth 1 score cp -20 nodes 30 pv e7e5"
+			echo "info depth 2 score mate -3 nodes 400 pv e7e5 g1f3"
+			echo "bestmove e7e5 ponder g1f3"
+			;;
+		*)
+			echo "info depth 1 seldepth 2 score cp 35 nodes 20 pv e2e4 e7e5"
+			echo "bestmove e2e4"
+			;;
+		esac
+		;;
+	quit)
+		exit 0
+		;;
+	esac
+done
@@ -0,0 +1,247 @@
+// Package uci implements the Universal Chess Interface, which is used by
+// chess GUIs and tournament managers to talk to chess engines.
+package uci
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/radovskyb/chess/ai"
+	"github.com/radovskyb/chess/engine"
+)
+
+// Name and Author are sent to the GUI to identify the engine.
+const (
+	Name   = "radovskyb/chess"
+	Author = "radovskyb"
+)
+
+// defaultMovesToGo is the number of moves that the remaining time is
+// shared between when the GUI doesn't say how many moves are left until
+// the next time control.
+const defaultMovesToGo = 30
+
+// Run runs the engine's side of UCI, reading commands from r and writing
+// responses to w until the quit command is read or r has no more input.
+func Run(r io.Reader, w io.Writer) error {
+	e := &session{w: w, board: engine.NewBoard()}
+	defer e.stop()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			e.println("id name " + Name)
+			e.println("id author " + Author)
+			e.println("uciok")
+		case "isready":
+			e.println("readyok")
+		case "ucinewgame":
+			e.stop()
+			e.board = engine.NewBoard()
+		case "position":
+			e.stop()
+			if err := e.position(fields[1:]); err != nil {
+				e.println("info string " + err.Error())
+			}
+		case "go":
+			e.stop()
+			e.search(fields[1:])
+		case "stop":
+			e.stop()
+		case "quit":
+			return nil
+		}
+		// Any other commands, such as debug and setoption, aren't
+		// supported, so they're ignored like UCI says they should be.
+	}
+	return scanner.Err()
+}
+
+// A session holds the state of the engine while it's running UCI.
+type session struct {
+	mu sync.Mutex // Guards writes to w.
+	w  io.Writer
+
+	board *engine.Board
+
+	// cancel stops the search that's currently running and done is
+	// closed when it has finished, or they're nil if there's no search
+	// running.
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// println writes a line to the GUI.
+func (e *session) println(line string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fmt.Fprintln(e.w, line)
+}
+
+// position sets up the board from the arguments of a position command,
+// which are startpos or fen followed by a FEN string, and then optionally
+// moves followed by moves in coordinate notation.
+func (e *session) position(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing position")
+	}
+
+	var b *engine.Board
+	switch args[0] {
+	case "startpos":
+		b, args = engine.NewBoard(), args[1:]
+	case "fen":
+		end := len(args)
+		for i, arg := range args {
+			if arg == "moves" {
+				end = i
+				break
+			}
+		}
+		var err error
+		if b, err = engine.ParseFEN(strings.Join(args[1:end], " ")); err != nil {
+			return err
+		}
+		args = args[end:]
+	default:
+		return fmt.Errorf("invalid position %q", args[0])
+	}
+
+	if len(args) > 0 && args[0] == "moves" {
+		for _, m := range args[1:] {
+			if len(m) < 4 {
+				return fmt.Errorf("invalid move %q", m)
+			}
+			if err := b.MoveByLocation(m[:2], m[2:]); err != nil {
+				return fmt.Errorf("invalid move %q: %v", m, err)
+			}
+		}
+	}
+
+	e.board = b
+	return nil
+}
+
+// search starts searching the board's position in the background using
+// the arguments of a go command to set the search's limits. The best
+// move is written when the search finishes.
+func (e *session) search(args []string) {
+	limits := e.limits(args)
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel, e.done = cancel, make(chan struct{})
+
+	b, done, start := e.board, e.done, time.Now()
+	go func() {
+		defer close(done)
+		result, err := ai.SearchContext(ctx, b, limits, func(r ai.Result) {
+			e.println(infoLine(r, time.Since(start)))
+		})
+
+		// An infinite search can't send it's best move until
+		// it's told to stop.
+		if limits.Infinite {
+			<-ctx.Done()
+		}
+		if err != nil {
+			e.println("bestmove 0000")
+			return
+		}
+		e.println("bestmove " + result.Move.String())
+	}()
+}
+
+// stop stops the search that's currently running, if there is one, and
+// waits for it to finish.
+func (e *session) stop() {
+	if e.cancel == nil {
+		return
+	}
+	e.cancel()
+	<-e.done
+	e.cancel, e.done = nil, nil
+}
+
+// limits returns the search limits from the arguments of a go command.
+func (e *session) limits(args []string) ai.Limits {
+	var limits ai.Limits
+	var timeLeft, inc [2]time.Duration
+	movesToGo := defaultMovesToGo
+
+	for i := 0; i < len(args); i++ {
+		// value returns the number after the current argument.
+		value := func() int {
+			if i+1 >= len(args) {
+				return 0
+			}
+			i++
+			n, _ := strconv.Atoi(args[i])
+			return n
+		}
+		ms := func() time.Duration {
+			return time.Duration(value()) * time.Millisecond
+		}
+
+		switch args[i] {
+		case "depth":
+			limits.Depth = value()
+		case "movetime":
+			limits.Time = ms()
+		case "wtime":
+			timeLeft[engine.White] = ms()
+		case "btime":
+			timeLeft[engine.Black] = ms()
+		case "winc":
+			inc[engine.White] = ms()
+		case "binc":
+			inc[engine.Black] = ms()
+		case "movestogo":
+			if n := value(); n > 0 {
+				movesToGo = n
+			}
+		case "infinite":
+			limits.Infinite = true
+		}
+	}
+
+	// When the GUI only gives the time left on the clock, use an even
+	// share of it for each of the moves left, plus most of the increment.
+	turn := e.board.Turn()
+	if limits.Time == 0 && timeLeft[turn] > 0 {
+		limits.Time = timeLeft[turn]/time.Duration(movesToGo) + inc[turn]*3/4
+		if limits.Time >= timeLeft[turn] {
+			limits.Time = timeLeft[turn] / 2
+		}
+	}
+	return limits
+}
+
+// infoLine returns an info line to send to the GUI about a search's
+// result after it has been searching for elapsed time.
+func infoLine(r ai.Result, elapsed time.Duration) string {
+	score := fmt.Sprintf("cp %d", r.Score)
+	if n, mate := r.MateIn(); mate {
+		score = fmt.Sprintf("mate %d", n)
+	}
+	ms := elapsed.Milliseconds()
+	nps := uint64(0)
+	if ms > 0 {
+		nps = r.Nodes * 1000 / uint64(ms)
+	}
+	pv := make([]string, len(r.PV))
+	for i, m := range r.PV {
+		pv[i] = m.String()
+	}
+	return fmt.Sprintf("info depth %d score %s nodes %d nps %d time %d pv %s",
+		r.Depth, score, r.Nodes, nps, ms, strings.Join(pv, " "))
+}
@@ -0,0 +1,147 @@
+package uci
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/radovskyb/chess/ai"
+	"github.com/radovskyb/chess/engine"
+)
+
+// A testGUI sends commands to an engine running Run and reads it's
+// responses.
+type testGUI struct {
+	t    *testing.T
+	in   *io.PipeWriter
+	out  *bufio.Scanner
+	done chan error
+}
+
+func newTestGUI(t *testing.T) *testGUI {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	g := &testGUI{t: t, in: inW, out: bufio.NewScanner(outR), done: make(chan error)}
+	go func() {
+		err := Run(inR, outW)
+		outW.Close()
+		g.done <- err
+	}()
+	return g
+}
+
+func (g *testGUI) send(cmd string) {
+	g.t.Helper()
+	if _, err := io.WriteString(g.in, cmd+"\n"); err != nil {
+		g.t.Fatal(err)
+	}
+}
+
+// readUntil reads lines from the engine until it reads a line starting
+// with prefix, and returns all of the lines read.
+func (g *testGUI) readUntil(prefix string) []string {
+	g.t.Helper()
+	var lines []string
+	for g.out.Scan() {
+		lines = append(lines, g.out.Text())
+		if strings.HasPrefix(g.out.Text(), prefix) {
+			return lines
+		}
+	}
+	g.t.Fatalf("expected a line starting with %q, got %q", prefix, lines)
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	g := newTestGUI(t)
+
+	g.send("uci")
+	lines := g.readUntil("uciok")
+	if len(lines) != 3 || lines[0] != "id name "+Name || lines[1] != "id author "+Author {
+		t.Errorf("expected id lines before uciok, got %q", lines)
+	}
+
+	g.send("isready")
+	g.readUntil("readyok")
+
+	// Black to move can checkmate with Qh4#.
+	g.send("ucinewgame")
+	g.send("position startpos moves f2f3 e7e5 g2g4")
+	g.send("go depth 2")
+	lines = g.readUntil("bestmove")
+	if last := lines[len(lines)-1]; last != "bestmove d8h4" {
+		t.Errorf("expected bestmove d8h4, got %q", last)
+	}
+	if !strings.HasPrefix(lines[0], "info depth 1 score mate 1 ") ||
+		!strings.HasSuffix(lines[0], " pv d8h4") {
+		t.Errorf("expected an info line with mate 1, got %q", lines[0])
+	}
+
+	// A position set up from a FEN string with a promotion.
+	g.send("position fen 8/4P3/8/8/8/8/k7/4K3 w - - 0 1 moves e7e8q a2a3")
+	g.send("go depth 1")
+	lines = g.readUntil("bestmove")
+	if strings.Contains(lines[len(lines)-1], "0000") {
+		t.Errorf("expected a move from the position, got %q", lines)
+	}
+
+	g.send("position startpos moves e2e5")
+	g.readUntil("info string")
+
+	g.send("quit")
+	if err := <-g.done; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRunStop(t *testing.T) {
+	g := newTestGUI(t)
+
+	g.send("position startpos")
+	g.send("go infinite")
+
+	// The search shouldn't send a best move until it's stopped.
+	time.Sleep(50 * time.Millisecond)
+	g.send("isready")
+	g.readUntil("readyok")
+
+	g.send("stop")
+	lines := g.readUntil("bestmove")
+	if len(lines[len(lines)-1]) != len("bestmove e2e4") {
+		t.Errorf("expected a best move, got %q", lines[len(lines)-1])
+	}
+
+	g.send("quit")
+	if err := <-g.done; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	testCases := []struct {
+		args     string
+		turn     string
+		expected ai.Limits
+	}{
+		{"depth 5", "w", ai.Limits{Depth: 5}},
+		{"movetime 1500", "w", ai.Limits{Time: 1500 * time.Millisecond}},
+		{"infinite", "w", ai.Limits{Infinite: true}},
+		{"wtime 60000 btime 30000", "w", ai.Limits{Time: 2 * time.Second}},
+		{"wtime 60000 btime 30000", "b", ai.Limits{Time: time.Second}},
+		{"wtime 60000 btime 30000 winc 2000 binc 2000 movestogo 10", "b",
+			ai.Limits{Time: 4500 * time.Millisecond}},
+		{"wtime 100 btime 100 winc 1000", "w", ai.Limits{Time: 50 * time.Millisecond}},
+	}
+	for _, tc := range testCases {
+		b, err := engine.ParseFEN("4k3/8/8/8/8/8/8/4K3 " + tc.turn + " - - 0 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		e := &session{board: b}
+		if limits := e.limits(strings.Fields(tc.args)); limits != tc.expected {
+			t.Errorf("go %s: expected %+v, got %+v", tc.args, tc.expected, limits)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Package web serves a browser client for playing chess. Players are
+// paired up into games over WebSockets, which are played on the server
+// by the server package, so the browser is sent the same messages as a
+// terminal client.
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/radovskyb/chess/clock"
+	"github.com/radovskyb/chess/server"
+)
+
+// DefaultAddr is the address that the browser client is served on when
+// no address is given.
+const DefaultAddr = ":8080"
+
+// static holds the browser client's files.
+//
+//go:embed static
+var static embed.FS
+
+// A Handler serves the browser client and pairs up the WebSocket
+// connections to /ws into games in a server.Lobby, where the first player
+// of each pair plays white.
+type Handler struct {
+	mux   *http.ServeMux
+	lobby *server.Lobby
+}
+
+// NewHandler creates a Handler that plays games with the time control,
+// or untimed games if the control doesn't have any stages.
+func NewHandler(control clock.Control) *Handler {
+	files, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+	h := &Handler{mux: http.NewServeMux(), lobby: server.NewLobby(control)}
+	h.mux.Handle("/", http.FileServer(http.FS(files)))
+	h.mux.HandleFunc("/ws", h.serveWebSocket)
+	return h
+}
+
+// ServeHTTP serves an HTTP request.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// serveWebSocket upgrades the request's connection to a WebSocket and
+// either starts a game with the player that's waiting for an opponent, or
+// waits for one.
+func (h *Handler) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrade(w, r)
+	if err != nil {
+		return
+	}
+	h.lobby.Join(conn)
+}
@@ -0,0 +1,124 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/radovskyb/chess/engine"
+	"github.com/radovskyb/chess/server"
+)
+
+func (c *testClient) send(msg server.Message) {
+	c.t.Helper()
+	data, err := json.Marshal(msg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	c.writeFrame(true, opText, data)
+}
+
+// expect reads the next message and checks that it's of type typ.
+func (c *testClient) expect(typ string) server.Message {
+	c.t.Helper()
+	op, payload := c.readFrame()
+	if op != opText {
+		c.t.Fatalf("expected a text message, got opcode %x", op)
+	}
+	var msg server.Message
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		c.t.Fatal(err)
+	}
+	if msg.Type != typ {
+		c.t.Fatalf("expected a %s message, got %+v", typ, msg)
+	}
+	return msg
+}
+
+func TestHandlerServesClient(t *testing.T) {
+	s := httptest.NewServer(NewHandler(nil))
+	defer s.Close()
+
+	resp, err := http.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `<table id="board">`) {
+		t.Errorf("expected the browser client, got %s %q", resp.Status, body)
+	}
+}
+
+func TestHandlerPlaysGame(t *testing.T) {
+	s := httptest.NewServer(NewHandler(nil))
+	defer s.Close()
+
+	white, black := dialWebSocket(t, s, "/ws"), dialWebSocket(t, s, "/ws")
+	if msg := white.expect(server.TypeStart); msg.Color != "white" || len(msg.Legal) != 20 {
+		t.Errorf("expected white to start with 20 legal moves, got %+v", msg)
+	}
+	if msg := black.expect(server.TypeStart); msg.Color != "black" {
+		t.Errorf("expected black to start, got %+v", msg)
+	}
+
+	// Fool's mate.
+	var msg server.Message
+	for i, move := range []string{"f2f3", "e7e5", "g2g4", "d8h4"} {
+		c := white
+		if i%2 == 1 {
+			c = black
+		}
+		c.send(server.Message{Type: server.TypeMove, Move: move})
+		msg = white.expect(server.TypeMove)
+		black.expect(server.TypeMove)
+
+		var m engine.MoveInfo
+		if err := m.Decode(msg.MoveInfo); err != nil {
+			t.Fatal(err)
+		}
+		if s := (engine.Move{From: m.From, To: m.To}).String(); s != move {
+			t.Errorf("expected move %s, got %s", move, s)
+		}
+	}
+	if msg.Status != "checkmate" || msg.Text != "white is in checkmate" || msg.Legal != nil {
+		t.Errorf("expected checkmate, got %+v", msg)
+	}
+
+	for _, c := range []*testClient{white, black} {
+		if msg := c.expect(server.TypeEnd); msg.Color != "black" {
+			t.Errorf("expected black to win, got %+v", msg)
+		}
+		if op, _ := c.readFrame(); op != opClose {
+			t.Errorf("expected the connection to be closed after the game, got %x", op)
+		}
+	}
+}
+
+func TestHandlerDropsDisconnectedPlayer(t *testing.T) {
+	s := httptest.NewServer(NewHandler(nil))
+	defer s.Close()
+
+	// A player that leaves before an opponent joins isn't paired up.
+	gone := dialWebSocket(t, s, "/ws")
+	gone.writeFrame(true, opClose, nil)
+	if op, _ := gone.readFrame(); op != opClose {
+		t.Fatalf("expected close frame, got %x", op)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	white, black := dialWebSocket(t, s, "/ws"), dialWebSocket(t, s, "/ws")
+	if msg := white.expect(server.TypeStart); msg.Color != "white" {
+		t.Errorf("expected white to start, got %+v", msg)
+	}
+	if msg := black.expect(server.TypeStart); msg.Color != "black" {
+		t.Errorf("expected black to start, got %+v", msg)
+	}
+}
@@ -0,0 +1,243 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// websocketGUID is appended to a client's key to make the accept key in
+// the WebSocket handshake.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxMessageSize is the largest message that's read from a client. The
+// client only ever sends short JSON messages.
+const maxMessageSize = 1 << 16
+
+// The WebSocket frame opcodes.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+var (
+	ErrNotWebSocket    = errors.New("web: not a websocket handshake")
+	ErrMessageTooLarge = errors.New("web: websocket message too large")
+	ErrUnmaskedFrame   = errors.New("web: websocket frame from client isn't masked")
+	ErrBadOrigin       = errors.New("web: websocket origin doesn't match the host")
+)
+
+// A wsConn is the server's side of a WebSocket connection, which only
+// supports text messages.
+//
+// It's an io.ReadWriteCloser where each Write sends a message, and Read
+// reads the messages one after the other with a newline after each, so
+// that it can be used with a server.Play game.
+type wsConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+
+	// buf holds the rest of the message being read.
+	buf bytes.Buffer
+
+	mu     sync.Mutex // Guards writes to rw and closed.
+	closed bool
+}
+
+// upgrade performs the server's side of the WebSocket handshake for the
+// request r and takes over it's connection.
+func upgrade(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || key == "" ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, ErrNotWebSocket.Error(), http.StatusBadRequest)
+		return nil, ErrNotWebSocket
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "web: unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, ErrNotWebSocket
+	}
+	// Browsers send the origin of the page that opened the WebSocket,
+	// so that pages on other sites can't connect as the player.
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || !strings.EqualFold(u.Host, r.Host) {
+			http.Error(w, ErrBadOrigin.Error(), http.StatusForbidden)
+			return nil, ErrBadOrigin
+		}
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, rw: rw}, nil
+}
+
+// headerContains reports whether the comma separated list in the header
+// name contains token, ignoring case.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Read reads from the messages that the client sends, with a newline
+// after each message.
+func (c *wsConn) Read(p []byte) (int, error) {
+	for c.buf.Len() == 0 {
+		msg, err := c.readMessage()
+		if err != nil {
+			return 0, err
+		}
+		c.buf.Write(msg)
+		c.buf.WriteByte('\n')
+	}
+	return c.buf.Read(p)
+}
+
+// readMessage reads the next data message from the client, answering
+// any control frames that come before it.
+func (c *wsConn) readMessage() ([]byte, error) {
+	var msg []byte
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case opPing:
+			c.writeFrame(opPong, payload)
+			continue
+		case opPong:
+			continue
+		case opClose:
+			c.Close()
+			return nil, io.EOF
+		}
+		if len(msg)+len(payload) > maxMessageSize {
+			return nil, ErrMessageTooLarge
+		}
+		msg = append(msg, payload...)
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+// readFrame reads a single frame from the client.
+func (c *wsConn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.rw, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, op = header[0]&0x80 != 0, header[0]&0x0f
+	if header[1]&0x80 == 0 {
+		return false, 0, nil, ErrUnmaskedFrame
+	}
+
+	n := uint64(header[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > maxMessageSize {
+		return false, 0, nil, ErrMessageTooLarge
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.rw, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.rw, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+// Write sends p to the client as a text message, without any newline
+// at the end of it.
+func (c *wsConn) Write(p []byte) (int, error) {
+	if err := c.writeFrame(opText, bytes.TrimSuffix(p, []byte("\n"))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writeFrame sends a single unfragmented frame to the client.
+func (c *wsConn) writeFrame(op byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+
+	header := []byte{0x80 | op, 0}
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	c.rw.Write(header)
+	c.rw.Write(payload)
+	return c.rw.Flush()
+}
+
+// Close sends a close frame to the client and closes the connection.
+func (c *wsConn) Close() error {
+	c.writeFrame(opClose, nil)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.conn.Close()
+}
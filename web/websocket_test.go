@@ -0,0 +1,207 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// A testClient is the browser's side of a WebSocket connection.
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// dialWebSocket connects to the WebSocket at the path on the server s.
+func dialWebSocket(t *testing.T, s *httptest.Server, path string) *testClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", s.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	io.WriteString(conn, "GET "+path+" HTTP/1.1\r\n"+
+		"Host: "+s.Listener.Addr().String()+"\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: keep-alive, Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+
+		"Sec-WebSocket-Version: 13\r\n\r\n")
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The accept key for the example key in RFC 6455.
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("expected a websocket handshake, got %s %v", resp.Status, resp.Header)
+	}
+	return &testClient{t: t, conn: conn, r: r}
+}
+
+// writeFrame sends a masked frame, like a browser does.
+func (c *testClient) writeFrame(fin bool, op byte, payload []byte) {
+	c.t.Helper()
+	b := []byte{op, 0x80}
+	if fin {
+		b[0] |= 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		b[1] |= byte(n)
+	case n <= 0xffff:
+		b[1] |= 126
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b[1] |= 127
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	}
+	mask := []byte{1, 2, 3, 4}
+	b = append(b, mask...)
+	for i, p := range payload {
+		b = append(b, p^mask[i%4])
+	}
+	if _, err := c.conn.Write(b); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// readFrame reads an unmasked frame from the server.
+func (c *testClient) readFrame() (op byte, payload []byte) {
+	c.t.Helper()
+	var header [2]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		c.t.Fatal(err)
+	}
+	if header[0]&0x80 == 0 || header[1]&0x80 != 0 {
+		c.t.Fatalf("expected an unmasked final frame, got header %x", header)
+	}
+	n := int(header[1])
+	switch n {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(c.r, ext[:])
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(c.r, ext[:])
+		n = int(binary.BigEndian.Uint64(ext[:]))
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		c.t.Fatal(err)
+	}
+	return header[0] & 0x0f, payload
+}
+
+// echoServer starts a server that reads lines from a WebSocket and
+// writes them back.
+func echoServer(t *testing.T) *httptest.Server {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			conn.Write(append(scanner.Bytes(), '\n'))
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestWebSocket(t *testing.T) {
+	c := dialWebSocket(t, echoServer(t), "/")
+
+	c.writeFrame(true, opText, []byte("hello"))
+	if op, payload := c.readFrame(); op != opText || string(payload) != "hello" {
+		t.Errorf("expected text message hello, got %x %q", op, payload)
+	}
+
+	// A fragmented message, with a ping in between the fragments.
+	c.writeFrame(false, opText, []byte("hello "))
+	c.writeFrame(true, opPing, []byte("ping"))
+	c.writeFrame(true, opContinuation, []byte("world"))
+	if op, payload := c.readFrame(); op != opPong || string(payload) != "ping" {
+		t.Errorf("expected pong, got %x %q", op, payload)
+	}
+	if _, payload := c.readFrame(); string(payload) != "hello world" {
+		t.Errorf("expected hello world, got %q", payload)
+	}
+
+	// A message with an extended length.
+	msg := bytes.Repeat([]byte("a"), 1000)
+	c.writeFrame(true, opText, msg)
+	if _, payload := c.readFrame(); !bytes.Equal(payload, msg) {
+		t.Errorf("expected message of %d bytes, got %d bytes", len(msg), len(payload))
+	}
+}
+
+func TestWebSocketClose(t *testing.T) {
+	c := dialWebSocket(t, echoServer(t), "/")
+	c.writeFrame(true, opClose, nil)
+	if op, _ := c.readFrame(); op != opClose {
+		t.Errorf("expected close frame, got %x", op)
+	}
+	if _, err := c.r.ReadByte(); err != io.EOF {
+		t.Errorf("expected connection to be closed, got %v", err)
+	}
+}
+
+func TestUpgradeErrors(t *testing.T) {
+	s := echoServer(t)
+	testCases := []struct {
+		header   http.Header
+		expected int
+	}{
+		{http.Header{}, http.StatusBadRequest},
+		{http.Header{
+			"Upgrade":           {"websocket"},
+			"Connection":        {"Upgrade"},
+			"Sec-Websocket-Key": {"dGhlIHNhbXBsZSBub25jZQ=="},
+		}, http.StatusUpgradeRequired},
+		{http.Header{
+			"Upgrade":               {"websocket"},
+			"Connection":            {"Upgrade"},
+			"Sec-Websocket-Key":     {"dGhlIHNhbXBsZSBub25jZQ=="},
+			"Sec-Websocket-Version": {"13"},
+			"Origin":                {"http://example.com"},
+		}, http.StatusForbidden},
+		{http.Header{
+			"Upgrade":               {"websocket"},
+			"Connection":            {"Upgrade"},
+			"Sec-Websocket-Key":     {"dGhlIHNhbXBsZSBub25jZQ=="},
+			"Sec-Websocket-Version": {"13"},
+			"Origin":                {s.URL},
+		}, http.StatusSwitchingProtocols},
+	}
+	for _, tc := range testCases {
+		req, err := http.NewRequest(http.MethodGet, s.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header = tc.header
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.expected {
+			t.Errorf("%v: expected status %d, got %d", tc.header, tc.expected, resp.StatusCode)
+		}
+		if tc.expected == http.StatusUpgradeRequired &&
+			!strings.Contains(resp.Header.Get("Sec-WebSocket-Version"), "13") {
+			t.Errorf("expected supported version in response, got %v", resp.Header)
+		}
+	}
+}